@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/guardian/s3-audit/awsapi"
+)
+
+// acceleration is the bucket's Transfer Acceleration status, and whether the
+// probe object could be read anonymously through the accelerate endpoint.
+type acceleration struct {
+	Enabled       bool `json:"enabled"`
+	AnonymousRead bool `json:"anonymousRead"`
+}
+
+// getAcceleration returns whether Transfer Acceleration is enabled.
+func getAcceleration(ctx context.Context, client awsapi.S3, bucketName string, region string) (*acceleration, error) {
+	out, err := client.GetBucketAccelerateConfiguration(ctx, &s3.GetBucketAccelerateConfigurationInput{Bucket: &bucketName}, inRegion(region))
+	if err != nil {
+		return nil, err
+	}
+
+	return &acceleration{Enabled: out.Status == s3types.BucketAccelerateStatusEnabled}, nil
+}
+
+// accelerateURL is the object's URL on the Transfer Acceleration endpoint,
+// which serves the bucket from any edge location.
+func accelerateURL(bucketName string, key string) string {
+	return fmt.Sprintf("https://%s.s3-accelerate.amazonaws.com/%s", bucketName, key)
+}
+
+// transferAccelerationCheck reports buckets with Transfer Acceleration
+// enabled. The accelerate hostname is easily forgotten when locking down a
+// bucket's standard endpoint, e.g. in a WAF or CDN-only setup, so anonymous
+// reads through it are reported separately.
+type transferAccelerationCheck struct{}
+
+func (transferAccelerationCheck) Name() string { return "transfer-acceleration" }
+
+func (c transferAccelerationCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	accel := facts.Acceleration
+	if accel == nil || !accel.Enabled {
+		return nil
+	}
+
+	if !accel.AnonymousRead {
+		return []Finding{{
+			Check:    c.Name(),
+			Severity: SeverityLow,
+			Message:  "Transfer Acceleration is enabled",
+			Details:  []string{"the bucket is also served from " + accelerateURL(facts.Name, "")},
+		}}
+	}
+
+	finding := Finding{
+		Check:    c.Name(),
+		Severity: SeverityHigh,
+		Message:  "objects can be read anonymously through the Transfer Acceleration endpoint",
+	}
+	if !facts.Exposure.AnonymousRead {
+		finding.Severity = SeverityCritical
+		finding.Details = append(finding.Details, "the standard endpoint doesn't allow anonymous reads, so the accelerate endpoint may have been missed when locking the bucket down")
+	}
+
+	return []Finding{finding}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/guardian/s3-audit/policy"
+)
+
+// accountNames maps account IDs to friendly names (e.g. "ophan-prod").
+type accountNames map[string]string
+
+// organization is the accounts in our AWS Organization by ID. It is nil
+// unless -org-profile is given.
+type organization map[string]orgAccount
+
+type orgAccount struct {
+	Name  string
+	State orgtypes.AccountState // e.g. ACTIVE, SUSPENDED or CLOSED
+}
+
+// loadOrganization lists the organization's accounts using orgProfile, which
+// must be allowed to list them (usually the management account).
+func loadOrganization(ctx context.Context, orgProfile string) (organization, error) {
+	if orgProfile == "" {
+		return nil, nil
+	}
+
+	config, err := loadConfig(ctx, orgProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	org := organization{}
+	paginator := organizations.NewListAccountsPaginator(organizations.NewFromConfig(config), &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list organization accounts: %w", err)
+		}
+
+		for _, account := range page.Accounts {
+			org[aws.ToString(account.Id)] = orgAccount{Name: aws.ToString(account.Name), State: account.State}
+		}
+	}
+
+	return org, nil
+}
+
+// loadAccountNames combines account names from the organization (if listed)
+// with those in the mapping file at path (if set). The file wins where both
+// name an account.
+func loadAccountNames(path string, org organization) (accountNames, error) {
+	names := accountNames{}
+	for id, account := range org {
+		names[id] = account.Name
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		fileNames := map[string]string{}
+		if err := json.Unmarshal(data, &fileNames); err != nil {
+			return nil, fmt.Errorf("invalid account names file %s: %w", path, err)
+		}
+
+		for id, name := range fileNames {
+			names[id] = name
+		}
+	}
+
+	return names, nil
+}
+
+// resolve returns the principal with its account name, e.g.
+// "ophan-prod (arn:aws:iam::123456789012:root)", or the principal unchanged
+// if the account is unknown.
+func (n accountNames) resolve(principal string) string {
+	name, ok := n[policy.AccountID(principal)]
+	if !ok {
+		return principal
+	}
+
+	return fmt.Sprintf("%s (%s)", name, principal)
+}
+
+func (n accountNames) resolveAll(principals []string) []string {
+	resolved := make([]string, 0, len(principals))
+	for _, principal := range principals {
+		resolved = append(resolved, n.resolve(principal))
+	}
+
+	return resolved
+}
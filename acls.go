@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// aclCount is how many of an account's buckets still have ACLs enabled, with
+// Object Ownership other than BucketOwnerEnforced, and how many have them
+// disabled, to track the move away from ACLs.
+type aclCount struct {
+	Enabled  int `json:"enabled"`
+	Disabled int `json:"disabled"`
+}
+
+// add counts a bucket by its Object Ownership setting, if known.
+func (c *aclCount) add(ownership string) {
+	switch ownership {
+	case "":
+	case string(s3types.ObjectOwnershipBucketOwnerEnforced):
+		c.Disabled++
+	default:
+		c.Enabled++
+	}
+}
+
+// migrated is the percentage of buckets with ACLs disabled.
+func (c aclCount) migrated() float64 {
+	if c.Enabled+c.Disabled == 0 {
+		return 100
+	}
+
+	return 100 * float64(c.Disabled) / float64(c.Enabled+c.Disabled)
+}
+
+// totalACLs is the count across all the report's accounts, and false if the
+// report predates counting them.
+func (r savedReport) totalACLs() (aclCount, bool) {
+	total := aclCount{}
+	for _, c := range r.ACLs {
+		total.Enabled += c.Enabled
+		total.Disabled += c.Disabled
+	}
+
+	return total, r.ACLs != nil
+}
+
+// printACLReport lists how many buckets in each account still have ACLs
+// enabled in the latest of the saved reports, with the percentage migrated
+// to BucketOwnerEnforced in each report, oldest first.
+func printACLReport(w io.Writer, reports []*savedReport) {
+	reports = slices.DeleteFunc(slices.Clone(reports), func(r *savedReport) bool { return r.ACLs == nil })
+	if len(reports) == 0 {
+		fmt.Fprintln(w, "No saved reports count ACLs yet.")
+		return
+	}
+	latest := reports[len(reports)-1]
+
+	profiles := []string{}
+	for _, r := range reports {
+		for profile := range r.ACLs {
+			if !slices.Contains(profiles, profile) {
+				profiles = append(profiles, profile)
+			}
+		}
+	}
+	slices.Sort(profiles)
+
+	header := []string{fmt.Sprintf("%-40s %8s %8s", "account", "enabled", "disabled")}
+	for _, r := range reports {
+		header = append(header, formatTime(r.Time))
+	}
+	fmt.Fprintln(w, "Buckets with ACLs enabled, and the percentage migrated to BucketOwnerEnforced")
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	row := func(label string, count func(r *savedReport) (aclCount, bool)) {
+		cells := []string{fmt.Sprintf("%-40s %8s %8s", label, "-", "-")}
+		if current, ok := count(latest); ok {
+			cells[0] = fmt.Sprintf("%-40s %8d %8d", label, current.Enabled, current.Disabled)
+		}
+		for _, r := range reports {
+			width := len(formatTime(r.Time))
+			if c, ok := count(r); ok {
+				cells = append(cells, fmt.Sprintf("%*.1f%%", width-1, c.migrated()))
+			} else {
+				cells = append(cells, fmt.Sprintf("%*s", width, "-"))
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+
+	for _, profile := range profiles {
+		row(profile, func(r *savedReport) (aclCount, bool) {
+			c, ok := r.ACLs[profile]
+			return c, ok
+		})
+	}
+	row("all accounts", (*savedReport).totalACLs)
+}
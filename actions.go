@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// noWrite blocks every AWS request that would change something: probe
+// objects, remediation, uploads and the rest. It is set by -no-write, or for
+// every subcommand and the scans serve and work run by S3_AUDIT_NO_WRITE.
+var noWrite = os.Getenv("S3_AUDIT_NO_WRITE") != ""
+
+// actions records the AWS requests that change something, set by
+// -action-log.
+var actions *actionLog
+
+// errWriteBlocked is returned for requests blocked by noWrite.
+var errWriteBlocked = errors.New("blocked by -no-write")
+
+// readOnlyPrefixes start the names of operations that don't change anything.
+// Every other operation is treated as a write, so that one added later is
+// guarded until listed here.
+var readOnlyPrefixes = []string{"Get", "List", "Describe", "Head", "Lookup", "Search", "Simulate", "Select", "BatchGet", "Receive", "Verify", "Sign"}
+
+func isWrite(operation string) bool {
+	for _, prefix := range readOnlyPrefixes {
+		if strings.HasPrefix(operation, prefix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setWriteGuard applies -no-write and opens the action log, if there is one.
+func setWriteGuard(block bool, logPath string) error {
+	noWrite = noWrite || block
+	if logPath == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to open action log: %w", err)
+	}
+	actions = &actionLog{enc: json.NewEncoder(f)}
+
+	return nil
+}
+
+// action is a write made, or blocked, by the tool, as a line of the action
+// log.
+type action struct {
+	Time      time.Time         `json:"time"`
+	Scan      string            `json:"scan,omitempty"`
+	Profile   string            `json:"profile"`
+	Service   string            `json:"service"`
+	Operation string            `json:"operation"`
+	Region    string            `json:"region"`
+	Params    map[string]string `json:"params,omitempty"` // what was written to, e.g. Bucket and Key
+	RequestID string            `json:"requestId,omitempty"`
+	Outcome   string            `json:"outcome"` // done, failed or blocked
+	Error     string            `json:"error,omitempty"`
+}
+
+// actionLog appends actions to a file as JSON lines.
+type actionLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (l *actionLog) record(a action) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.enc.Encode(a); err != nil {
+		component("actions").Error("unable to write action log", "error", err)
+	}
+}
+
+// actionParams are the input fields logged, identifying what was written to.
+// Bodies and values aren't, as they may be large or sensitive.
+var actionParams = []string{"Bucket", "Key", "Name", "QueueUrl", "AccountId", "RoleArn"}
+
+func paramsOf(input any) map[string]string {
+	v := reflect.Indirect(reflect.ValueOf(input))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	params := map[string]string{}
+	for _, name := range actionParams {
+		switch field := v.FieldByName(name); {
+		case !field.IsValid():
+		case field.Kind() == reflect.String:
+			params[name] = field.String()
+		case field.Kind() == reflect.Pointer && field.Type().Elem().Kind() == reflect.String && !field.IsNil():
+			params[name] = field.Elem().String()
+		}
+	}
+
+	return params
+}
+
+// addWriteGuard adds a guard to a client's middleware stack that blocks
+// writes under -no-write and records them in the action log. profile is the
+// one the client's credentials came from.
+func addWriteGuard(profile string) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("s3-audit/guardWrites", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			return guardWrite(ctx, profile, in, next)
+		}), middleware.Before)
+	}
+}
+
+func guardWrite(ctx context.Context, profile string, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	operation := awsmiddleware.GetOperationName(ctx)
+	if !isWrite(operation) {
+		return next.HandleInitialize(ctx, in)
+	}
+
+	a := action{
+		Time:      time.Now(),
+		Scan:      scanID(ctx),
+		Profile:   profile,
+		Service:   awsmiddleware.GetServiceID(ctx),
+		Operation: operation,
+		Region:    awsmiddleware.GetRegion(ctx),
+		Params:    paramsOf(in.Parameters),
+	}
+	log := component("actions").With("service", a.Service, "operation", a.Operation, "profile", profile, "params", a.Params)
+
+	if noWrite {
+		log.Debug("blocked write")
+		a.Outcome = "blocked"
+		if actions != nil {
+			actions.record(a)
+		}
+		return middleware.InitializeOutput{}, middleware.Metadata{}, fmt.Errorf("%s %s: %w", a.Service, operation, errWriteBlocked)
+	}
+
+	out, metadata, err := next.HandleInitialize(ctx, in)
+
+	a.Outcome = "done"
+	if err != nil {
+		a.Outcome, a.Error = "failed", err.Error()
+	}
+	a.RequestID, _ = awsmiddleware.GetRequestIDMetadata(metadata)
+	log.Debug("write", "outcome", a.Outcome, "requestId", a.RequestID)
+	if actions != nil {
+		actions.record(a)
+	}
+
+	return out, metadata, err
+}
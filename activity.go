@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/guardian/s3-audit/awsapi"
+)
+
+// bucketActivity is the evidence of whether a bucket is still used: requests
+// from CloudWatch request metrics, where the bucket has a whole-bucket
+// filter, and the newest object in a sample of its listing.
+type bucketActivity struct {
+	Days         int        `json:"days"`         // period Requests covers
+	Requests     *int64     `json:"requests"`     // nil without request metrics
+	LastModified *time.Time `json:"lastModified"` // newest object sampled, nil if the bucket is empty
+	Sampled      int        `json:"sampled"`      // objects sampled
+	Complete     bool       `json:"complete"`     // whether the sample covered every object
+}
+
+// getBucketActivity collects activity over the last days, listing up to
+// sampleSize objects.
+func getBucketActivity(ctx context.Context, config aws.Config, client awsapi.S3, bucket string, region string, days int, sampleSize int) (*bucketActivity, error) {
+	activity := &bucketActivity{Days: days}
+
+	var err error
+	if activity.Requests, err = countRequests(ctx, config, client, bucket, region, days); err != nil {
+		return nil, err
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{Bucket: &bucket, MaxKeys: aws.Int32(int32(min(sampleSize, 1000)))})
+	for paginator.HasMorePages() && activity.Sampled < sampleSize {
+		page, err := paginator.NextPage(ctx, inRegion(region))
+		if err != nil {
+			return nil, fmt.Errorf("unable to list objects: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			if object.LastModified != nil && (activity.LastModified == nil || object.LastModified.After(*activity.LastModified)) {
+				activity.LastModified = object.LastModified
+			}
+		}
+		activity.Sampled += len(page.Contents)
+	}
+	activity.Complete = !paginator.HasMorePages()
+
+	return activity, nil
+}
+
+// countRequests sums AllRequests over the last days for the bucket's
+// whole-bucket request metrics filter, or returns nil if it has none. Unlike
+// listing the metrics, this tells a quiet bucket from one without metrics,
+// as CloudWatch only lists metrics with recent data.
+func countRequests(ctx context.Context, config aws.Config, client awsapi.S3, bucket string, region string, days int) (*int64, error) {
+	filterID := ""
+	input := &s3.ListBucketMetricsConfigurationsInput{Bucket: &bucket}
+	for filterID == "" {
+		page, err := client.ListBucketMetricsConfigurations(ctx, input, inRegion(region))
+		if err != nil {
+			return nil, fmt.Errorf("unable to list request metrics configurations: %w", err)
+		}
+
+		for _, conf := range page.MetricsConfigurationList {
+			if conf.Filter == nil {
+				filterID = aws.ToString(conf.Id)
+				break
+			}
+		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+	if filterID == "" {
+		return nil, nil
+	}
+
+	cw := cloudwatch.NewFromConfig(config, func(o *cloudwatch.Options) { o.Region = region })
+
+	end := time.Now()
+	start := end.AddDate(0, 0, -days)
+	out, err := cw.GetMetricData(ctx, &cloudwatch.GetMetricDataInput{
+		MetricDataQueries: []cwtypes.MetricDataQuery{{
+			Id: aws.String("requests"),
+			MetricStat: &cwtypes.MetricStat{
+				Metric: &cwtypes.Metric{
+					Namespace:  aws.String("AWS/S3"),
+					MetricName: aws.String("AllRequests"),
+					Dimensions: []cwtypes.Dimension{
+						{Name: aws.String("BucketName"), Value: &bucket},
+						{Name: aws.String("FilterId"), Value: &filterID},
+					},
+				},
+				Period: aws.Int32(86400),
+				Stat:   aws.String("Sum"),
+			},
+		}},
+		StartTime: &start,
+		EndTime:   &end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get request metrics: %w", err)
+	}
+
+	requests := int64(0)
+	for _, result := range out.MetricDataResults {
+		for _, v := range result.Values {
+			requests += int64(v)
+		}
+	}
+
+	return &requests, nil
+}
+
+// unusedBucketsCheck reports buckets that look unused: no requests in the
+// period or, without request metrics, no objects written in it. They are
+// decommission candidates rather than security problems.
+type unusedBucketsCheck struct {
+	params unusedBucketsParams
+}
+
+func (unusedBucketsCheck) Name() string { return "unused-buckets" }
+
+func (c unusedBucketsCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	activity := facts.Activity
+	if activity == nil {
+		return nil
+	}
+
+	since := time.Now().AddDate(0, 0, -activity.Days)
+	if facts.Created != nil && facts.Created.After(since) {
+		return nil
+	}
+
+	finding := Finding{Check: c.Name(), Severity: SeverityLow}
+	switch {
+	case activity.Requests != nil && *activity.Requests == 0:
+		finding.Message = fmt.Sprintf("decommission candidate: no requests in %d days", activity.Days)
+	case activity.Requests != nil:
+		return nil
+	case activity.LastModified == nil && activity.Complete:
+		finding.Message = "decommission candidate: bucket is empty"
+	case activity.LastModified != nil && activity.LastModified.Before(since):
+		finding.Message = fmt.Sprintf("decommission candidate: no objects written in %d days", activity.Days)
+		finding.Details = append(finding.Details, "newest object sampled was written "+formatTime(*activity.LastModified))
+		if !activity.Complete {
+			finding.Details = append(finding.Details, fmt.Sprintf("sampled the first %d objects only", activity.Sampled))
+		}
+		finding.Details = append(finding.Details, "reads are unknown without request metrics")
+	default:
+		return nil
+	}
+
+	return []Finding{finding}
+}
+
+// printDecommissionList lists the unused buckets found, with their owners
+// and sizes, for -report unused.
+func printDecommissionList(w io.Writer, findings findingStream) error {
+	fmt.Fprintln(w, "Decommission candidates")
+
+	n := 0
+	err := findings(func(f Finding) error {
+		if f.Check != "unused-buckets" {
+			return nil
+		}
+		n++
+
+		owner := f.Owner
+		if owner == "" {
+			owner = unowned
+		}
+		size := "size unknown"
+		if f.Metadata != nil && f.Metadata.SizeBytes > 0 {
+			size = formatBytes(f.Metadata.SizeBytes)
+		}
+		fmt.Fprintf(w, "  %s/%s\t%s\t%s\t%s\n", f.Account, f.Bucket, owner, size, f.Message)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+
+	return nil
+}
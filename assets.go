@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// bucketAsset is a bucket as recorded in the asset inventory, from the
+// facts a scan collects.
+type bucketAsset struct {
+	Name            string            `json:"name"`
+	ARN             string            `json:"arn"`
+	Account         string            `json:"account"`
+	AccountID       string            `json:"accountId"`
+	Region          string            `json:"region"`
+	Created         *time.Time        `json:"created"`
+	Owner           string            `json:"owner"`
+	Tags            map[string]string `json:"tags"`
+	Encryption      string            `json:"encryption"` // the default algorithm, "" if none
+	KMSKeyID        string            `json:"kmsKeyId,omitempty"`
+	Objects         *int64            `json:"objects"` // nil without CloudWatch storage metrics
+	SizeBytes       *int64            `json:"sizeBytes"`
+	Public          bool              `json:"public"` // by Access Analyzer or policy; the probe isn't run
+	ObjectOwnership string            `json:"objectOwnership"`
+	ObjectLock      bool              `json:"objectLock"`
+}
+
+func assetOf(facts *BucketFacts) bucketAsset {
+	asset := bucketAsset{
+		Name:            facts.Name,
+		ARN:             "arn:aws:s3:::" + facts.Name,
+		Account:         facts.Account,
+		AccountID:       facts.AccountID,
+		Region:          facts.Region,
+		Created:         facts.Created,
+		Owner:           facts.Owner,
+		Tags:            facts.Tags,
+		Public:          facts.Exposure.any(),
+		ObjectOwnership: facts.ObjectOwnership,
+		ObjectLock:      facts.ObjectLock != nil,
+	}
+	if facts.Encryption != nil {
+		asset.Encryption, asset.KMSKeyID = facts.Encryption.Algorithm, facts.Encryption.KMSKeyID
+	}
+	if facts.Size != nil {
+		asset.Objects, asset.SizeBytes = &facts.Size.Objects, &facts.Size.Bytes
+	}
+
+	return asset
+}
+
+// inventoryMain exports every bucket in the accounts, whether or not it has
+// findings, for ingestion into a CMDB. Nothing is written to the accounts,
+// so buckets are public only by Access Analyzer or their policy.
+func inventoryMain(args []string) {
+	fs := flag.NewFlagSet("inventory", flag.ExitOnError)
+	profiles := fs.String("profiles", "deployTools", "comma-separated AWS profiles (one per account) to export the buckets of")
+	configFile := fs.String("config", "", "JSON config file")
+	ownersFile := fs.String("owners", "", "JSON file mapping Stack and App tags, and bucket names, to owning teams")
+	format := fs.String("format", "json", "json for a list of buckets, or cyclonedx for a CycloneDX BOM of data components")
+	output := fs.String("output", "-", "file to write the inventory to, or - for stdout")
+	endpointURL := fs.String("endpoint-url", "", "send AWS requests to this URL instead")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit inventory [-profiles profiles] [-format json|cyclonedx] [-output file]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *format != "json" && *format != "cyclonedx" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	conf, err := loadConfigFile(*configFile)
+	check(err, "unable to load config")
+	check(setEndpoints(*endpointURL, conf.Endpoints), "invalid endpoints")
+	check(setWriteGuard(true, ""), "unable to block writes")
+
+	teams, err := loadOwners(*ownersFile)
+	check(err, "unable to load owners")
+
+	ctx := context.Background()
+	s := &scanner{conf: conf, owners: teams, clients: newAWSClients, s3Compatible: conf.S3Compatible}
+
+	log := component("inventory")
+	assets := []bucketAsset{}
+	for _, profile := range strings.Split(*profiles, ",") {
+		found, errs, err := s.inventoryAccount(ctx, profile)
+		if err != nil {
+			log.Warn("unable to export account", "account", profile, "error", err)
+			continue
+		}
+		for _, e := range errs {
+			log.Warn("unable to read bucket", "account", profile, "bucket", e.Bucket, "error", e.Error)
+		}
+		assets = append(assets, found...)
+	}
+
+	w := os.Stdout
+	if *output != "-" {
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		check(err, "unable to write inventory")
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "cyclonedx" {
+		err = writeCycloneDX(w, assets, time.Now())
+	} else {
+		err = writeIndentedJSON(w, assets)
+	}
+	check(err, "unable to write inventory")
+}
+
+// inventoryAccount collects the facts of every bucket in the account, and
+// their sizes from CloudWatch. Parts that can't be read are left empty and
+// returned as errors.
+func (s *scanner) inventoryAccount(ctx context.Context, profile string) ([]bucketAsset, []scanError, error) {
+	as, clients, err := s.newAccountScan(ctx, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	buckets, err := listBuckets(ctx, as.client)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to list buckets: %w", err)
+	}
+
+	if !s.s3Compatible {
+		if as.publicByAccessAnalyzer, err = getAccessAnalyzerPublicBuckets(ctx, clients.accessAnalyzer); err != nil {
+			as.fail("", err)
+		}
+		if as.bucketSizes, err = getBucketSizes(ctx, as.config, bucketRegions(buckets)); err != nil {
+			as.fail("", err)
+		}
+	}
+
+	assets := []bucketAsset{}
+	for _, bucket := range buckets {
+		facts := collectFacts(ctx, as, bucket)
+		facts.Owner = s.owners.resolve(facts.Name, facts.Tags)
+		assets = append(assets, assetOf(facts))
+	}
+
+	return assets, as.errors, nil
+}
+
+func writeIndentedJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// cycloneDXBOM is a CycloneDX 1.6 bill of materials listing buckets as data
+// components, with the asset's fields as properties.
+type cycloneDXBOM struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Metadata    cycloneDXMetadata    `json:"metadata"`
+	Components  []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     struct {
+		Components []cycloneDXComponent `json:"components"`
+	} `json:"tools"`
+}
+
+type cycloneDXComponent struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Name       string              `json:"name"`
+	Group      string              `json:"group,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func writeCycloneDX(w io.Writer, assets []bucketAsset, at time.Time) error {
+	bom := cycloneDXBOM{BOMFormat: "CycloneDX", SpecVersion: "1.6", Version: 1, Components: []cycloneDXComponent{}}
+	bom.Metadata.Timestamp = at.UTC().Format(time.RFC3339)
+	bom.Metadata.Tools.Components = []cycloneDXComponent{{Type: "application", Name: "s3-audit"}}
+
+	for _, a := range assets {
+		props := []cycloneDXProperty{
+			{"s3-audit:account", a.Account},
+			{"s3-audit:accountId", a.AccountID},
+			{"s3-audit:region", a.Region},
+			{"s3-audit:owner", a.Owner},
+			{"s3-audit:encryption", a.Encryption},
+			{"s3-audit:kmsKeyId", a.KMSKeyID},
+			{"s3-audit:public", strconv.FormatBool(a.Public)},
+			{"s3-audit:objectOwnership", a.ObjectOwnership},
+			{"s3-audit:objectLock", strconv.FormatBool(a.ObjectLock)},
+		}
+		if a.Created != nil {
+			props = append(props, cycloneDXProperty{"s3-audit:created", a.Created.UTC().Format(time.RFC3339)})
+		}
+		if a.SizeBytes != nil {
+			props = append(props,
+				cycloneDXProperty{"s3-audit:objects", strconv.FormatInt(aws.ToInt64(a.Objects), 10)},
+				cycloneDXProperty{"s3-audit:sizeBytes", strconv.FormatInt(*a.SizeBytes, 10)},
+			)
+		}
+		for _, key := range slices.Sorted(maps.Keys(a.Tags)) {
+			props = append(props, cycloneDXProperty{"aws:tag:" + key, a.Tags[key]})
+		}
+		props = slices.DeleteFunc(props, func(p cycloneDXProperty) bool { return p.Value == "" })
+
+		bom.Components = append(bom.Components, cycloneDXComponent{Type: "data", BOMRef: a.ARN, Name: a.Name, Group: a.AccountID, Properties: props})
+	}
+
+	return writeIndentedJSON(w, bom)
+}
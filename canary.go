@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/guardian/s3-audit/awsapi"
+)
+
+// canaryMarkerKey is the object a canary bucket holds, saying what it is.
+const canaryMarkerKey = "S3-AUDIT-CANARY.txt"
+
+// canaryMain creates a deliberately public bucket, waits for each way
+// s3-audit finds public buckets to flag it, and deletes it again, so a
+// broken detection path shows up before a real public bucket is missed.
+func canaryMain(args []string) {
+	fs := flag.NewFlagSet("canary", flag.ExitOnError)
+	profile := fs.String("profile", "deployTools", "AWS profile for the account to create the canary bucket in, which mustn't block public access account-wide")
+	configFile := fs.String("config", "", "JSON config file, whose outputs are sent the canary's finding")
+	timeout := fs.Duration("timeout", 30*time.Minute, "how long each detection path has to flag the canary; Access Analyzer can take up to 30 minutes")
+	interval := fs.Duration("interval", 30*time.Second, "how often to check the detection paths")
+	var outputs stringList
+	fs.Var(&outputs, "output", "also send the canary's finding to an output, as for scan; may be repeated")
+	actionLog := fs.String("action-log", "", "append every change made, e.g. creating the canary, to this file as JSON lines with request IDs")
+	endpointURL := fs.String("endpoint-url", "", "send AWS requests to this URL instead")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit canary [-profile profile] [-config config.json] [-timeout 30m]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *timeout <= 0 || *interval <= 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	conf, err := loadConfigFile(*configFile)
+	check(err, "unable to load config")
+	check(setEndpoints(*endpointURL, conf.Endpoints), "invalid endpoints")
+	check(setWriteGuard(false, *actionLog), "invalid -action-log")
+	if noWrite {
+		check(errWriteBlocked, "unable to create the canary")
+	}
+	for _, o := range outputs {
+		output, err := parseOutput(o)
+		check(err, "invalid -output")
+		conf.Outputs = append(conf.Outputs, output)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config, err := loadConfig(ctx, *profile)
+	check(err, "unable to load AWS config")
+	identity, err := sts.NewFromConfig(config).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	check(err, "unable to get caller identity")
+	accountID := aws.ToString(identity.Account)
+
+	c := &canary{
+		client:    s3.NewFromConfig(config, func(o *s3.Options) { o.UsePathStyle = endpoints.s3Endpoint() != "" }),
+		bucket:    fmt.Sprintf("s3-audit-canary-%s-%s", accountID, time.Now().UTC().Format("20060102t150405")),
+		region:    config.Region,
+		accountID: accountID,
+	}
+	check(c.checkAccountBlock(ctx, s3control.NewFromConfig(config)), "unable to create the canary")
+
+	s := &scanner{conf: conf, owners: &owners{}, clients: newAWSClients, buckets: []string{c.bucket}}
+	s.selection, err = newCheckSelection("public-access", "", nil)
+	check(err, "invalid checks")
+	s.checks, err = s.builtinChecks()
+	check(err, "invalid config")
+	as, clients, err := s.newAccountScan(ctx, *profile)
+	check(err, "unable to scan "+*profile)
+
+	results, err := c.run(ctx, func() []canaryResult {
+		return c.verify(ctx, s, as, clients.accessAnalyzer, *timeout, *interval)
+	})
+	check(err, "unable to create the canary")
+	printCanaryResults(os.Stdout, results)
+
+	for _, r := range results {
+		if !r.Detected {
+			os.Exit(1)
+		}
+	}
+}
+
+// canary is a deliberately public bucket, empty apart from a marker object.
+type canary struct {
+	client    *s3.Client
+	bucket    string
+	region    string
+	accountID string
+}
+
+// checkAccountBlock fails if account-level Block Public Access would stop
+// the canary being made public.
+func (c *canary) checkAccountBlock(ctx context.Context, control *s3control.Client) error {
+	out, err := control.GetPublicAccessBlock(ctx, &s3control.GetPublicAccessBlockInput{AccountId: &c.accountID})
+	if isErrorCode(err, "NoSuchPublicAccessBlockConfiguration") {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get account-level Block Public Access: %w", err)
+	}
+
+	if conf := out.PublicAccessBlockConfiguration; conf != nil && (aws.ToBool(conf.BlockPublicPolicy) || aws.ToBool(conf.RestrictPublicBuckets)) {
+		return fmt.Errorf("account %s blocks public bucket policies account-wide, so the canary can't be public; use another account", c.accountID)
+	}
+	return nil
+}
+
+// run creates the canary, verifies it and deletes it again, even if
+// creating it failed part way or the run was interrupted.
+func (c *canary) run(ctx context.Context, verify func() []canaryResult) ([]canaryResult, error) {
+	log := component("canary").With("bucket", c.bucket)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		defer cancel()
+		if err := c.delete(ctx); err != nil {
+			log.Error("unable to delete canary bucket; delete it by hand", "error", err)
+			return
+		}
+		log.Info("deleted canary bucket")
+	}()
+
+	if err := c.create(ctx); err != nil {
+		return nil, err
+	}
+	log.Info("created public canary bucket", "region", c.region)
+
+	return verify(), nil
+}
+
+// create makes the canary bucket and opens it to anonymous reads.
+func (c *canary) create(ctx context.Context) error {
+	in := &s3.CreateBucketInput{Bucket: &c.bucket}
+	if c.region != "us-east-1" {
+		in.CreateBucketConfiguration = &s3types.CreateBucketConfiguration{LocationConstraint: s3types.BucketLocationConstraint(c.region)}
+	}
+	if _, err := c.client.CreateBucket(ctx, in); err != nil {
+		return err
+	}
+
+	tags := &s3types.Tagging{TagSet: []s3types.Tag{
+		{Key: aws.String("Purpose"), Value: aws.String("s3-audit canary: deliberately public, and deleted once detected")},
+	}}
+	if _, err := c.client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{Bucket: &c.bucket, Tagging: tags}); err != nil {
+		return err
+	}
+
+	marker := "This bucket is a canary made public on purpose by s3-audit canary, to test that public buckets are detected. It holds nothing else and is deleted once the test is done.\n"
+	if _, err := c.client.PutObject(ctx, &s3.PutObjectInput{Bucket: &c.bucket, Key: aws.String(canaryMarkerKey), Body: strings.NewReader(marker)}); err != nil {
+		return err
+	}
+
+	// New buckets block public access.
+	if _, err := c.client.DeletePublicAccessBlock(ctx, &s3.DeletePublicAccessBlockInput{Bucket: &c.bucket}); err != nil {
+		return err
+	}
+
+	doc := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Sid":"S3AuditCanary","Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::%s/*"}]}`, c.bucket)
+	if _, err := c.client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{Bucket: &c.bucket, Policy: &doc}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// delete empties the canary bucket, including any probe object left behind,
+// and deletes it.
+func (c *canary) delete(ctx context.Context) error {
+	paginator := s3.NewListObjectsV2Paginator(c.client, &s3.ListObjectsV2Input{Bucket: &c.bucket})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if isErrorCode(err, "NoSuchBucket") {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, object := range page.Contents {
+			if _, err := c.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: &c.bucket, Key: object.Key}); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := c.client.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: &c.bucket})
+	return err
+}
+
+// canaryResult is whether a detection path flagged the canary, and how
+// long after it was made public.
+type canaryResult struct {
+	Path     string
+	Detected bool
+	After    time.Duration
+	Error    string // the last error checking the path, if it wasn't detected
+}
+
+// verify checks each detection path every interval until all have flagged
+// the canary or timeout has passed. The paths are the anonymous read probe,
+// S3's own policy status, Access Analyzer, a scan of the bucket, and the
+// scan's finding being sent to the outputs.
+func (c *canary) verify(ctx context.Context, s *scanner, as *accountScan, analyzer awsapi.AccessAnalyzer, timeout time.Duration, interval time.Duration) []canaryResult {
+	log := component("canary").With("bucket", c.bucket)
+	started := time.Now()
+	bucket := s3types.Bucket{Name: &c.bucket, BucketRegion: &c.region}
+
+	var findings []Finding
+	paths := []struct {
+		name   string
+		detect func() (bool, error)
+	}{
+		{"probe", func() (bool, error) {
+			public, _ := canGetObject(ctx, log, c.client, c.bucket, false)
+			return public, nil
+		}},
+		{"policy status", func() (bool, error) {
+			out, err := c.client.GetBucketPolicyStatus(ctx, &s3.GetBucketPolicyStatusInput{Bucket: &c.bucket})
+			if err != nil {
+				return false, err
+			}
+			return out.PolicyStatus != nil && aws.ToBool(out.PolicyStatus.IsPublic), nil
+		}},
+		{"access analyzer", func() (bool, error) {
+			public, err := getAccessAnalyzerPublicBuckets(ctx, analyzer)
+			as.publicByAccessAnalyzer = public
+			return public[c.bucket], err
+		}},
+		{"scan", func() (bool, error) {
+			as.errors = nil
+			findings = s.scanBucket(ctx, as, bucket)
+			if len(findings) == 0 && len(as.errors) > 0 {
+				return false, errors.New(as.errors[0].Error)
+			}
+			for i := range findings {
+				findings[i].Details = append(findings[i].Details, "canary bucket made public by s3-audit canary, and deleted once detected")
+			}
+			return len(findings) > 0, nil
+		}},
+		{"notifications", func() (bool, error) {
+			if len(s.conf.Outputs) == 0 {
+				return false, errors.New("no outputs configured")
+			}
+			if len(findings) == 0 {
+				return false, errors.New("the scan hasn't found the canary")
+			}
+			err := sendOutputs(ctx, s.conf.Outputs, savedReport{Time: time.Now(), Profiles: []string{as.Profile}}, sliceStream(findings))
+			return err == nil, err
+		}},
+	}
+
+	results := make([]canaryResult, len(paths))
+	for i, p := range paths {
+		results[i].Path = p.name
+	}
+	for {
+		pending := 0
+		for i, p := range paths {
+			if results[i].Detected {
+				continue
+			}
+
+			detected, err := p.detect()
+			results[i].Error = ""
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+			if detected {
+				results[i].Detected, results[i].After = true, time.Since(started)
+				log.Info("canary detected", "path", p.name, "after", results[i].After.Round(time.Second))
+				continue
+			}
+			pending++
+		}
+
+		if pending == 0 || time.Since(started) >= timeout {
+			return results
+		}
+		select {
+		case <-ctx.Done():
+			return results
+		case <-time.After(interval):
+		}
+	}
+}
+
+func printCanaryResults(w io.Writer, results []canaryResult) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Detection path\tResult")
+	for _, r := range results {
+		switch {
+		case r.Detected:
+			fmt.Fprintf(tw, "%s\tdetected after %s\n", r.Path, r.After.Round(time.Second))
+		case r.Error != "":
+			fmt.Fprintf(tw, "%s\tNOT DETECTED: %s\n", r.Path, r.Error)
+		default:
+			fmt.Fprintf(tw, "%s\tNOT DETECTED\n", r.Path)
+		}
+	}
+	tw.Flush()
+}
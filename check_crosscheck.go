@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// configDisagreementCheck reports where AWS Config's public read/write
+// prohibited rules disagree with what we found: the read rule with whether
+// the bucket is public, and the write rule with whether anyone can write.
+type configDisagreementCheck struct{}
+
+func (configDisagreementCheck) Name() string { return "config-disagreement" }
+
+func (c configDisagreementCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	findings := []Finding{}
+
+	for _, rule := range slices.Sorted(maps.Keys(facts.ConfigCompliance)) {
+		eval := facts.ConfigCompliance[rule]
+		access, public, how := "public access", facts.Exposure.any(), fmt.Sprintf(" (%s)", facts.Exposure)
+		if eval.Identifier == "S3_BUCKET_PUBLIC_WRITE_PROHIBITED" {
+			access, public, how = "public write access", isPublicWrite(facts), ""
+		}
+
+		finding := Finding{Check: c.Name(), Subject: rule}
+		switch {
+		case eval.Compliance == "COMPLIANT" && public:
+			finding.Severity = SeverityMedium
+			finding.Message = fmt.Sprintf("Config rule %s says COMPLIANT but we found %s%s", rule, access, how)
+		case eval.Compliance == "NON_COMPLIANT" && !public:
+			finding.Severity = SeverityLow
+			finding.Message = fmt.Sprintf("Config rule %s says NON_COMPLIANT but we found no %s", rule, access)
+		default:
+			continue
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
+
+// trustedAdvisorDisagreementCheck reports where Trusted Advisor's bucket
+// permissions check disagrees with what we found.
+type trustedAdvisorDisagreementCheck struct{}
+
+func (trustedAdvisorDisagreementCheck) Name() string { return "trusted-advisor-disagreement" }
+
+func (c trustedAdvisorDisagreementCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	ta := facts.TrustedAdvisor
+	if ta == nil {
+		return nil
+	}
+
+	finding := Finding{Check: c.Name()}
+	switch {
+	case !ta.flagged() && facts.Exposure.any():
+		finding.Severity = SeverityMedium
+		finding.Message = fmt.Sprintf("Trusted Advisor reports %s but we found public access (%s)", ta.Status, facts.Exposure)
+	case ta.flagged() && !facts.Exposure.any():
+		finding.Severity = SeverityLow
+		finding.Message = fmt.Sprintf("Trusted Advisor reports %s %v but we found no public access", ta.Status, ta.Reasons)
+	default:
+		return nil
+	}
+
+	return []Finding{finding}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// dataEventsCheck reports sensitive buckets without CloudTrail data events.
+type dataEventsCheck struct {
+	conf *Config
+}
+
+func (dataEventsCheck) Name() string { return "data-events" }
+
+func (c dataEventsCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	if len(facts.DataEventTrails) > 0 || !c.conf.isSensitive(facts.Tags) {
+		return nil
+	}
+
+	return []Finding{{
+		Check:    c.Name(),
+		Severity: SeverityMedium,
+		Message:  "sensitive bucket has no CloudTrail data events, so object access isn't audited",
+	}}
+}
+
+// kmsKeyPolicyCheck reports customer-managed bucket keys whose policies are
+// open, as a locked-down bucket is still exposed through an open key, and
+// keys that aren't on the allowed list.
+type kmsKeyPolicyCheck struct {
+	names  accountNames
+	params kmsKeyPolicyParams
+}
+
+func (kmsKeyPolicyCheck) Name() string { return "kms-key-policy" }
+
+func (c kmsKeyPolicyCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	findings := []Finding{}
+	if enc := facts.Encryption; enc != nil && enc.KMS {
+		keyARN := ""
+		if facts.KMSKey != nil {
+			keyARN = facts.KMSKey.ARN
+		}
+		if !c.params.allowed(enc.KMSKeyID, keyARN) {
+			finding := Finding{Check: c.Name(), Subject: "key-not-allowed", Severity: SeverityMedium}
+			finding.Message = fmt.Sprintf("bucket is encrypted with %s, which isn't an allowed key", cmp.Or(keyARN, enc.KMSKeyID, "the AWS managed key"))
+			findings = append(findings, finding)
+		}
+	}
+
+	key := facts.KMSKey
+	if key == nil || key.Policy == nil {
+		return findings
+	}
+
+	if stmts := key.Policy.PublicStatements(); len(stmts) > 0 {
+		finding := Finding{Check: c.Name(), Subject: "public-key-policy", Severity: SeverityHigh}
+		finding.Message = fmt.Sprintf("bucket key %s has a policy granting access to anyone", key.ARN)
+		for _, stmt := range stmts {
+			finding.Details = append(finding.Details, fmt.Sprintf("statement %q grants %s", stmt.Sid, strings.Join(stmt.Action, ", ")))
+		}
+		findings = append(findings, finding)
+	}
+
+	if external := key.Policy.ExternalAccounts(facts.AccountID); len(external) > 0 {
+		finding := Finding{Check: c.Name(), Subject: "cross-account-key-policy", Severity: SeverityMedium}
+		finding.Message = fmt.Sprintf("bucket key %s has a policy granting access to other accounts", key.ARN)
+		finding.Grantees = c.names.resolveAll(external)
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
+
+// requesterPaysCheck reports Requester Pays at odds with who the bucket is
+// shared with. Requester Pays rejects anonymous requests, so a bucket open
+// to anonymous access is either broken for its users or, as any AWS account
+// can still read it, public in all but name. Without it, accounts the bucket
+// is shared with outside those known have their downloads billed to us.
+type requesterPaysCheck struct {
+	names accountNames
+}
+
+func (requesterPaysCheck) Name() string { return "requester-pays" }
+
+func (c requesterPaysCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	anonymous := facts.Policy != nil && facts.Policy.IsPublic()
+	if facts.ACL != nil {
+		// AuthenticatedUsers requests are signed, so work with Requester Pays.
+		for _, grant := range facts.ACL.Grants {
+			anonymous = anonymous || grant.Grantee == "http://acs.amazonaws.com/groups/global/AllUsers"
+		}
+	}
+
+	if facts.RequesterPays {
+		if !anonymous {
+			return nil
+		}
+
+		return []Finding{{
+			Check:    c.Name(),
+			Severity: SeverityMedium,
+			Message:  "Requester Pays is enabled but the bucket grants anonymous access, which Requester Pays rejects",
+			Details:  []string{"anonymous clients get 403 Access Denied, while any AWS account can still read the bucket at its own cost"},
+		}}
+	}
+
+	if facts.Policy == nil {
+		return nil
+	}
+
+	unknown := []string{}
+	for _, id := range facts.Policy.ExternalAccounts(facts.AccountID) {
+		if _, ok := c.names[id]; !ok {
+			unknown = append(unknown, id)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	return []Finding{{
+		Check:    c.Name(),
+		Severity: SeverityLow,
+		Message:  fmt.Sprintf("bucket is shared with unknown accounts %s without Requester Pays, so their requests and downloads are billed to this account", strings.Join(unknown, ", ")),
+	}}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/guardian/s3-audit/policy"
+)
+
+// broadActionsCheck reports statements granting sweeping S3 actions to wide
+// principals.
+type broadActionsCheck struct{}
+
+func (broadActionsCheck) Name() string { return "broad-actions" }
+
+func (c broadActionsCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	if facts.Policy == nil {
+		return nil
+	}
+
+	grants := facts.Policy.BroadGrants(facts.AccountID)
+	if len(grants) == 0 {
+		return nil
+	}
+
+	finding := Finding{Check: c.Name(), Severity: SeverityMedium}
+	if facts.Exposure.Policy {
+		finding.Severity = SeverityHigh
+	}
+	finding.Message = fmt.Sprintf("%d statement(s) grant broad S3 actions to wide principals", len(grants))
+	for _, g := range grants {
+		finding.Details = append(finding.Details, g.String())
+	}
+
+	return []Finding{finding}
+}
+
+// confusedDeputyCheck reports service principal grants without source
+// conditions.
+type confusedDeputyCheck struct{}
+
+func (confusedDeputyCheck) Name() string { return "confused-deputy" }
+
+func (c confusedDeputyCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	if facts.Policy == nil {
+		return nil
+	}
+
+	deputies := facts.Policy.ConfusedDeputies()
+	if len(deputies) == 0 {
+		return nil
+	}
+
+	finding := Finding{Check: c.Name(), Severity: SeverityMedium}
+	finding.Message = fmt.Sprintf("%d service principal statement(s) lack source conditions", len(deputies))
+	for _, d := range deputies {
+		finding.Details = append(finding.Details, d.String())
+	}
+
+	return []Finding{finding}
+}
+
+// negatedElementsCheck reports NotPrincipal, NotAction and NotResource in
+// Allow statements.
+type negatedElementsCheck struct{}
+
+func (negatedElementsCheck) Name() string { return "negated-elements" }
+
+func (c negatedElementsCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	if facts.Policy == nil {
+		return nil
+	}
+
+	patterns := facts.Policy.AntiPatterns()
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	finding := Finding{Check: c.Name(), Severity: SeverityMedium}
+	finding.Message = fmt.Sprintf("%d use(s) of NotPrincipal, NotAction or NotResource in Allow statements", len(patterns))
+	for _, p := range patterns {
+		finding.Details = append(finding.Details, p.String())
+	}
+
+	return []Finding{finding}
+}
+
+// policyComplexityCheck reports policies big enough to be hard to review.
+type policyComplexityCheck struct {
+	params policyComplexityParams
+}
+
+func (policyComplexityCheck) Name() string { return "policy-complexity" }
+
+func (c policyComplexityCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	m := facts.PolicyMetrics
+	if m == nil {
+		return nil
+	}
+
+	reasons := c.reasons(*m)
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	finding := Finding{Check: c.Name(), Severity: SeverityLow, Details: reasons}
+	finding.Message = fmt.Sprintf("policy is a review candidate: %.1fKB of %dKB limit, %d statements, %d principals",
+		float64(m.Size)/1024, policy.MaxSize/1024, m.Statements, m.Principals)
+
+	return []Finding{finding}
+}
+
+func (c policyComplexityCheck) reasons(m policy.Metrics) []string {
+	reasons := []string{}
+	if m.SizeRatio() >= c.params.MaxSizeRatio {
+		reasons = append(reasons, fmt.Sprintf("uses %.0f%% of the policy size limit", m.SizeRatio()*100))
+	}
+	if m.Statements > c.params.MaxStatements {
+		reasons = append(reasons, fmt.Sprintf("has more than %d statements", c.params.MaxStatements))
+	}
+	if m.Principals > c.params.MaxPrincipals {
+		reasons = append(reasons, fmt.Sprintf("names more than %d distinct principals", c.params.MaxPrincipals))
+	}
+
+	return reasons
+}
+
+// policyDriftCheck reports policies that have drifted from an approved
+// template they appear to be derived from.
+type policyDriftCheck struct {
+	templates []policy.Template
+}
+
+func (policyDriftCheck) Name() string { return "policy-drift" }
+
+func (c policyDriftCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	if facts.Policy == nil {
+		return nil
+	}
+
+	findings := []Finding{}
+	for _, tmpl := range c.templates {
+		if !tmpl.Applies(facts.Policy, facts.Name, facts.AccountID) {
+			continue
+		}
+
+		deviations := tmpl.Diff(facts.Policy, facts.Name, facts.AccountID)
+		if len(deviations) == 0 {
+			continue
+		}
+
+		finding := Finding{Check: c.Name(), Subject: tmpl.Name, Severity: SeverityLow}
+		finding.Message = fmt.Sprintf("policy has drifted from template %s", tmpl.Name)
+		for _, d := range deviations {
+			finding.Details = append(finding.Details, d.String())
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
+
+// danglingPrincipalsCheck reports grants to principals that no longer exist:
+// deleted users and roles, and accounts that are closed or, with
+// -org-profile, outside the organization and not in the account names file.
+// A grant to an account that isn't ours is a takeover risk if the ID ends up
+// with someone else.
+type danglingPrincipalsCheck struct {
+	org   organization
+	names accountNames
+}
+
+func (danglingPrincipalsCheck) Name() string { return "dangling-principals" }
+
+func (c danglingPrincipalsCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	if facts.Policy == nil {
+		return nil
+	}
+
+	findings := []Finding{}
+	if deleted := facts.Policy.DeletedPrincipals(); len(deleted) > 0 {
+		finding := Finding{Check: c.Name(), Subject: "deleted-principals", Severity: SeverityLow, Grantees: deleted}
+		finding.Message = fmt.Sprintf("policy grants %d deleted IAM principal(s), which can be removed", len(deleted))
+		findings = append(findings, finding)
+	}
+
+	if c.org == nil {
+		return findings
+	}
+
+	closed, unknown := []string{}, []string{}
+	for _, id := range facts.Policy.ExternalAccounts(facts.AccountID) {
+		account, inOrg := c.org[id]
+		switch {
+		case inOrg && account.State != orgtypes.AccountStateActive && account.State != "":
+			closed = append(closed, id)
+		case !inOrg && c.names[id] == "":
+			unknown = append(unknown, id)
+		}
+	}
+
+	if len(closed) > 0 {
+		finding := Finding{Check: c.Name(), Subject: "closed-accounts", Severity: SeverityHigh, Grantees: c.names.resolveAll(closed)}
+		finding.Message = fmt.Sprintf("policy grants access to %d closed or suspended account(s)", len(closed))
+		findings = append(findings, finding)
+	}
+	if len(unknown) > 0 {
+		finding := Finding{Check: c.Name(), Subject: "unknown-accounts", Severity: SeverityMedium, Grantees: unknown}
+		finding.Message = fmt.Sprintf("policy grants access to %d unknown account(s) outside the organization", len(unknown))
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
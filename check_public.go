@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/guardian/s3-audit/policy"
+)
+
+// publicAccessCheck reports buckets that are public by any measure: the
+// probe, Access Analyzer or the bucket policy. Policies that would be
+// public but for their conditions are reported too, graded by how narrow
+// the conditions are.
+type publicAccessCheck struct {
+	conf  *Config
+	names accountNames
+}
+
+func (publicAccessCheck) Name() string { return "public-access" }
+
+func (c publicAccessCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	exposure := facts.Exposure
+
+	mitigations := []policy.Mitigation{}
+	grantees := []string{}
+	if facts.Policy != nil {
+		// Conditions on other statements don't mitigate one that is public
+		// regardless of them.
+		stmts := facts.Policy.PublicStatements()
+		if len(stmts) == 0 {
+			stmts = facts.Policy.Statement
+		}
+		for _, stmt := range stmts {
+			mitigations = append(mitigations, stmt.Mitigations()...)
+		}
+		grantees = c.names.resolveAll(facts.Policy.Grantees())
+	}
+
+	if !exposure.any() && len(mitigations) == 0 {
+		return nil
+	}
+
+	finding := Finding{Check: c.Name()}
+	finding.Public, finding.AWSPublic, finding.PolicyPublic = exposure.AnonymousRead, exposure.AccessAnalyzer, exposure.Policy
+	finding.Mitigations, finding.Grantees = mitigations, grantees
+	finding.SensitiveData = facts.SensitiveData
+	finding.Message = fmt.Sprintf("public: %v, awspublic: %v, policypublic: %v", exposure.AnonymousRead, exposure.AccessAnalyzer, exposure.Policy)
+	for _, m := range mitigations {
+		finding.Details = append(finding.Details, fmt.Sprintf("mitigated by condition %s", m))
+	}
+	for _, f := range facts.SensitiveData {
+		finding.Details = append(finding.Details, fmt.Sprintf("Macie reports %s (finding %s)", f.Type, f.ID))
+	}
+	if ta := facts.TrustedAdvisor; ta != nil {
+		finding.Details = append(finding.Details, fmt.Sprintf("Trusted Advisor status is %s %v", ta.Status, ta.Reasons))
+	}
+	finding.PublicWrite = isPublicWrite(facts)
+	finding.Egress = facts.Egress
+	if facts.Egress != nil {
+		finding.Details = append(finding.Details, facts.Egress.String())
+	}
+	finding.Severity = c.conf.severityFor(&finding, facts)
+
+	return []Finding{finding}
+}
+
+// isPublicWrite reports whether the bucket policy or ACL lets anyone write
+// to or delete from the bucket.
+func isPublicWrite(facts *BucketFacts) bool {
+	if facts.Policy != nil && facts.Policy.IsPublicWrite() {
+		return true
+	}
+
+	if facts.ACL != nil {
+		for _, grant := range facts.ACL.Grants {
+			isWrite := grant.Permission == "WRITE" || grant.Permission == "FULL_CONTROL"
+			if isWrite && slices.Contains(publicGroups, grant.Grantee) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// publicObjectsCheck reports objects made public by their own ACLs, as
+// found in the bucket's inventory report with -inventory.
+type publicObjectsCheck struct{}
+
+func (publicObjectsCheck) Name() string { return "public-objects" }
+
+func (c publicObjectsCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	objects := facts.PublicObjects
+	if objects == nil || objects.Count == 0 {
+		return nil
+	}
+
+	finding := Finding{Check: c.Name(), Severity: SeverityHigh}
+	finding.Message = fmt.Sprintf("%d objects have ACLs granting public read in the inventory of %s", objects.Count, objects.Report)
+	if pab := facts.PublicAccessBlock; pab != nil && pab.IgnorePublicAcls {
+		finding.Severity = SeverityLow
+		finding.Message += ", but Block Public Access ignores them"
+	}
+	for _, key := range objects.Keys {
+		finding.Details = append(finding.Details, "public object "+key)
+	}
+
+	return []Finding{finding}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/guardian/s3-audit/policy"
+)
+
+func mustParsePolicy(t *testing.T, statement string) *policy.Policy {
+	t.Helper()
+
+	p, err := policy.Parse(`{"Version":"2012-10-17","Statement":[` + statement + `]}`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return p
+}
+
+func TestPublicAccessCheck(t *testing.T) {
+	const (
+		vpceOnly = `{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"*","Condition":{"StringEquals":{"aws:SourceVpce":"vpce-1a2b3c4d"}}}`
+		wideIP   = `{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"*","Condition":{"IpAddress":{"aws:SourceIp":"10.0.0.0/12"}}}`
+		account  = `{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"s3:GetObject","Resource":"*"}`
+	)
+	allUsersWrite := &ACL{Grants: []Grant{{Type: "Group", Grantee: "http://acs.amazonaws.com/groups/global/AllUsers", Permission: "WRITE"}}}
+
+	tests := []struct {
+		name     string
+		facts    BucketFacts
+		severity Severity // ignored if want is false
+		score    int
+		signals  []string
+		want     bool
+	}{
+		{"private", BucketFacts{}, 0, 0, nil, false},
+		{"account grant", BucketFacts{Policy: mustParsePolicy(t, account)}, 0, 0, nil, false},
+		{"anonymous read", BucketFacts{Exposure: Exposure{AnonymousRead: true}},
+			SeverityHigh, 50, []string{signalPublic}, true},
+		{"public write ACL", BucketFacts{Exposure: Exposure{AnonymousRead: true}, ACL: allUsersWrite},
+			SeverityCritical, 80, []string{signalPublic, signalPublicWrite}, true},
+		{"Access Analyzer, narrow conditions", BucketFacts{Exposure: Exposure{AccessAnalyzer: true}, Policy: mustParsePolicy(t, vpceOnly)},
+			SeverityLow, 5, []string{signalAccessAnalyzer, signalNarrowConditions}, true},
+		{"Access Analyzer, no conditions", BucketFacts{Exposure: Exposure{AccessAnalyzer: true}},
+			SeverityHigh, 50, []string{signalPublic}, true},
+		{"broad conditions only", BucketFacts{Policy: mustParsePolicy(t, wideIP)},
+			SeverityMedium, 20, []string{signalBroadConditions}, true},
+		{"narrow conditions only", BucketFacts{Policy: mustParsePolicy(t, vpceOnly)},
+			SeverityLow, 0, []string{signalNarrowConditions}, true},
+		{"personal data in production", BucketFacts{Exposure: Exposure{AnonymousRead: true}, AccountID: "111111111111",
+			SensitiveData: []macieFinding{{ID: "1", Type: "SensitiveData:S3Object/Personal"}}},
+			SeverityCritical, 95, []string{signalPublic, signalSensitiveData, signalProduction}, true},
+		{"custom identifiers", BucketFacts{Exposure: Exposure{AnonymousRead: true},
+			SensitiveData: []macieFinding{{ID: "1", Type: "SensitiveData:S3Object/CustomIdentifier"}}},
+			SeverityHigh, 50, []string{signalPublic}, true},
+		{"sensitive and production tags", BucketFacts{Exposure: Exposure{Policy: true}, Tags: map[string]string{"Data": "PII", "Stage": "PROD"}},
+			SeverityCritical, 80, []string{signalPublic, signalSensitiveTags, signalProduction}, true},
+	}
+
+	conf := &Config{
+		SensitiveTags: map[string][]string{"Data": {"PII"}},
+		Scoring: Scoring{
+			ProductionAccounts: []string{"111111111111"},
+			ProductionTags:     map[string][]string{"Stage": {"PROD"}},
+		},
+	}
+	if err := conf.Scoring.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := publicAccessCheck{conf: conf}.Run(t.Context(), &tt.facts)
+			if !tt.want {
+				if len(findings) != 0 {
+					t.Errorf("Run() = %+v, want no findings", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("Run() = %+v, want one finding", findings)
+			}
+
+			f := findings[0]
+			if f.Severity != tt.severity || f.Score != tt.score || !slices.Equal(f.Signals, tt.signals) {
+				t.Errorf("Run() = %s, score %d, signals %v; want %s, score %d, signals %v", f.Severity, f.Score, f.Signals, tt.severity, tt.score, tt.signals)
+			}
+		})
+	}
+}
+
+func TestScoring(t *testing.T) {
+	tests := []struct {
+		name    string
+		scoring Scoring
+		want    Severity
+		wantErr bool
+	}{
+		{"defaults", Scoring{}, SeverityHigh, false},
+		{"heavier public", Scoring{Weights: map[string]int{signalPublic: 90}}, SeverityCritical, false},
+		{"higher thresholds", Scoring{Thresholds: Thresholds{Critical: 200, High: 100, Medium: 60}}, SeverityLow, false},
+		{"unknown signal", Scoring{Weights: map[string]int{"pubilc": 10}}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &Config{Scoring: tt.scoring}
+			err := conf.Scoring.validate()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("validate() passed, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := Finding{Public: true}
+			if got := conf.severityFor(&f, &BucketFacts{}); got != tt.want {
+				t.Errorf("severityFor() = %s (score %d), want %s", got, f.Score, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublicObjectsCheck(t *testing.T) {
+	objects := &publicObjects{Report: "2026-10-01", Count: 2, Keys: []string{"a", "b"}}
+
+	tests := []struct {
+		name  string
+		facts BucketFacts
+		want  []Severity
+	}{
+		{"no inventory", BucketFacts{}, nil},
+		{"none public", BucketFacts{PublicObjects: &publicObjects{Report: "2026-10-01"}}, nil},
+		{"public objects", BucketFacts{PublicObjects: objects}, []Severity{SeverityHigh}},
+		{"ACLs ignored", BucketFacts{PublicObjects: objects, PublicAccessBlock: &PublicAccessBlock{IgnorePublicAcls: true}}, []Severity{SeverityLow}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []Severity{}
+			for _, f := range (publicObjectsCheck{}).Run(t.Context(), &tt.facts) {
+				got = append(got, f.Severity)
+			}
+			if !slices.Equal(got, tt.want) && !(len(got) == 0 && len(tt.want) == 0) {
+				t.Errorf("Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
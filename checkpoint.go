@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// checkpoint records which accounts a scan has finished so that an
+// interrupted run can pick up where it left off with --resume.
+type checkpoint struct {
+	Profiles    []string            `json:"profiles"`
+	Completed   []string            `json:"completed"`
+	Offset      int64               `json:"offset"`      // size of the findings file after the last completed account
+	Buckets     map[string]int      `json:"buckets"`     // buckets scanned per completed profile
+	Errors      []scanError         `json:"errors"`      // from completed profiles
+	ACLs        map[string]aclCount `json:"acls"`        // per completed profile
+	Replication *replicationMap     `json:"replication"` // of completed profiles, with the replication check
+
+	dir string
+}
+
+func defaultCheckpointDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "s3-audit")
+}
+
+// loadCheckpoint returns the saved checkpoint when resuming, or starts a new
+// one (discarding any previous progress) otherwise.
+func loadCheckpoint(dir string, profiles []string, resume bool) (*checkpoint, error) {
+	cp := &checkpoint{Profiles: profiles, dir: dir}
+
+	if !resume {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, err
+		}
+
+		return cp, cp.removeFiles()
+	}
+
+	data, err := os.ReadFile(cp.path())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no checkpoint to resume from in %s", dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, err
+	}
+
+	if !slices.Equal(cp.Profiles, profiles) {
+		return nil, fmt.Errorf("checkpoint is for profiles %v, not %v", cp.Profiles, profiles)
+	}
+
+	return cp, nil
+}
+
+func (c *checkpoint) path() string {
+	return filepath.Join(c.dir, "checkpoint.json")
+}
+
+func (c *checkpoint) findingsPath() string {
+	return filepath.Join(c.dir, "findings.jsonl")
+}
+
+func (c *checkpoint) isCompleted(profile string) bool {
+	return slices.Contains(c.Completed, profile)
+}
+
+// markCompleted records profile as done. The file is replaced atomically so
+// an interruption mid-write can't leave a corrupt checkpoint behind.
+func (c *checkpoint) markCompleted(profile string, offset int64, buckets int, acls aclCount, errs []scanError) error {
+	c.Completed = append(c.Completed, profile)
+	c.Offset = offset
+	if c.Buckets == nil {
+		c.Buckets = map[string]int{}
+	}
+	c.Buckets[profile] = buckets
+	if c.ACLs == nil {
+		c.ACLs = map[string]aclCount{}
+	}
+	c.ACLs[profile] = acls
+	c.Errors = append(c.Errors, errs...)
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.path())
+}
+
+// clear removes the checkpoint and findings once a scan has finished.
+func (c *checkpoint) clear() error {
+	return c.removeFiles()
+}
+
+// removeFiles removes the files a scan keeps in the checkpoint directory.
+// The directory itself is left, as it may be shared: -checkpoint-dir can be
+// any directory, and holds the checkpoints of targeted scans and of each of
+// serve's schedules in subdirectories.
+func (c *checkpoint) removeFiles() error {
+	errs := []error{}
+	for _, path := range []string{c.path(), c.path() + ".tmp", c.findingsPath(), c.progressPath(), c.progressPath() + ".tmp"} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// accountProgress is how far the scan of the current account has got, for
+// the status API of s3-audit serve.
+type accountProgress struct {
+	Account string `json:"account"`
+	Buckets int    `json:"buckets"`
+	Scanned int    `json:"scanned"`
+	Errors  int    `json:"errors"`
+}
+
+func (c *checkpoint) progressPath() string {
+	return filepath.Join(c.dir, "progress.json")
+}
+
+// saveProgress records progress through the current account. It is only
+// informational, so failing to is ignored.
+func (c *checkpoint) saveProgress(p accountProgress) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+
+	tmp := c.progressPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err == nil {
+		os.Rename(tmp, c.progressPath())
+	}
+}
+
+// readProgress reads the checkpoint and progress a scan in dir has saved
+// since it started. Either is nil if there isn't one yet.
+func readProgress(dir string, since time.Time) (*checkpoint, *accountProgress) {
+	read := func(path string, v any) bool {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Before(since) {
+			return false
+		}
+		data, err := os.ReadFile(path)
+		return err == nil && json.Unmarshal(data, v) == nil
+	}
+
+	cp := &checkpoint{dir: dir}
+	if !read(cp.path(), cp) {
+		cp = nil
+	}
+	progress := &accountProgress{}
+	if !read(filepath.Join(dir, "progress.json"), progress) {
+		progress = nil
+	}
+
+	return cp, progress
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// Check looks for one kind of problem in a bucket's facts. Findings it
+// returns only need Check, Severity, Message and any details; the scanner
+// fills in the account, bucket and context common to every finding.
+type Check interface {
+	Name() string
+	Run(ctx context.Context, facts *BucketFacts) []Finding
+}
+
+// fallibleCheck is a check that can fail to evaluate, such as a custom rule
+// or plugin. The scanner calls runFallible instead of Run and records the
+// failure as a scan error for the bucket, so that a broken rule isn't
+// mistaken for a clean result.
+type fallibleCheck interface {
+	Check
+	runFallible(ctx context.Context, facts *BucketFacts) ([]Finding, error)
+}
+
+// builtinChecks returns the checks run on every bucket, in report order,
+// followed by any custom Rego and CEL rules and plugins, less any not
+// selected with -checks and -skip-checks.
+func (s *scanner) builtinChecks() ([]Check, error) {
+	complexity, err := paramsFor[policyComplexityParams](s.conf, "policy-complexity")
+	if err != nil {
+		return nil, err
+	}
+	kmsKeyPolicy, err := paramsFor[kmsKeyPolicyParams](s.conf, "kms-key-policy")
+	if err != nil {
+		return nil, err
+	}
+	unused, err := paramsFor[unusedBucketsParams](s.conf, "unused-buckets")
+	if err != nil {
+		return nil, err
+	}
+	storageCost, err := paramsFor[storageCostParams](s.conf, "storage-cost")
+	if err != nil {
+		return nil, err
+	}
+	typosquatting, err := paramsFor[typosquattingParams](s.conf, "typosquatting")
+	if err != nil {
+		return nil, err
+	}
+	lockParams, err := paramsFor[objectLockParams](s.conf, "object-lock")
+	if err != nil {
+		return nil, err
+	}
+	stateBuckets, err := paramsFor[stateBucketParams](s.conf, "state-bucket")
+	if err != nil {
+		return nil, err
+	}
+	if err := stateBuckets.compile(); err != nil {
+		return nil, err
+	}
+	if lockParams.Mode != "COMPLIANCE" && lockParams.Mode != "GOVERNANCE" {
+		return nil, errors.New("invalid parameters for object-lock: mode must be COMPLIANCE or GOVERNANCE")
+	}
+
+	checks := []Check{
+		publicAccessCheck{conf: s.conf, names: s.accountNames},
+		configDisagreementCheck{},
+		trustedAdvisorDisagreementCheck{},
+		cloudFrontOriginCheck{},
+		broadActionsCheck{},
+		confusedDeputyCheck{},
+		negatedElementsCheck{},
+		policyComplexityCheck{params: complexity},
+		policyDriftCheck{templates: s.templates},
+		danglingPrincipalsCheck{org: s.organization, names: s.accountNames},
+		dataEventsCheck{conf: s.conf},
+		logBucketCheck{},
+		stateBucketCheck{params: stateBuckets},
+		notificationTargetsCheck{names: s.accountNames},
+		requesterPaysCheck{names: s.accountNames},
+		objectLockCheck{params: lockParams},
+	}
+
+	if s.rules != nil {
+		checks = append(checks, s.rules)
+	}
+	for _, rule := range s.expressions {
+		checks = append(checks, rule)
+	}
+	for _, p := range s.plugins {
+		checks = append(checks, p)
+	}
+
+	checks = append(checks,
+		kmsKeyPolicyCheck{names: s.accountNames, params: kmsKeyPolicy},
+		publicObjectsCheck{},
+		unusedBucketsCheck{params: unused},
+		storageCostCheck{params: storageCost},
+		transferAccelerationCheck{},
+		typosquattingCheck{params: typosquatting},
+	)
+
+	return slices.DeleteFunc(checks, func(c Check) bool { return !s.selection.enabled(c.Name()) }), nil
+}
+
+// customCheckNames returns the names of the configured rules and plugins,
+// which can be chosen with -checks alongside the built-in checks.
+func customCheckNames(rules *ruleEngine, expressions []ExpressionRule, plugins []*plugin) []string {
+	names := []string{}
+	if rules != nil {
+		names = append(names, rules.Name())
+	}
+	for _, rule := range expressions {
+		names = append(names, rule.Name())
+	}
+	for _, p := range plugins {
+		names = append(names, p.Name())
+	}
+
+	return names
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+)
+
+// cloudFrontOrigin is a distribution reading from a bucket.
+type cloudFrontOrigin struct {
+	DistributionID string
+	Protected      bool // uses Origin Access Control or an Origin Access Identity
+	Website        bool // uses the S3 website endpoint, which can't use either
+}
+
+// s3OriginDomain matches S3 REST and website endpoints, e.g.
+// bucket.s3.eu-west-1.amazonaws.com or bucket.s3-website-eu-west-1.amazonaws.com.
+var s3OriginDomain = regexp.MustCompile(`^(.+?)\.s3(-website)?([.-][a-z0-9-]+)?\.amazonaws\.com$`)
+
+// getCloudFrontOrigins maps bucket names to the distributions in the account
+// that use them as origins.
+func getCloudFrontOrigins(ctx context.Context, client *cloudfront.Client) (map[string][]cloudFrontOrigin, error) {
+	origins := map[string][]cloudFrontOrigin{}
+
+	paginator := cloudfront.NewListDistributionsPaginator(client, &cloudfront.ListDistributionsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return origins, err
+		}
+
+		if page.DistributionList == nil {
+			continue
+		}
+
+		for _, dist := range page.DistributionList.Items {
+			if dist.Origins == nil {
+				continue
+			}
+
+			for _, origin := range dist.Origins.Items {
+				match := s3OriginDomain.FindStringSubmatch(aws.ToString(origin.DomainName))
+				if match == nil {
+					continue
+				}
+
+				hasOAI := origin.S3OriginConfig != nil && aws.ToString(origin.S3OriginConfig.OriginAccessIdentity) != ""
+				hasOAC := aws.ToString(origin.OriginAccessControlId) != ""
+
+				origins[match[1]] = append(origins[match[1]], cloudFrontOrigin{
+					DistributionID: aws.ToString(dist.Id),
+					Protected:      hasOAI || hasOAC,
+					Website:        match[2] != "",
+				})
+			}
+		}
+	}
+
+	return origins, nil
+}
+
+// cloudFrontOriginCheck explains how a public bucket relates to the
+// distributions in front of it: either they read it directly, which is
+// likely the only reason it is public, or they use OAC/OAI, in which case
+// the public access is probably unnecessary.
+type cloudFrontOriginCheck struct{}
+
+func (cloudFrontOriginCheck) Name() string { return "cloudfront-origin" }
+
+func (c cloudFrontOriginCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	origins := facts.CloudFrontOrigins
+	if len(origins) == 0 || !facts.Exposure.any() {
+		return nil
+	}
+
+	finding := Finding{Check: c.Name()}
+	unprotected := 0
+	for _, origin := range origins {
+		switch {
+		case origin.Website:
+			unprotected++
+			finding.Details = append(finding.Details, fmt.Sprintf("distribution %s uses the S3 website endpoint, which needs public access; use the REST endpoint with OAC unless website features are required", origin.DistributionID))
+		case !origin.Protected:
+			unprotected++
+			finding.Details = append(finding.Details, fmt.Sprintf("distribution %s reads the bucket without OAC or OAI; enable OAC and remove public access", origin.DistributionID))
+		default:
+			finding.Details = append(finding.Details, fmt.Sprintf("distribution %s already uses OAC/OAI, so public access shouldn't be needed for it", origin.DistributionID))
+		}
+	}
+
+	finding.Severity = SeverityMedium
+	switch unprotected {
+	case len(origins):
+		finding.Message = "public bucket is a CloudFront origin without OAC/OAI; it is likely only public so CloudFront can read it"
+	case 0:
+		finding.Message = "public bucket is only served through CloudFront with OAC/OAI; public access is probably unnecessary"
+	default:
+		finding.Message = "public bucket is a CloudFront origin, with and without OAC/OAI"
+	}
+
+	return []Finding{finding}
+}
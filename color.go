@@ -0,0 +1,29 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ANSI escapes for colouring severities in terminal output.
+const colorReset = "\x1b[0m"
+
+var severityColors = map[Severity]string{
+	SeverityLow:      "\x1b[36m",   // cyan
+	SeverityMedium:   "\x1b[33m",   // yellow
+	SeverityHigh:     "\x1b[31m",   // red
+	SeverityCritical: "\x1b[1;31m", // bold red
+}
+
+// colorize reports whether to colour output to w: only when it is a
+// terminal, and NO_COLOR (see https://no-color.org) isn't set.
+func colorize(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	return term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// subcommand is a subcommand and its flags, for shell completion. Scanning
+// is the default, and its flags are completed with or without "scan".
+type subcommand struct {
+	Name  string
+	Flags []string
+}
+
+var subcommands = []subcommand{
+	{"scan", []string{"config", "profiles", "resume", "checkpoint-dir", "account-names", "org-profile", "rules", "templates", "checks", "skip-checks", "inventory", "owners", "min-severity", "only-check", "owner", "group-by", "framework", "report", "triage", "triage-profile", "history-dir", "template", "sign", "sign-key", "timeout", "quiet", "summary", "emf", "otlp-endpoint", "log-format", "log-level", "debug", "output", "bucket", "no-write", "action-log", "endpoint-url", "s3-compatible", "discovery", "config-snapshot"}},
+	{"verify", []string{"profile", "endpoint-url"}},
+	{"list-checks", nil},
+	{"sweep", []string{"profile", "bucket", "fix", "role-arn", "report-bucket", "priority", "no-write", "action-log", "endpoint-url"}},
+	{"explain", []string{"profiles", "config", "endpoint-url"}},
+	{"diff", nil},
+	{"watch", []string{"profiles", "config", "bucket", "interval", "checks", "skip-checks", "account-names", "owners", "no-write", "action-log", "endpoint-url", "s3-compatible"}},
+	{"triage", []string{"history-dir", "triage", "profile"}},
+	{"preflight", []string{"profiles", "checks", "skip-checks", "endpoint-url"}},
+	{"iam-policy", []string{"checks", "skip-checks", "remediation", "role-arn"}},
+	{"serve", []string{"config", "checkpoint-dir", "history-dir", "log-format", "listen"}},
+	{"coordinate", []string{"profiles", "profile", "queue", "results", "history-dir", "timeout"}},
+	{"work", []string{"profile", "queue", "config"}},
+	{"grafana-dashboard", []string{"title"}},
+	{"glue-table", []string{"location", "table", "accounts"}},
+	{"inventory", []string{"profiles", "config", "owners", "format", "output", "endpoint-url"}},
+	{"import", []string{"merge", "output"}},
+	{"canary", []string{"profile", "config", "timeout", "interval", "output", "action-log", "endpoint-url"}},
+	{"completion", nil},
+}
+
+// completionMain prints a completion script for the shell. Bucket names are
+// completed from the latest saved report, which the script asks for with
+// -buckets.
+func completionMain(args []string) {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	buckets := fs.Bool("buckets", false, "list the buckets in the latest saved report, for completing -bucket")
+	historyDir := fs.String("history-dir", defaultHistoryDir(), "directory where completed scans are saved")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit completion bash|zsh|fish")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *buckets {
+		report, err := latestReport(*historyDir)
+		check(err, "unable to load report")
+		if report != nil {
+			for _, name := range reportBuckets(report) {
+				fmt.Println(name)
+			}
+		}
+		return
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	switch fs.Arg(0) {
+	case "bash":
+		printBashCompletion(os.Stdout)
+	case "zsh":
+		// zsh can run bash completion functions.
+		fmt.Println("autoload -U +X bashcompinit && bashcompinit")
+		printBashCompletion(os.Stdout)
+	case "fish":
+		printFishCompletion(os.Stdout)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// reportBuckets returns the buckets with findings in the report, sorted.
+func reportBuckets(r *savedReport) []string {
+	names := []string{}
+	for _, f := range r.Findings {
+		if f.Bucket != "" && !slices.Contains(names, f.Bucket) {
+			names = append(names, f.Bucket)
+		}
+	}
+
+	slices.Sort(names)
+	return names
+}
+
+func checkIDs() string {
+	ids := []string{}
+	for _, c := range registry {
+		ids = append(ids, c.ID)
+	}
+
+	return strings.Join(ids, " ")
+}
+
+func subcommandNames() string {
+	names := []string{}
+	for _, sub := range subcommands {
+		names = append(names, sub.Name)
+	}
+
+	return strings.Join(names, " ")
+}
+
+func dashed(flags []string) string {
+	dashed := []string{}
+	for _, f := range flags {
+		dashed = append(dashed, "-"+f)
+	}
+
+	return strings.Join(dashed, " ")
+}
+
+func printBashCompletion(w io.Writer) {
+	fmt.Fprintf(w, `_s3_audit() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}" flags
+    case "$prev" in
+        -bucket|--bucket)
+            COMPREPLY=($(compgen -W "$(s3-audit completion -buckets 2>/dev/null)" -- "$cur"))
+            return ;;
+        -checks|--checks|-skip-checks|--skip-checks|-only-check|--only-check)
+            COMPREPLY=($(compgen -W %q -- "$cur"))
+            return ;;
+    esac
+    if [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then
+        COMPREPLY=($(compgen -W %q -- "$cur"))
+        return
+    fi
+    case "${COMP_WORDS[1]}" in
+`, checkIDs(), subcommandNames())
+	for _, sub := range subcommands[1:] {
+		fmt.Fprintf(w, "        %s) flags=%q ;;\n", sub.Name, dashed(sub.Flags))
+	}
+	fmt.Fprintf(w, `        *) flags=%q ;;
+    esac
+    COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+}
+complete -o default -F _s3_audit s3-audit
+`, dashed(subcommands[0].Flags))
+}
+
+func printFishCompletion(w io.Writer) {
+	others := strings.Join(strings.Fields(subcommandNames())[1:], " ")
+	fmt.Fprintln(w, "complete -c s3-audit -f")
+	fmt.Fprintf(w, "complete -c s3-audit -n __fish_use_subcommand -a %q\n", subcommandNames())
+	for _, sub := range subcommands {
+		condition := fmt.Sprintf("__fish_seen_subcommand_from %s", sub.Name)
+		if sub.Name == "scan" {
+			condition = "not __fish_seen_subcommand_from " + others
+		}
+		for _, f := range sub.Flags {
+			fmt.Fprintf(w, "complete -c s3-audit -n %q -o %s\n", condition, f)
+		}
+	}
+	fmt.Fprintln(w, `complete -c s3-audit -o bucket -x -a "(s3-audit completion -buckets 2>/dev/null)"`)
+	fmt.Fprintf(w, "complete -c s3-audit -o checks -o skip-checks -o only-check -x -a %q\n", checkIDs())
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+)
+
+// Config is the optional JSON config file passed with -config.
+type Config struct {
+	// Expressions are inline CEL rules evaluated against each bucket.
+	Expressions []ExpressionRule `json:"expressions"`
+
+	// Checks sets the parameters of built-in checks by ID, see checkParams.
+	Checks map[string]json.RawMessage `json:"checks"`
+
+	// Plugins are external checks, see Plugin.
+	Plugins []Plugin `json:"plugins"`
+
+	// SensitiveTags marks buckets as sensitive when they have one of the
+	// given values for a tag, e.g. {"DataClassification": ["pii"]}.
+	SensitiveTags map[string][]string `json:"sensitiveTags"`
+
+	// SensitiveNames are regular expressions, e.g. "backup" or "pii", that
+	// mark a bucket as likely to hold sensitive data when they match its
+	// name or any of its tag keys or values. Every finding on such a bucket
+	// is raised a severity.
+	SensitiveNames []string `json:"sensitiveNames"`
+	sensitiveNames []*regexp.Regexp
+
+	// Scoring overrides how public-access findings are graded.
+	Scoring Scoring `json:"scoring"`
+
+	// EgressPricePerGB is the data transfer price used to estimate what
+	// public buckets cost in downloads, in USD.
+	EgressPricePerGB float64 `json:"egressPricePerGB"`
+
+	// Timezone (an IANA name) and TimeFormat (a Go time layout) are used for
+	// times in reports. They default to UTC and RFC 3339.
+	Timezone   string `json:"timezone"`
+	TimeFormat string `json:"timeFormat"`
+
+	// Endpoints overrides the endpoint URL per service, keyed as in the
+	// shared AWS config file's services section (e.g. s3, s3_control, sts).
+	// -endpoint-url applies to services without an entry.
+	Endpoints map[string]string `json:"endpoints"`
+
+	// S3Compatible is set when the S3 endpoint is an S3-compatible store
+	// such as MinIO or Ceph rather than AWS: only S3 is called, and checks
+	// of AWS-only features are skipped. See s3CompatibleChecks.
+	S3Compatible bool `json:"s3Compatible"`
+
+	// Outputs are where the findings are sent as well as stdout, see Output.
+	Outputs []Output `json:"outputs"`
+
+	// Routing sends each team its own findings, see Routing.
+	Routing Routing `json:"routing"`
+
+	// Schedules are the scans s3-audit serve runs, see Schedule.
+	Schedules []Schedule `json:"schedules"`
+}
+
+func loadConfigFile(path string) (*Config, error) {
+	conf := &Config{}
+	if path == "" {
+		return conf, conf.Scoring.validate()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, conf); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	if err := conf.Scoring.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	if err := conf.validateCheckParams(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	for _, pattern := range conf.SensitiveNames {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config %s: sensitive name: %w", path, err)
+		}
+		conf.sensitiveNames = append(conf.sensitiveNames, re)
+	}
+
+	for _, o := range conf.Outputs {
+		if err := o.validate(); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+	}
+
+	if err := conf.Routing.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	for i, s := range conf.Schedules {
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+		if slices.ContainsFunc(conf.Schedules[:i], func(other Schedule) bool { return other.Name == s.Name }) {
+			return nil, fmt.Errorf("invalid config %s: more than one schedule named %s", path, s.Name)
+		}
+	}
+
+	return conf, nil
+}
+
+func (c *Config) isSensitive(tags map[string]string) bool {
+	return hasTag(tags, c.SensitiveTags)
+}
+
+// sensitiveNameMatch returns what about the bucket matches one of
+// SensitiveNames, or "" if nothing does.
+func (c *Config) sensitiveNameMatch(name string, tags map[string]string) string {
+	for _, re := range c.sensitiveNames {
+		if re.MatchString(name) {
+			return fmt.Sprintf("name matches %s", re)
+		}
+		for _, key := range slices.Sorted(maps.Keys(tags)) {
+			if re.MatchString(key) || re.MatchString(tags[key]) {
+				return fmt.Sprintf("tag %s=%s matches %s", key, tags[key], re)
+			}
+		}
+	}
+
+	return ""
+}
+
+// escalateSensitiveName raises a finding on a bucket whose name or tags
+// match SensitiveNames by one severity, noting why.
+func (c *Config) escalateSensitiveName(facts *BucketFacts, finding *Finding) {
+	match := c.sensitiveNameMatch(facts.Name, facts.Tags)
+	if match == "" {
+		return
+	}
+
+	if finding.Severity < SeverityCritical {
+		finding.Severity++
+	}
+	finding.Details = append(finding.Details, fmt.Sprintf("likely sensitive contents: %s", match))
+}
+
+func (c *Config) egressPricePerGB() float64 {
+	if c.EgressPricePerGB > 0 {
+		return c.EgressPricePerGB
+	}
+
+	return defaultEgressPricePerGB
+}
+
+func (c *Config) isProduction(facts *BucketFacts) bool {
+	isProductionAccount := slices.Contains(c.Scoring.ProductionAccounts, facts.AccountID) ||
+		slices.Contains(c.Scoring.ProductionAccounts, facts.Account)
+
+	return isProductionAccount || hasTag(facts.Tags, c.Scoring.ProductionTags)
+}
+
+// hasTag reports whether tags has one of the wanted values for any key.
+func hasTag(tags map[string]string, wanted map[string][]string) bool {
+	for key, values := range wanted {
+		if value, ok := tags[key]; ok && slices.Contains(values, value) {
+			return true
+		}
+	}
+
+	return false
+}
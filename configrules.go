@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/configservice"
+	cstypes "github.com/aws/aws-sdk-go-v2/service/configservice/types"
+)
+
+// publicAccessConfigRules are the source identifiers of the AWS managed
+// Config rules that overlap with the public-access check. Rule names vary
+// (Security Hub adds its own prefix) so rules are found by identifier.
+var publicAccessConfigRules = map[string]bool{
+	"S3_BUCKET_PUBLIC_READ_PROHIBITED":  true,
+	"S3_BUCKET_PUBLIC_WRITE_PROHIBITED": true,
+}
+
+// configEvaluation is a public-access Config rule's evaluation of a bucket.
+type configEvaluation struct {
+	Identifier string `json:"identifier"` // the managed rule, one of publicAccessConfigRules
+	Compliance string `json:"compliance"` // COMPLIANT or NON_COMPLIANT
+}
+
+// getConfigCompliance maps bucket names to the evaluation of each
+// public-access Config rule, keyed by rule name. Config is regional, so each
+// of regions is queried; a region that fails is skipped and its error
+// returned along with the others' results.
+func getConfigCompliance(ctx context.Context, config aws.Config, regions []string) (map[string]map[string]configEvaluation, error) {
+	errs := []error{}
+	compliance := map[string]map[string]configEvaluation{}
+
+	for _, region := range regions {
+		client := configservice.NewFromConfig(config, func(o *configservice.Options) { o.Region = region })
+
+		rules := map[string]string{} // name to source identifier
+		paginator := configservice.NewDescribeConfigRulesPaginator(client, &configservice.DescribeConfigRulesInput{})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to list Config rules in %s: %w", region, err))
+				break
+			}
+
+			for _, rule := range page.ConfigRules {
+				if rule.Source != nil && rule.Source.Owner == cstypes.OwnerAws && publicAccessConfigRules[aws.ToString(rule.Source.SourceIdentifier)] {
+					rules[aws.ToString(rule.ConfigRuleName)] = aws.ToString(rule.Source.SourceIdentifier)
+				}
+			}
+		}
+
+		for rule, identifier := range rules {
+			paginator := configservice.NewGetComplianceDetailsByConfigRulePaginator(client, &configservice.GetComplianceDetailsByConfigRuleInput{
+				ConfigRuleName:  aws.String(rule),
+				ComplianceTypes: []cstypes.ComplianceType{cstypes.ComplianceTypeCompliant, cstypes.ComplianceTypeNonCompliant},
+			})
+			for paginator.HasMorePages() {
+				page, err := paginator.NextPage(ctx)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("unable to get compliance for Config rule %s in %s: %w", rule, region, err))
+					break
+				}
+
+				for _, result := range page.EvaluationResults {
+					if result.EvaluationResultIdentifier == nil || result.EvaluationResultIdentifier.EvaluationResultQualifier == nil {
+						continue
+					}
+
+					bucket := aws.ToString(result.EvaluationResultIdentifier.EvaluationResultQualifier.ResourceId)
+					if compliance[bucket] == nil {
+						compliance[bucket] = map[string]configEvaluation{}
+					}
+					compliance[bucket][rule] = configEvaluation{Identifier: identifier, Compliance: string(result.ComplianceType)}
+				}
+			}
+		}
+	}
+
+	return compliance, errors.Join(errs...)
+}
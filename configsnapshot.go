@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/guardian/s3-audit/policy"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// An offline scan (-config-snapshot) reads buckets' configuration from AWS
+// Config snapshots, configuration history files or the output of
+// get-resource-config-history and batch-get-resource-config, rather than
+// calling the accounts. Only offlineChecks of the built-in checks run, as
+// Config records neither the probe's result nor the other services'.
+
+// configItem is a configuration item as Config records it. The
+// configuration and supplementary configuration are JSON objects in
+// snapshots, but strings of JSON from the API.
+type configItem struct {
+	AccountID     string                     `json:"awsAccountId"`
+	Status        string                     `json:"configurationItemStatus"`
+	CaptureTime   time.Time                  `json:"configurationItemCaptureTime"`
+	ResourceType  string                     `json:"resourceType"`
+	ResourceName  string                     `json:"resourceName"`
+	Region        string                     `json:"awsRegion"`
+	CreationTime  *time.Time                 `json:"resourceCreationTime"`
+	Tags          map[string]string          `json:"tags"`
+	Supplementary map[string]json.RawMessage `json:"supplementaryConfiguration"`
+}
+
+// configFile is any of the files Config items are read from.
+type configFile struct {
+	ConfigurationItems     []configItem `json:"configurationItems"`
+	BaseConfigurationItems []configItem `json:"baseConfigurationItems"`
+}
+
+// configSnapshots are the buckets recorded in Config snapshots, by account
+// ID and then bucket name.
+type configSnapshots map[string]map[string]*BucketFacts
+
+// accounts returns the IDs of the accounts with buckets recorded, which an
+// offline scan scans in place of profiles.
+func (cs configSnapshots) accounts() []string {
+	return slices.Sorted(maps.Keys(cs))
+}
+
+// loadConfigSnapshots reads every Config file at the locations: files,
+// directories, or s3://bucket/prefix read with the profile. Files may be
+// gzipped, as Config delivers them. Where a bucket is recorded more than
+// once its latest configuration is used, and buckets last recorded as
+// deleted are left out.
+func loadConfigSnapshots(ctx context.Context, locations []string, profile string) (configSnapshots, error) {
+	latest := map[string]configItem{}
+	read := func(name string, data []byte) error {
+		items, err := parseConfigFile(data)
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %w", name, err)
+		}
+		for _, item := range items {
+			if item.ResourceType != "AWS::S3::Bucket" {
+				continue
+			}
+			id := item.AccountID + "/" + item.ResourceName
+			if prev, ok := latest[id]; !ok || item.CaptureTime.After(prev.CaptureTime) {
+				latest[id] = item
+			}
+		}
+		return nil
+	}
+
+	for _, location := range locations {
+		var err error
+		if strings.HasPrefix(location, "s3://") {
+			err = readS3ConfigFiles(ctx, location, profile, read)
+		} else {
+			err = readLocalConfigFiles(location, read)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	snapshots := configSnapshots{}
+	for _, item := range latest {
+		if strings.HasPrefix(item.Status, "ResourceDeleted") {
+			continue
+		}
+		facts, err := snapshotFacts(item)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read configuration of %s: %w", item.ResourceName, err)
+		}
+		if snapshots[item.AccountID] == nil {
+			snapshots[item.AccountID] = map[string]*BucketFacts{}
+		}
+		snapshots[item.AccountID][item.ResourceName] = facts
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no buckets found in %s", strings.Join(locations, ", "))
+	}
+
+	return snapshots, nil
+}
+
+// parseConfigFile returns the configuration items in a file, gunzipping it
+// if need be.
+func parseConfigFile(data []byte) ([]configItem, error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, err
+		}
+	}
+
+	var file configFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	return append(file.ConfigurationItems, file.BaseConfigurationItems...), nil
+}
+
+// isConfigFile reports whether a file or object looks like Config output.
+func isConfigFile(name string) bool {
+	return strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".json.gz")
+}
+
+func readLocalConfigFiles(location string, read func(string, []byte) error) error {
+	return filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (path != location && !isConfigFile(path)) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return read(path, data)
+	})
+}
+
+// readS3ConfigFiles reads the object at location, or every Config file
+// under it as a prefix, such as the AWSLogs/ prefix of a delivery channel.
+func readS3ConfigFiles(ctx context.Context, location string, profile string, read func(string, []byte) error) error {
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+	config, err := loadConfig(ctx, profile)
+	if err != nil {
+		return fmt.Errorf("unable to load AWS config: %w", err)
+	}
+	client, err := regionalS3Client(ctx, config, bucket)
+	if err != nil {
+		return err
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{Bucket: &bucket, Prefix: &prefix})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to list %s: %w", location, err)
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			if key != prefix && !isConfigFile(key) {
+				continue
+			}
+
+			out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key})
+			if err != nil {
+				return fmt.Errorf("unable to get s3://%s/%s: %w", bucket, key, err)
+			}
+			data, err := io.ReadAll(out.Body)
+			out.Body.Close()
+			if err != nil {
+				return fmt.Errorf("unable to get s3://%s/%s: %w", bucket, key, err)
+			}
+			if err := read("s3://"+bucket+"/"+key, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// supplementary decodes the item's supplementary configuration of the
+// given type into v, leaving v unchanged if there is none.
+func (item configItem) supplementary(name string, v any) error {
+	raw := item.Supplementary[name]
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		raw = json.RawMessage(s)
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// configACLGroups are the grantee groups as Config names them.
+var configACLGroups = map[string]string{
+	"AllUsers":           "http://acs.amazonaws.com/groups/global/AllUsers",
+	"AuthenticatedUsers": "http://acs.amazonaws.com/groups/global/AuthenticatedUsers",
+	"LogDelivery":        "http://acs.amazonaws.com/groups/s3/LogDelivery",
+}
+
+// configACLPermissions are the ACL permissions as Config names them.
+var configACLPermissions = map[string]string{
+	"FullControl": "FULL_CONTROL",
+	"Read":        "READ",
+	"Write":       "WRITE",
+	"ReadAcp":     "READ_ACP",
+	"WriteAcp":    "WRITE_ACP",
+}
+
+// snapshotFacts is what a configuration item records of a bucket's facts.
+// The rest, such as the probe's result, are left empty.
+func snapshotFacts(item configItem) (*BucketFacts, error) {
+	facts := &BucketFacts{
+		Account:   item.AccountID,
+		AccountID: item.AccountID,
+		Name:      item.ResourceName,
+		Region:    item.Region,
+		Created:   item.CreationTime,
+		Tags:      map[string]string{},
+	}
+	maps.Copy(facts.Tags, item.Tags)
+
+	var bucketPolicy struct {
+		PolicyText *string `json:"policyText"`
+	}
+	var acl struct {
+		Owner struct {
+			ID string `json:"id"`
+		} `json:"owner"`
+		GrantList []struct {
+			Grantee    json.RawMessage `json:"grantee"`
+			Permission string          `json:"permission"`
+		} `json:"grantList"`
+	}
+	var pab *struct {
+		BlockPublicAcls       bool `json:"blockPublicAcls"`
+		IgnorePublicAcls      bool `json:"ignorePublicAcls"`
+		BlockPublicPolicy     bool `json:"blockPublicPolicy"`
+		RestrictPublicBuckets bool `json:"restrictPublicBuckets"`
+	}
+	var encryption struct {
+		Rules []struct {
+			Default *struct {
+				SSEAlgorithm   string `json:"sseAlgorithm"`
+				KMSMasterKeyID string `json:"kmsMasterKeyID"`
+			} `json:"applyServerSideEncryptionByDefault"`
+			BucketKeyEnabled bool `json:"bucketKeyEnabled"`
+		} `json:"rules"`
+	}
+	var tagging struct {
+		TagSets []struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"tagSets"`
+	}
+	var accelerate struct {
+		Status string `json:"status"`
+	}
+
+	for name, v := range map[string]any{
+		"BucketPolicy":                      &bucketPolicy,
+		"AccessControlList":                 &acl,
+		"PublicAccessBlockConfiguration":    &pab,
+		"ServerSideEncryptionConfiguration": &encryption,
+		"BucketTaggingConfiguration":        &tagging,
+		"BucketAccelerateConfiguration":     &accelerate,
+		"IsRequesterPaysEnabled":            &facts.RequesterPays,
+	} {
+		if err := item.supplementary(name, v); err != nil {
+			return nil, err
+		}
+	}
+
+	if bucketPolicy.PolicyText != nil {
+		p, err := policy.Parse(*bucketPolicy.PolicyText)
+		if err != nil {
+			return nil, fmt.Errorf("BucketPolicy: %w", err)
+		}
+		metrics := p.Metrics()
+		facts.Policy, facts.PolicyMetrics = p, &metrics
+	}
+
+	if item.Supplementary["AccessControlList"] != nil {
+		facts.ACL = &ACL{Owner: acl.Owner.ID, Grants: []Grant{}}
+		for _, g := range acl.GrantList {
+			grant := Grant{Permission: configACLPermissions[g.Permission]}
+			if grant.Permission == "" {
+				grant.Permission = strings.ToUpper(g.Permission)
+			}
+
+			var group string
+			var canonical struct {
+				ID string `json:"id"`
+			}
+			switch {
+			case json.Unmarshal(g.Grantee, &group) == nil:
+				grant.Type, grant.Grantee = string(s3types.TypeGroup), group
+				if uri, ok := configACLGroups[group]; ok {
+					grant.Grantee = uri
+				}
+			case json.Unmarshal(g.Grantee, &canonical) == nil && canonical.ID != "":
+				grant.Type, grant.Grantee = string(s3types.TypeCanonicalUser), canonical.ID
+			default:
+				continue
+			}
+			facts.ACL.Grants = append(facts.ACL.Grants, grant)
+		}
+	}
+
+	if pab != nil {
+		facts.PublicAccessBlock = &PublicAccessBlock{
+			BlockPublicAcls:       pab.BlockPublicAcls,
+			IgnorePublicAcls:      pab.IgnorePublicAcls,
+			BlockPublicPolicy:     pab.BlockPublicPolicy,
+			RestrictPublicBuckets: pab.RestrictPublicBuckets,
+		}
+	}
+
+	for _, rule := range encryption.Rules {
+		if rule.Default == nil {
+			continue
+		}
+		algorithm := s3types.ServerSideEncryption(rule.Default.SSEAlgorithm)
+		facts.Encryption = &Encryption{
+			Algorithm:        string(algorithm),
+			KMS:              algorithm == s3types.ServerSideEncryptionAwsKms || algorithm == s3types.ServerSideEncryptionAwsKmsDsse,
+			KMSKeyID:         rule.Default.KMSMasterKeyID,
+			BucketKeyEnabled: rule.BucketKeyEnabled,
+		}
+		break
+	}
+
+	if len(facts.Tags) == 0 {
+		for _, set := range tagging.TagSets {
+			maps.Copy(facts.Tags, set.Tags)
+		}
+	}
+
+	if accelerate.Status != "" {
+		facts.Acceleration = &acceleration{Enabled: accelerate.Status == string(s3types.BucketAccelerateStatusEnabled)}
+	}
+
+	facts.Exposure.Policy = facts.Policy != nil && facts.Policy.IsPublic()
+
+	return facts, nil
+}
+
+// scanSnapshotAccount runs the checks on the buckets recorded for the
+// account, as scanAccount does on those it lists.
+func (s *scanner) scanSnapshotAccount(ctx context.Context, accountID string) (int, []scanError, error) {
+	ctx, span := tracer.Start(ctx, "scanAccount", trace.WithAttributes(attribute.String("account", accountID), attribute.Bool("offline", true)))
+	defer span.End()
+
+	as := &accountScan{
+		account:     account{Profile: accountID, ID: accountID},
+		log:         component("scan").With("scan", scanID(ctx), "account", accountID),
+		snapshot:    s.snapshots[accountID],
+		bucketNames: map[string]bool{},
+	}
+
+	buckets := []s3types.Bucket{}
+	for _, name := range slices.Sorted(maps.Keys(as.snapshot)) {
+		as.bucketNames[name] = true
+		buckets = append(buckets, s3types.Bucket{Name: aws.String(name), BucketRegion: aws.String(as.snapshot[name].Region)})
+	}
+	if len(s.buckets) > 0 {
+		buckets = s.targetBuckets(buckets)
+	}
+
+	if err := s.scanBuckets(ctx, as, buckets); err != nil {
+		return 0, as.errors, err
+	}
+	span.SetAttributes(attribute.Int("buckets", len(buckets)), attribute.Int("errors", len(as.errors)))
+
+	return len(buckets), as.errors, nil
+}
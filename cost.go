@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/guardian/s3-audit/awsapi"
+)
+
+// costChecks are advisory checks about spend rather than security. Their
+// findings are reported in a section of their own and don't count towards
+// account scores.
+var costChecks = []string{"unused-buckets", "storage-cost"}
+
+func isCostFinding(f Finding) bool {
+	return slices.Contains(costChecks, f.Check)
+}
+
+// printCostFindings prints the cost findings section of the report, after a
+// blank line, if there are any.
+func printCostFindings(w io.Writer, findings findingStream) error {
+	n := 0
+	cols := columns{}
+	err := findings(func(f Finding) error {
+		n++
+		cols.fit(f)
+		return nil
+	})
+	if err != nil || n == 0 {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nCost findings (%d) - advisory, not counted in scores:\n", n)
+	return findings(func(f Finding) error {
+		cols.print(w, f)
+		return nil
+	})
+}
+
+// storageTiering is whether anything moves the bucket's objects out of S3
+// Standard as they age.
+type storageTiering struct {
+	Transitions        bool `json:"transitions"`        // an enabled lifecycle rule transitions objects
+	IntelligentTiering bool `json:"intelligentTiering"` // an Intelligent-Tiering archive configuration is enabled
+}
+
+func getStorageTiering(ctx context.Context, client awsapi.S3, bucketName string, region string) (*storageTiering, error) {
+	tiering := &storageTiering{}
+
+	lifecycle, err := client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{Bucket: &bucketName}, inRegion(region))
+	if err != nil && !isErrorCode(err, "NoSuchLifecycleConfiguration") {
+		return nil, fmt.Errorf("unable to get lifecycle configuration: %w", err)
+	}
+	if lifecycle != nil {
+		for _, rule := range lifecycle.Rules {
+			if rule.Status == s3types.ExpirationStatusEnabled && (len(rule.Transitions) > 0 || len(rule.NoncurrentVersionTransitions) > 0) {
+				tiering.Transitions = true
+			}
+		}
+	}
+
+	input := &s3.ListBucketIntelligentTieringConfigurationsInput{Bucket: &bucketName}
+	for {
+		out, err := client.ListBucketIntelligentTieringConfigurations(ctx, input, inRegion(region))
+		if err != nil {
+			return nil, fmt.Errorf("unable to list Intelligent-Tiering configurations: %w", err)
+		}
+
+		for _, conf := range out.IntelligentTieringConfigurationList {
+			if conf.Status == s3types.IntelligentTieringStatusEnabled {
+				tiering.IntelligentTiering = true
+			}
+		}
+
+		if !aws.ToBool(out.IsTruncated) {
+			return tiering, nil
+		}
+		input.ContinuationToken = out.NextContinuationToken
+	}
+}
+
+// storageCostCheck reports large buckets kept entirely in S3 Standard, with
+// no lifecycle transitions or Intelligent-Tiering to move what is no longer
+// read to cheaper storage.
+type storageCostCheck struct {
+	params storageCostParams
+}
+
+func (storageCostCheck) Name() string { return "storage-cost" }
+
+func (c storageCostCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	tiering := facts.Tiering
+	if tiering == nil || tiering.Transitions || tiering.IntelligentTiering || facts.Size == nil {
+		return nil
+	}
+
+	return []Finding{{
+		Check:    c.Name(),
+		Severity: SeverityLow,
+		Message:  fmt.Sprintf("%s in S3 Standard with no lifecycle transitions or Intelligent-Tiering", formatBytes(facts.Size.StandardBytes)),
+	}}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	cttypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+)
+
+// dataEventSelector is a trail's selector for S3 object-level (data) events,
+// reduced to the object ARN prefixes it includes and excludes. No includes
+// means every bucket.
+type dataEventSelector struct {
+	Trail    string
+	Includes []string
+	Excludes []string
+}
+
+func (s dataEventSelector) covers(bucket string) bool {
+	objects := "arn:aws:s3:::" + bucket + "/"
+
+	for _, prefix := range s.Excludes {
+		if strings.HasPrefix(objects, prefix) {
+			return false
+		}
+	}
+
+	if len(s.Includes) == 0 {
+		return true
+	}
+
+	// A prefix within the bucket gives partial coverage, which we count.
+	for _, prefix := range s.Includes {
+		if strings.HasPrefix(objects, prefix) || strings.HasPrefix(prefix, objects) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getDataEventSelectors returns the S3 data event selectors of every trail
+// that is currently logging, including organization and multi-region trails
+// homed elsewhere.
+func getDataEventSelectors(ctx context.Context, client *cloudtrail.Client) ([]dataEventSelector, error) {
+	trails, err := client.DescribeTrails(ctx, &cloudtrail.DescribeTrailsInput{IncludeShadowTrails: aws.Bool(true)})
+	if err != nil {
+		return nil, err
+	}
+
+	selectors := []dataEventSelector{}
+	for _, trail := range trails.TrailList {
+		inHomeRegion := func(o *cloudtrail.Options) { o.Region = aws.ToString(trail.HomeRegion) }
+
+		status, err := client.GetTrailStatus(ctx, &cloudtrail.GetTrailStatusInput{Name: trail.TrailARN}, inHomeRegion)
+		if err != nil {
+			return nil, err
+		}
+		if !aws.ToBool(status.IsLogging) {
+			continue
+		}
+
+		out, err := client.GetEventSelectors(ctx, &cloudtrail.GetEventSelectorsInput{TrailName: trail.TrailARN}, inHomeRegion)
+		if err != nil {
+			return nil, err
+		}
+
+		name := aws.ToString(trail.Name)
+		selectors = append(selectors, basicDataEventSelectors(name, out.EventSelectors)...)
+		selectors = append(selectors, advancedDataEventSelectors(name, out.AdvancedEventSelectors)...)
+	}
+
+	return selectors, nil
+}
+
+func basicDataEventSelectors(trail string, eventSelectors []cttypes.EventSelector) []dataEventSelector {
+	selectors := []dataEventSelector{}
+
+	for _, es := range eventSelectors {
+		for _, resource := range es.DataResources {
+			if aws.ToString(resource.Type) != "AWS::S3::Object" {
+				continue
+			}
+
+			// "arn:aws:s3" (or "arn:aws:s3:::") selects every bucket.
+			includes := resource.Values
+			if slices.Contains(includes, "arn:aws:s3") || slices.Contains(includes, "arn:aws:s3:::") {
+				includes = nil
+			}
+
+			selectors = append(selectors, dataEventSelector{Trail: trail, Includes: includes})
+		}
+	}
+
+	return selectors
+}
+
+func advancedDataEventSelectors(trail string, eventSelectors []cttypes.AdvancedEventSelector) []dataEventSelector {
+	selectors := []dataEventSelector{}
+
+	for _, es := range eventSelectors {
+		fields := map[string]cttypes.AdvancedFieldSelector{}
+		for _, field := range es.FieldSelectors {
+			fields[aws.ToString(field.Field)] = field
+		}
+
+		if !slices.Contains(fields["eventCategory"].Equals, "Data") || !slices.Contains(fields["resources.type"].Equals, "AWS::S3::Object") {
+			continue
+		}
+
+		arn := fields["resources.ARN"]
+		selectors = append(selectors, dataEventSelector{
+			Trail:    trail,
+			Includes: append(append([]string{}, arn.StartsWith...), arn.Equals...),
+			Excludes: append(append([]string{}, arn.NotStartsWith...), arn.NotEquals...),
+		})
+	}
+
+	return selectors
+}
+
+// dataEventTrails returns the trails logging object-level events for bucket.
+func dataEventTrails(selectors []dataEventSelector, bucket string) []string {
+	trails := []string{}
+	for _, s := range selectors {
+		if s.covers(bucket) && !slices.Contains(trails, s.Trail) {
+			trails = append(trails, s.Trail)
+		}
+	}
+
+	return trails
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// debugRequests logs every AWS request, set by -debug.
+var debugRequests bool
+
+// sensitiveQueryParams are redacted from logged URLs, as presigned URLs
+// carry credentials in the query string. Headers aren't logged at all.
+var sensitiveQueryParams = []string{"X-Amz-Credential", "X-Amz-Security-Token", "X-Amz-Signature"}
+
+// requestLog is filled in as a request passes down the middleware stack, and
+// logged by logRequests once it completes.
+type requestLog struct {
+	method string
+	url    string
+}
+
+type requestLogKey struct{}
+
+// addRequestLogging adds request logging to a client's middleware stack:
+// once per operation, after any retries, so throttling shows up as retries.
+func addRequestLogging(stack *middleware.Stack) error {
+	err := stack.Initialize.Add(middleware.InitializeMiddlewareFunc("s3-audit/logRequests", logRequests), middleware.Before)
+	if err != nil {
+		return err
+	}
+
+	// Deserialize is the last step before the request is sent, so the
+	// endpoint has been resolved.
+	return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("s3-audit/recordRequest", recordRequest), middleware.After)
+}
+
+func logRequests(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	req := &requestLog{}
+	ctx = middleware.WithStackValue(ctx, requestLogKey{}, req)
+
+	start := time.Now()
+	out, metadata, err := next.HandleInitialize(ctx, in)
+
+	attrs := []any{
+		"service", awsmiddleware.GetServiceID(ctx),
+		"operation", awsmiddleware.GetOperationName(ctx),
+		"method", req.method,
+		"endpoint", req.url,
+		"duration", time.Since(start),
+	}
+	if resp, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok {
+		attrs = append(attrs, "status", resp.StatusCode)
+	}
+	if id, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+		attrs = append(attrs, "requestId", id)
+	}
+	if attempts, ok := retry.GetAttemptResults(metadata); ok && len(attempts.Results) > 1 {
+		attrs = append(attrs, "retries", len(attempts.Results)-1)
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	component("aws").DebugContext(ctx, "request", attrs...)
+
+	return out, metadata, err
+}
+
+func recordRequest(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+	req, _ := middleware.GetStackValue(ctx, requestLogKey{}).(*requestLog)
+	if r, ok := in.Request.(*smithyhttp.Request); ok && req != nil {
+		req.method = r.Method
+		req.url = redactURL(r.URL)
+	}
+
+	return next.HandleDeserialize(ctx, in)
+}
+
+// redactURL returns u without sensitive query parameters' values.
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	redacted := *u
+	redacted.User = nil
+
+	query := redacted.Query()
+	for key := range query {
+		for _, sensitive := range sensitiveQueryParams {
+			if strings.EqualFold(key, sensitive) {
+				query.Set(key, "REDACTED")
+			}
+		}
+	}
+	redacted.RawQuery = query.Encode()
+
+	return redacted.String()
+}
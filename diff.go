@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// diffMain compares two saved reports, e.g. last week's and this week's, or
+// from before and after remediation.
+func diffMain(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit diff before.json after.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	before, err := loadReport(fs.Arg(0))
+	check(err, "unable to load report")
+	after, err := loadReport(fs.Arg(1))
+	check(err, "unable to load report")
+
+	printDiff(os.Stdout, before, after)
+}
+
+// findingChange is a finding in both reports whose message, severity, score
+// or details differ.
+type findingChange struct {
+	Before Finding
+	After  Finding
+}
+
+// changedFindings returns the findings in both before and after that have
+// changed, in the order of after.
+func changedFindings(before []Finding, after []Finding) []findingChange {
+	beforeKeys := map[string]Finding{}
+	for _, f := range before {
+		beforeKeys[findingKey(f)] = f
+	}
+
+	changes := []findingChange{}
+	for _, f := range after {
+		b, ok := beforeKeys[findingKey(f)]
+		if ok && (b.Message != f.Message || b.Severity != f.Severity || b.Score != f.Score || !slices.Equal(b.Details, f.Details)) {
+			changes = append(changes, findingChange{Before: b, After: f})
+		}
+	}
+
+	return changes
+}
+
+func printDiff(w io.Writer, before *savedReport, after *savedReport) {
+	added, resolved := compareFindings(before.Findings, after.Findings)
+	changed := changedFindings(before.Findings, after.Findings)
+
+	fmt.Fprintf(w, "Comparing %s (%d findings) with %s (%d findings)\n", formatTime(before.Time), len(before.Findings), formatTime(after.Time), len(after.Findings))
+	fmt.Fprintf(w, "%d added, %d removed, %d changed\n", len(added), len(resolved), len(changed))
+
+	if len(added) > 0 {
+		fmt.Fprintln(w, "\nAdded")
+		printReport(w, sliceStream(added), "")
+	}
+
+	if len(resolved) > 0 {
+		fmt.Fprintln(w, "\nRemoved")
+		printReport(w, sliceStream(resolved), "")
+	}
+
+	if len(changed) > 0 {
+		fmt.Fprintln(w, "\nChanged")
+		afters := []Finding{}
+		for _, c := range changed {
+			afters = append(afters, c.After)
+		}
+		cols := columnsFor(afters)
+		for _, c := range changed {
+			cols.print(w, c.After)
+			if c.Before.Severity != c.After.Severity {
+				fmt.Fprintf(w, "\t\tseverity was %s\n", c.Before.Severity)
+			}
+			if c.Before.Score != c.After.Score {
+				fmt.Fprintf(w, "\t\tscore was %d\n", c.Before.Score)
+			}
+			for _, d := range c.Before.Details {
+				if !slices.Contains(c.After.Details, d) {
+					fmt.Fprintf(w, "\t\tno longer: %s\n", d)
+				}
+			}
+		}
+	}
+
+	scores := []string{}
+	for _, profile := range after.Profiles {
+		if score, ok := before.Scores[profile]; ok && score != after.Scores[profile] {
+			scores = append(scores, fmt.Sprintf("  %-20s %3d -> %3d", profile, score, after.Scores[profile]))
+		}
+	}
+	if len(scores) > 0 {
+		fmt.Fprintln(w, "\nScores")
+		for _, s := range scores {
+			fmt.Fprintln(w, s)
+		}
+	}
+}
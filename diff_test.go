@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestFindingKey(t *testing.T) {
+	f := Finding{Account: "prod", AccountID: "111111111111", Bucket: "logs", Check: "lifecycle", Subject: "expiry", Message: "no expiry rule", Severity: SeverityLow}
+
+	tests := []struct {
+		name  string
+		other Finding
+		same  bool
+	}{
+		{"identical", f, true},
+		{"other message and severity", Finding{Account: "prod", AccountID: "111111111111", Bucket: "logs", Check: "lifecycle", Subject: "expiry", Message: "changed", Severity: SeverityHigh}, true},
+		{"other profile name", Finding{Account: "production", AccountID: "111111111111", Bucket: "logs", Check: "lifecycle", Subject: "expiry"}, true},
+		{"other subject", Finding{AccountID: "111111111111", Bucket: "logs", Check: "lifecycle", Subject: "transition"}, false},
+		{"other bucket", Finding{AccountID: "111111111111", Bucket: "data", Check: "lifecycle", Subject: "expiry"}, false},
+		{"other check", Finding{AccountID: "111111111111", Bucket: "logs", Check: "versioning", Subject: "expiry"}, false},
+		{"other account", Finding{AccountID: "222222222222", Bucket: "logs", Check: "lifecycle", Subject: "expiry"}, false},
+		{"no account ID", Finding{Account: "prod", Bucket: "logs", Check: "lifecycle", Subject: "expiry"}, false},
+		// The fields are separated, so they can't run into each other.
+		{"shifted fields", Finding{AccountID: "111111111111", Bucket: "log", Check: "slifecycle", Subject: "expiry"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findingKey(tt.other) == findingKey(f); got != tt.same {
+				t.Errorf("findingKey() equal = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestChangedFindings(t *testing.T) {
+	unchanged := Finding{AccountID: "111111111111", Bucket: "a", Check: "versioning", Message: "off"}
+	before := Finding{AccountID: "111111111111", Bucket: "b", Check: "public-access", Severity: SeverityMedium, Score: 20}
+	after := before
+	after.Severity, after.Score = SeverityHigh, 50
+	gone := Finding{AccountID: "111111111111", Bucket: "c", Check: "versioning"}
+	added := Finding{AccountID: "111111111111", Bucket: "d", Check: "versioning"}
+
+	changes := changedFindings([]Finding{unchanged, before, gone}, []Finding{unchanged, after, added})
+	if len(changes) != 1 || changes[0].Before.Score != 20 || changes[0].After.Score != 50 {
+		t.Errorf("changedFindings() = %+v, want b's score change only", changes)
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// A distributed scan shares accounts out to workers through an SQS queue.
+// The coordinator sends a message per account; a worker scans the account
+// and writes the report it saved, with every finding, to S3 under the run's
+// results prefix, where the coordinator collects and merges them. Triage,
+// -min-severity, -owner and -only-check are applied once, by the
+// coordinator, so the merged report is what a single scan of the accounts
+// would have saved. Workers need the same AWS profiles as the coordinator.
+//
+// Each worker scans one account, so the replication check only compares
+// replicas with sources in the same account; scan the accounts together to
+// compare replicas across them.
+
+// scanJob is the message sent for each account.
+type scanJob struct {
+	Run     string `json:"run"`
+	Profile string `json:"profile"`
+	Results string `json:"results"` // s3://bucket/prefix/run, for <profile>.json
+}
+
+// jobVisibility is how long a worker has a job before another may take it;
+// workers extend it while they scan.
+const jobVisibility = 5 * time.Minute
+
+// coordinateMain shards a scan of the accounts across workers and reports
+// the merged results.
+func coordinateMain(args []string) {
+	fs := flag.NewFlagSet("coordinate", flag.ExitOnError)
+	profiles := fs.String("profiles", "deployTools", "comma-separated AWS profiles (one per account) to scan")
+	profile := fs.String("profile", "deployTools", "AWS profile to use the queue and results bucket with")
+	queueURL := fs.String("queue", "", "URL of the SQS queue workers read")
+	results := fs.String("results", "", "s3://bucket/prefix where workers write their reports")
+	historyDir := fs.String("history-dir", defaultHistoryDir(), "directory where completed scans are saved for comparison")
+	timeout := fs.Duration("timeout", 12*time.Hour, "stop waiting for workers after this long and report what was found so far")
+	minSeverity := fs.String("min-severity", "LOW", "only report findings of this severity or above: LOW, MEDIUM, HIGH or CRITICAL")
+	onlyCheck := fs.String("only-check", "", "comma-separated checks to report findings from, by ID or custom check, rule or plugin name; all if empty")
+	owner := fs.String("owner", "", "only report findings for buckets owned by this team")
+	triageFile := fs.String("triage", defaultTriageFile(), "file of findings suppressed with s3-audit triage, which are left out of the report: a path, s3://bucket/key or ssm:/parameter-name")
+	triageProfile := fs.String("triage-profile", "", "AWS profile to read an S3 or SSM triage file with; -profile if empty")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit coordinate -queue url -results s3://bucket/prefix -profiles profiles")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *queueURL == "" || *results == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	bucket, prefix, err := parseS3URL(*results)
+	check(err, "invalid -results")
+	var threshold Severity
+	check(threshold.UnmarshalText([]byte(*minSeverity)), "invalid -min-severity")
+	// Workers may run custom rules and plugins the coordinator doesn't know
+	// of, so any check name is allowed.
+	reported, err := newReportSelection(*onlyCheck, nil, true)
+	check(err, "invalid -only-check")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	config, err := loadConfig(ctx, *profile)
+	check(err, "unable to load AWS config")
+	queue := sqs.NewFromConfig(config)
+	store, err := regionalS3Client(ctx, config, bucket)
+	check(err, "unable to find results bucket")
+
+	started := time.Now()
+	run := started.UTC().Format("20060102T150405Z")
+	runPrefix := path.Join(prefix, run)
+	accounts := strings.Split(*profiles, ",")
+	log := component("coordinate").With("run", run)
+	for _, p := range accounts {
+		body, _ := json.Marshal(scanJob{Run: run, Profile: p, Results: "s3://" + bucket + "/" + runPrefix})
+		_, err := queue.SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: queueURL, MessageBody: aws.String(string(body))})
+		check(err, "unable to queue "+p)
+	}
+	log.Info("queued accounts for workers", "accounts", len(accounts))
+
+	reports := collectReports(ctx, log, store, bucket, runPrefix, accounts)
+	merged := mergeReports(started, accounts, reports)
+
+	previous, err := latestReportSummary(*historyDir)
+	check(err, "unable to load previous report")
+	suppressions, err := loadTriage(ctx, triageLocation{path: *triageFile, profile: cmp.Or(*triageProfile, *profile)})
+	check(err, "unable to load triage file")
+
+	// As with a single scan, partial results aren't saved.
+	stopped := ctx.Err() != nil
+	if stopped {
+		log.Warn("stopped waiting for workers, reporting partial results", "reason", context.Cause(ctx))
+	} else {
+		_, err = saveReport(*historyDir, merged)
+		check(err, "unable to save report")
+	}
+
+	findings := reportFilter{suppressions, reported, threshold, *owner}.apply(sliceStream(merged.Findings))
+	check(printReport(os.Stdout, findings.filter(func(f Finding) bool { return !isCostFinding(f) }), ""), "unable to report")
+	check(printCostFindings(os.Stdout, findings.filter(isCostFinding)), "unable to report")
+	fmt.Println()
+	printLeagueTable(os.Stdout, merged, previous)
+	if len(merged.Errors) > 0 {
+		fmt.Println()
+		printErrors(os.Stdout, merged.Errors)
+	}
+
+	counts, err := findings.severityCounts()
+	check(err, "unable to report")
+	switch {
+	case stopped:
+		os.Exit(1)
+	case totalCount(counts) > 0:
+		os.Exit(3)
+	}
+}
+
+// collectReports polls for each account's report until they are all in or
+// the context ends.
+func collectReports(ctx context.Context, log *slog.Logger, client *s3.Client, bucket string, prefix string, accounts []string) map[string]*savedReport {
+	reports := map[string]*savedReport{}
+	for {
+		for _, p := range accounts {
+			if reports[p] != nil {
+				continue
+			}
+
+			key := path.Join(prefix, p+".json")
+			out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key})
+			if err != nil {
+				continue
+			}
+			data, err := io.ReadAll(out.Body)
+			out.Body.Close()
+
+			var r savedReport
+			if err == nil {
+				err = json.Unmarshal(data, &r)
+			}
+			if err != nil {
+				log.Warn("ignoring unreadable report", "account", p, "error", err)
+				continue
+			}
+			reports[p] = &r
+			log.Info("account scanned", "account", p, "done", len(reports), "of", len(accounts))
+		}
+
+		if len(reports) == len(accounts) {
+			return reports
+		}
+
+		select {
+		case <-ctx.Done():
+			return reports
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
+
+// mergeReports combines the accounts' reports into one, as a scan of them
+// all would have made.
+func mergeReports(at time.Time, accounts []string, reports map[string]*savedReport) savedReport {
+	merged := savedReport{Time: at, Profiles: accounts, Buckets: map[string]int{}, ACLs: map[string]aclCount{}, Findings: []Finding{}, Errors: []scanError{}}
+	for _, p := range accounts {
+		r := reports[p]
+		if r == nil {
+			merged.Errors = append(merged.Errors, scanError{Account: p, Error: "no report from a worker"})
+			continue
+		}
+
+		for profile, n := range r.Buckets {
+			merged.Buckets[profile] = n
+		}
+		for profile, c := range r.ACLs {
+			merged.ACLs[profile] = c
+		}
+		merged.Findings = append(merged.Findings, r.Findings...)
+		merged.Errors = append(merged.Errors, r.Errors...)
+	}
+	merged.Scores = scoreAccounts(merged.Findings, merged.Buckets)
+
+	return merged
+}
+
+// workMain takes accounts from the queue and scans them until stopped.
+func workMain(args []string) {
+	fs := flag.NewFlagSet("work", flag.ExitOnError)
+	profile := fs.String("profile", "deployTools", "AWS profile to use the queue and results bucket with")
+	queueURL := fs.String("queue", "", "URL of the SQS queue to take accounts from")
+	configFile := fs.String("config", "", "JSON config file for the scans")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit work -queue url [-config config.json] [scan flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *queueURL == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config, err := loadConfig(ctx, *profile)
+	check(err, "unable to load AWS config")
+	queue := sqs.NewFromConfig(config)
+	exe, err := os.Executable()
+	check(err, "unable to find s3-audit executable")
+
+	log := component("work")
+	for ctx.Err() == nil {
+		out, err := queue.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            queueURL,
+			MaxNumberOfMessages: 1,
+			WaitTimeSeconds:     20,
+			VisibilityTimeout:   int32(jobVisibility.Seconds()),
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Error("unable to receive from queue", "error", err)
+			time.Sleep(time.Minute)
+			continue
+		}
+
+		for _, msg := range out.Messages {
+			var job scanJob
+			if err := json.Unmarshal([]byte(aws.ToString(msg.Body)), &job); err != nil {
+				log.Error("ignoring invalid job", "error", err)
+				continue
+			}
+
+			jobLog := log.With("run", job.Run, "account", job.Profile)
+			jobLog.Info("scanning account")
+			if err := runJob(ctx, queue, *queueURL, msg.ReceiptHandle, config, exe, *configFile, fs.Args(), job); err != nil {
+				// The job is retried when its visibility times out.
+				jobLog.Error("scan failed", "error", err)
+				continue
+			}
+
+			_, err := queue.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: queueURL, ReceiptHandle: msg.ReceiptHandle})
+			if err != nil {
+				jobLog.Error("unable to delete job", "error", err)
+			}
+			jobLog.Info("account scanned")
+		}
+	}
+}
+
+// runJob scans the job's account as a child process, keeping the job from
+// other workers meanwhile, and uploads the report it saved. That has every
+// finding, unlike what it reports, which the worker's triage file and any
+// -min-severity, -owner or -only-check among the scan flags filter.
+func runJob(ctx context.Context, queue *sqs.Client, queueURL string, receipt *string, config aws.Config, exe string, configFile string, extra []string, job scanJob) error {
+	dir, err := os.MkdirTemp("", "s3-audit-work")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	history := filepath.Join(dir, "history")
+	args := slices.Concat([]string{
+		"scan",
+		"-profiles", job.Profile,
+		"-config", configFile,
+		"-checkpoint-dir", filepath.Join(dir, "checkpoint"),
+		"-history-dir", history,
+		"-quiet",
+	}, extra)
+
+	heartbeat, stop := context.WithCancel(ctx)
+	defer stop()
+	go func() {
+		for {
+			select {
+			case <-heartbeat.Done():
+				return
+			case <-time.After(jobVisibility / 2):
+				queue.ChangeMessageVisibility(heartbeat, &sqs.ChangeMessageVisibilityInput{
+					QueueUrl:          &queueURL,
+					ReceiptHandle:     receipt,
+					VisibilityTimeout: int32(jobVisibility.Seconds()),
+				})
+			}
+		}
+	}()
+
+	cmd := exec.CommandContext(ctx, exe, args...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = time.Minute
+
+	// Exit status 3 means there were findings.
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 3) {
+		return fmt.Errorf("scan failed: %w", err)
+	}
+
+	paths, err := reportPaths(history)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("scan saved no report")
+	}
+	report, err := os.Open(paths[len(paths)-1])
+	if err != nil {
+		return err
+	}
+	defer report.Close()
+
+	bucket, prefix, err := parseS3URL(job.Results)
+	if err != nil {
+		return err
+	}
+	client, err := regionalS3Client(ctx, config, bucket)
+	if err != nil {
+		return err
+	}
+
+	key := path.Join(prefix, job.Profile+".json")
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{Bucket: &bucket, Key: &key, Body: report})
+	if err != nil {
+		return fmt.Errorf("unable to upload report: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	r53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// bucketReference is something that serves or resolves to a bucket by name.
+type bucketReference struct {
+	Bucket string
+	Source string // e.g. "Route 53 record www.example.com (CNAME)"
+}
+
+// getRoute53BucketReferences finds the records in the account's hosted
+// zones that point at S3: CNAMEs to bucket hostnames, and aliases to the S3
+// website endpoint, which serve the bucket named after the record.
+func getRoute53BucketReferences(ctx context.Context, client *route53.Client) ([]bucketReference, error) {
+	refs := []bucketReference{}
+
+	zones := route53.NewListHostedZonesPaginator(client, &route53.ListHostedZonesInput{})
+	for zones.HasMorePages() {
+		page, err := zones.NextPage(ctx)
+		if err != nil {
+			return refs, fmt.Errorf("unable to list hosted zones: %w", err)
+		}
+
+		for _, zone := range page.HostedZones {
+			records := route53.NewListResourceRecordSetsPaginator(client, &route53.ListResourceRecordSetsInput{HostedZoneId: zone.Id})
+			for records.HasMorePages() {
+				page, err := records.NextPage(ctx)
+				if err != nil {
+					return refs, fmt.Errorf("unable to list records in %s: %w", aws.ToString(zone.Name), err)
+				}
+
+				for _, record := range page.ResourceRecordSets {
+					refs = append(refs, recordBucketReferences(record)...)
+				}
+			}
+		}
+	}
+
+	return refs, nil
+}
+
+func recordBucketReferences(record r53types.ResourceRecordSet) []bucketReference {
+	name := strings.TrimSuffix(aws.ToString(record.Name), ".")
+	refs := []bucketReference{}
+
+	if alias := record.AliasTarget; alias != nil {
+		if strings.HasPrefix(aws.ToString(alias.DNSName), "s3-website") {
+			refs = append(refs, bucketReference{Bucket: name, Source: fmt.Sprintf("Route 53 record %s (alias to the S3 website endpoint)", name)})
+		}
+		return refs
+	}
+
+	if record.Type != r53types.RRTypeCname {
+		return refs
+	}
+	for _, rr := range record.ResourceRecords {
+		match := s3OriginDomain.FindStringSubmatch(strings.TrimSuffix(aws.ToString(rr.Value), "."))
+		if match != nil {
+			refs = append(refs, bucketReference{Bucket: match[1], Source: fmt.Sprintf("Route 53 record %s (CNAME)", name)})
+		}
+	}
+
+	return refs
+}
+
+// cloudFrontBucketReferences lists the buckets CloudFront distributions in
+// the account use as origins.
+func cloudFrontBucketReferences(origins map[string][]cloudFrontOrigin) []bucketReference {
+	refs := []bucketReference{}
+	for bucket, list := range origins {
+		for _, origin := range list {
+			refs = append(refs, bucketReference{Bucket: bucket, Source: "CloudFront distribution " + origin.DistributionID})
+		}
+	}
+
+	slices.SortFunc(refs, func(a, b bucketReference) int { return strings.Compare(a.Bucket+a.Source, b.Bucket+b.Source) })
+	return refs
+}
+
+// danglingReferenceFindings reports references to buckets that no longer
+// exist. Anyone can create a bucket with the name and serve their own
+// content from our domain or distribution.
+func (s *scanner) danglingReferenceFindings(ctx context.Context, as *accountScan, refs []bucketReference) []Finding {
+	findings := []Finding{}
+	exists := map[string]bool{}
+
+	for _, ref := range refs {
+		if as.bucketNames[ref.Bucket] {
+			continue
+		}
+
+		found, ok := exists[ref.Bucket]
+		if !ok {
+			var err error
+			if found, err = bucketExists(ctx, ref.Bucket); err != nil {
+				as.fail("", fmt.Errorf("unable to check bucket %s exists: %w", ref.Bucket, err))
+				continue
+			}
+			exists[ref.Bucket] = found
+		}
+		if found {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			Account:   as.Profile,
+			AccountID: as.ID,
+			Bucket:    ref.Bucket,
+			Check:     "dangling-bucket-reference",
+			Subject:   ref.Source,
+			Severity:  SeverityHigh,
+			Message:   fmt.Sprintf("%s points at a bucket that doesn't exist, which anyone could create to take it over", ref.Source),
+		})
+	}
+
+	return findings
+}
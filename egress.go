@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// defaultEgressPricePerGB is the first tier of S3 data transfer out to the
+// internet, in USD.
+const defaultEgressPricePerGB = 0.09
+
+// egressLookback is the period downloads are summed over; the estimate is
+// scaled to 30 days.
+const egressLookback = 30 * 24 * time.Hour
+
+// egressEstimate is the monthly cost of data downloaded from a public
+// bucket. CloudWatch doesn't say who downloaded it, so this is an upper
+// bound on what anonymous access costs.
+type egressEstimate struct {
+	BytesDownloaded int64   `json:"bytesDownloaded"`
+	MonthlyUSD      float64 `json:"monthlyUsd"`
+}
+
+func (e egressEstimate) String() string {
+	return fmt.Sprintf("%s downloaded in the last 30 days, up to $%.2f a month in data transfer", formatBytes(e.BytesDownloaded), e.MonthlyUSD)
+}
+
+// estimateEgress sums BytesDownloaded over the lookback. The metric is only
+// published for buckets with a request metrics filter; for filters other
+// than the whole bucket the estimate only covers what they match. It
+// returns nil if there are no request metrics.
+func estimateEgress(ctx context.Context, config aws.Config, bucket string, region string, pricePerGB float64) (*egressEstimate, error) {
+	client := cloudwatch.NewFromConfig(config, func(o *cloudwatch.Options) { o.Region = region })
+
+	metrics, err := client.ListMetrics(ctx, &cloudwatch.ListMetricsInput{
+		Namespace:  aws.String("AWS/S3"),
+		MetricName: aws.String("BytesDownloaded"),
+		Dimensions: []cwtypes.DimensionFilter{{Name: aws.String("BucketName"), Value: &bucket}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list request metrics: %w", err)
+	}
+	if len(metrics.Metrics) == 0 {
+		return nil, nil
+	}
+
+	// Prefer the whole-bucket filter, which is what the console creates.
+	metric := metrics.Metrics[0]
+	for _, m := range metrics.Metrics {
+		if filter, _ := dimension(m.Dimensions, "FilterId"); filter == "EntireBucket" {
+			metric = m
+		}
+	}
+
+	end := time.Now()
+	start := end.Add(-egressLookback)
+	out, err := client.GetMetricData(ctx, &cloudwatch.GetMetricDataInput{
+		MetricDataQueries: []cwtypes.MetricDataQuery{{
+			Id:         aws.String("downloaded"),
+			MetricStat: &cwtypes.MetricStat{Metric: &metric, Period: aws.Int32(86400), Stat: aws.String("Sum")},
+		}},
+		StartTime: &start,
+		EndTime:   &end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get request metrics: %w", err)
+	}
+
+	estimate := &egressEstimate{}
+	for _, result := range out.MetricDataResults {
+		for _, v := range result.Values {
+			estimate.BytesDownloaded += int64(v)
+		}
+	}
+
+	const gb = 1 << 30
+	estimate.MonthlyUSD = float64(estimate.BytesDownloaded) / gb * pricePerGB
+
+	return estimate, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// apiStats counts the AWS requests made, for scan metrics.
+var apiStats struct {
+	calls, errors, retries atomic.Int64
+}
+
+// addRequestCounting adds counting to a client's middleware stack, once per
+// operation so that retries are counted separately.
+func addRequestCounting(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("s3-audit/countAPIRequests", countAPIRequests), middleware.Before)
+}
+
+func countAPIRequests(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	out, metadata, err := next.HandleInitialize(ctx, in)
+
+	apiStats.calls.Add(1)
+	if err != nil {
+		apiStats.errors.Add(1)
+	}
+	if attempts, ok := retry.GetAttemptResults(metadata); ok && len(attempts.Results) > 1 {
+		apiStats.retries.Add(int64(len(attempts.Results) - 1))
+	}
+
+	return out, metadata, err
+}
+
+// emfNamespace is the CloudWatch namespace of the scan metrics.
+const emfNamespace = "S3Audit"
+
+// writeEMF writes the scan's metrics as a CloudWatch Embedded Metric Format
+// log line, which CloudWatch Logs turns into metrics for dashboards and
+// alarms on the audit job itself.
+func writeEMF(w io.Writer, r savedReport, findings findingStream, took time.Duration) error {
+	counts, err := findings.severityCounts()
+	if err != nil {
+		return err
+	}
+	values := map[string]any{
+		"Service":         "s3-audit",
+		"ScanDuration":    took.Seconds(),
+		"AccountsScanned": len(r.Buckets),
+		"BucketsScanned":  r.bucketCount(),
+		"ScanErrors":      len(r.Errors),
+		"APICalls":        apiStats.calls.Load(),
+		"APIErrors":       apiStats.errors.Load(),
+		"APIRetries":      apiStats.retries.Load(),
+	}
+	metrics := []map[string]string{
+		{"Name": "ScanDuration", "Unit": "Seconds"},
+		{"Name": "AccountsScanned", "Unit": "Count"},
+		{"Name": "BucketsScanned", "Unit": "Count"},
+		{"Name": "ScanErrors", "Unit": "Count"},
+		{"Name": "APICalls", "Unit": "Count"},
+		{"Name": "APIErrors", "Unit": "Count"},
+		{"Name": "APIRetries", "Unit": "Count"},
+	}
+	for sev := SeverityLow; sev <= SeverityCritical; sev++ {
+		name := "Findings" + sev.String()
+		values[name] = counts[sev]
+		metrics = append(metrics, map[string]string{"Name": name, "Unit": "Count"})
+	}
+
+	values["_aws"] = map[string]any{
+		"Timestamp": r.Time.UnixMilli(),
+		"CloudWatchMetrics": []map[string]any{{
+			"Namespace":  emfNamespace,
+			"Dimensions": [][]string{{"Service"}},
+			"Metrics":    metrics,
+		}},
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// endpoints overrides where AWS requests are sent, for running against
+// LocalStack, moto or an air-gapped partition. It is set from -endpoint-url
+// and the config file's endpoints before any AWS config is loaded.
+var endpoints serviceEndpoints
+
+// serviceEndpoints maps a service, named as in the services section of the
+// shared AWS config file (e.g. s3, s3_control, sts), to its endpoint URL. The
+// key "" applies to every service without its own entry.
+type serviceEndpoints map[string]string
+
+// setEndpoints validates and applies the endpoint overrides.
+func setEndpoints(all string, services map[string]string) error {
+	endpoints = serviceEndpoints{}
+	if all != "" {
+		endpoints[""] = all
+	}
+	for service, endpoint := range services {
+		endpoints[strings.ToLower(service)] = endpoint
+	}
+
+	for service, endpoint := range endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			if service == "" {
+				service = "all services"
+			}
+			return fmt.Errorf("invalid endpoint %q for %s: must be an absolute URL", endpoint, service)
+		}
+	}
+
+	return nil
+}
+
+// checkS3Compatible checks that an S3-compatible store has an endpoint to
+// reach it at, once setEndpoints has been called.
+func checkS3Compatible(conf *Config) error {
+	if conf.S3Compatible && endpoints.s3Endpoint() == "" {
+		return errors.New("an S3-compatible store needs -endpoint-url or an s3 endpoint in the config file")
+	}
+
+	return nil
+}
+
+// GetServiceBaseEndpoint satisfies the SDK's (unexported) interface for
+// config sources that provide per-service endpoints. sdkID is the service ID,
+// e.g. "S3 Control".
+func (e serviceEndpoints) GetServiceBaseEndpoint(_ context.Context, sdkID string) (string, bool, error) {
+	if endpoint, ok := e[strings.ReplaceAll(strings.ToLower(sdkID), " ", "_")]; ok {
+		return endpoint, true, nil
+	}
+
+	endpoint, ok := e[""]
+	return endpoint, ok, nil
+}
+
+// s3Endpoint is the endpoint S3 requests go to, or "" for AWS.
+func (e serviceEndpoints) s3Endpoint() string {
+	endpoint, _, _ := e.GetServiceBaseEndpoint(context.Background(), "S3")
+	return endpoint
+}
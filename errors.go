@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// scanError is a failure to read part of an account or bucket. The scan
+// carries on without that part, and the report lists the error so that the
+// gap isn't mistaken for a clean result.
+type scanError struct {
+	Account string `json:"account"`
+	Bucket  string `json:"bucket,omitempty"` // empty for account-level errors
+	Error   string `json:"error"`
+}
+
+func (e scanError) String() string {
+	if e.Bucket == "" {
+		return fmt.Sprintf("%s: %s", e.Account, e.Error)
+	}
+
+	return fmt.Sprintf("%s/%s: %s", e.Account, e.Bucket, e.Error)
+}
+
+// fail logs and records an error reading part of the account, or of bucket
+// if given.
+func (as *accountScan) fail(bucket string, err error) {
+	logger := as.log
+	if bucket != "" {
+		logger = logger.With("bucket", bucket)
+	}
+
+	// S3-compatible stores implement only some of the S3 API; what they
+	// don't is left out rather than reported.
+	if as.s3Compatible && isErrorCode(err, "NotImplemented") {
+		logger.Debug("not supported by the S3-compatible store", "error", err)
+		return
+	}
+	logger.Warn("scan incomplete", "error", err)
+
+	as.errors = append(as.errors, scanError{Account: as.Profile, Bucket: bucket, Error: err.Error()})
+}
+
+// printErrors lists the scan errors, account-level ones first.
+func printErrors(w io.Writer, errs []scanError) {
+	if len(errs) == 0 {
+		return
+	}
+
+	errs = slices.Clone(errs)
+	slices.SortStableFunc(errs, func(a, b scanError) int {
+		return strings.Compare(a.Account+"/"+a.Bucket, b.Account+"/"+b.Bucket)
+	})
+
+	fmt.Fprintf(w, "Errors (%d) - these parts of the scan are incomplete:\n", len(errs))
+	for _, e := range errs {
+		fmt.Fprintf(w, "  %s\n", e)
+	}
+}
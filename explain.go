@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// explainMain collects the facts about one bucket and prints why it is or
+// isn't considered public.
+func explainMain(args []string) {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	profiles := fs.String("profiles", "deployTools", "comma-separated AWS profiles to look for the bucket in")
+	configFile := fs.String("config", "", "JSON config file")
+	endpointURL := fs.String("endpoint-url", "", "send AWS requests to this URL instead")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit explain [-profiles profiles] bucket")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	name := fs.Arg(0)
+
+	conf, err := loadConfigFile(*configFile)
+	check(err, "unable to load config")
+	check(setEndpoints(*endpointURL, conf.Endpoints), "invalid endpoints")
+
+	ctx := context.Background()
+	s := &scanner{conf: conf, clients: newAWSClients}
+	for _, profile := range strings.Split(*profiles, ",") {
+		as, clients, err := s.newAccountScan(ctx, profile)
+		check(err, "unable to scan "+profile)
+
+		buckets, err := listBuckets(ctx, as.client)
+		check(err, "unable to list buckets in "+profile)
+
+		i := slices.IndexFunc(buckets, func(b s3types.Bucket) bool { return aws.ToString(b.Name) == name })
+		if i < 0 {
+			continue
+		}
+
+		if as.publicByAccessAnalyzer, err = getAccessAnalyzerPublicBuckets(ctx, clients.accessAnalyzer); err != nil {
+			as.fail("", err)
+		}
+
+		facts := collectFacts(ctx, as, buckets[i])
+		printExplanation(os.Stdout, facts)
+		if len(as.errors) > 0 {
+			fmt.Println()
+			printErrors(os.Stdout, as.errors)
+		}
+		return
+	}
+
+	check(fmt.Errorf("bucket %s not found in %s", name, *profiles), "unable to explain")
+}
+
+// printExplanation prints each of the ways the bucket can be made public,
+// and the verdict from them.
+func printExplanation(w io.Writer, facts *BucketFacts) {
+	exposure := facts.Exposure
+	pab := facts.PublicAccessBlock
+	if pab == nil {
+		pab = &PublicAccessBlock{}
+	}
+
+	fmt.Fprintf(w, "%s in %s (%s), %s\n\n", facts.Name, facts.Account, facts.AccountID, facts.Region)
+
+	reasons := []string{}
+	if exposure.AnonymousRead {
+		reasons = append(reasons, "readable anonymously")
+	}
+	if exposure.AccessAnalyzer {
+		reasons = append(reasons, "Access Analyzer finding")
+	}
+	if exposure.Policy {
+		reasons = append(reasons, "public policy")
+	}
+	if len(reasons) > 0 {
+		fmt.Fprintf(w, "Verdict: PUBLIC (%s)\n\n", strings.Join(reasons, ", "))
+	} else {
+		fmt.Fprintf(w, "Verdict: not public\n\n")
+	}
+
+	fmt.Fprintln(w, "Probe")
+	if exposure.AnonymousRead {
+		fmt.Fprintln(w, "  a test object written to the bucket could be read without credentials")
+	} else {
+		fmt.Fprintln(w, "  a test object couldn't be written, or couldn't be read without credentials")
+	}
+	if accel := facts.Acceleration; accel != nil && accel.Enabled {
+		fmt.Fprintf(w, "  Transfer Acceleration is enabled; read anonymously through it: %v\n", accel.AnonymousRead)
+	}
+
+	fmt.Fprintln(w, "\nAccess Analyzer")
+	if exposure.AccessAnalyzer {
+		fmt.Fprintln(w, "  has an active finding that the bucket is public")
+	} else {
+		fmt.Fprintln(w, "  has no public finding for the bucket (or no analyzer is set up)")
+	}
+
+	fmt.Fprintln(w, "\nBlock Public Access (bucket)")
+	if facts.PublicAccessBlock == nil {
+		fmt.Fprintln(w, "  not configured")
+	} else {
+		fmt.Fprintf(w, "  BlockPublicAcls: %v, IgnorePublicAcls: %v, BlockPublicPolicy: %v, RestrictPublicBuckets: %v\n",
+			pab.BlockPublicAcls, pab.IgnorePublicAcls, pab.BlockPublicPolicy, pab.RestrictPublicBuckets)
+	}
+
+	fmt.Fprintln(w, "\nPolicy")
+	if facts.Policy == nil {
+		fmt.Fprintln(w, "  none")
+	} else {
+		if facts.Policy.IsPublic() && pab.RestrictPublicBuckets {
+			fmt.Fprintln(w, "  public, but RestrictPublicBuckets limits it to AWS service principals and this account")
+		}
+
+		explained := 0
+		for _, stmt := range facts.Policy.Statement {
+			var why string
+			switch {
+			case stmt.IsPublic():
+				why = "grants access to anyone"
+			case stmt.IsAllow() && stmt.HasWildcardPrincipal():
+				why = "would grant access to anyone, but its conditions restrict it to fixed values, so it isn't public"
+			default:
+				continue
+			}
+			explained++
+
+			data, _ := json.MarshalIndent(stmt, "    ", "  ")
+			fmt.Fprintf(w, "  statement %s:\n    %s\n", why, data)
+			for _, m := range stmt.Mitigations() {
+				narrow := ""
+				if m.Narrow {
+					narrow = " (narrow enough not to be meaningfully public)"
+				}
+				fmt.Fprintf(w, "    limited by condition %s%s\n", m, narrow)
+			}
+		}
+		if explained == 0 {
+			fmt.Fprintln(w, "  no statement grants access to anyone")
+		}
+	}
+
+	fmt.Fprintln(w, "\nACL")
+	if facts.ACL == nil {
+		fmt.Fprintln(w, "  unknown")
+	} else {
+		for _, grant := range facts.ACL.Grants {
+			public := ""
+			if slices.Contains(publicGroups, grant.Grantee) {
+				public = " (public)"
+				if pab.IgnorePublicAcls {
+					public = " (public, but ignored as IgnorePublicAcls is set)"
+				}
+			}
+			fmt.Fprintf(w, "  %s to %s %s%s\n", grant.Permission, grant.Type, grant.Grantee, public)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// ExpressionRule is a CEL expression over the bucket facts, available as
+// bucket, that produces a finding when it evaluates to true. For example:
+//
+//	{
+//	  "check": "prod-kms",
+//	  "severity": "HIGH",
+//	  "expression": "has(bucket.tags.Stage) && bucket.tags.Stage == \"PROD\" && !(has(bucket.encryption.kms) && bucket.encryption.kms)",
+//	  "message": "PROD buckets must use KMS encryption"
+//	}
+//
+// Facts a bucket doesn't have, such as tags it isn't given, are missing
+// rather than empty, so test for them with has() or in. An expression
+// reading one that is missing doesn't match.
+type ExpressionRule struct {
+	Check      string   `json:"check"`
+	Severity   Severity `json:"severity"`
+	Expression string   `json:"expression"`
+	Message    string   `json:"message"`
+
+	program cel.Program
+}
+
+// compileExpressions type-checks every rule up front so a typo fails the
+// run before any scanning starts.
+func compileExpressions(rules []ExpressionRule) ([]ExpressionRule, error) {
+	env, err := cel.NewEnv(cel.Variable("bucket", cel.DynType))
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := make([]ExpressionRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Check == "" {
+			return nil, fmt.Errorf("expression %q has no check name", rule.Expression)
+		}
+
+		ast, issues := env.Compile(rule.Expression)
+		if issues.Err() != nil {
+			return nil, fmt.Errorf("expression for %s: %w", rule.Check, issues.Err())
+		}
+
+		if rule.program, err = env.Program(ast); err != nil {
+			return nil, fmt.Errorf("expression for %s: %w", rule.Check, err)
+		}
+
+		if rule.Message == "" {
+			rule.Message = rule.Expression
+		}
+
+		compiled = append(compiled, rule)
+	}
+
+	return compiled, nil
+}
+
+// matches evaluates the rule. CEL works on the JSON form of the facts so the
+// field names match those seen by Rego rules and in reports.
+func (r ExpressionRule) matches(facts *BucketFacts) (bool, error) {
+	data, err := json.Marshal(facts)
+	if err != nil {
+		return false, err
+	}
+
+	var bucket map[string]any
+	if err := json.Unmarshal(data, &bucket); err != nil {
+		return false, err
+	}
+
+	out, _, err := r.program.Eval(map[string]any{"bucket": bucket})
+	if isMissingFact(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression for %s returned %v, not a bool", r.Check, out.Value())
+	}
+
+	return matched, nil
+}
+
+// isMissingFact reports whether a CEL error is from reading a fact the
+// bucket doesn't have. CEL has no error types for these, only messages.
+func isMissingFact(err error) bool {
+	return err != nil && (strings.HasPrefix(err.Error(), "no such key") || strings.HasPrefix(err.Error(), "no such attribute"))
+}
+
+func (r ExpressionRule) Name() string { return r.Check }
+
+func (r ExpressionRule) Run(ctx context.Context, facts *BucketFacts) []Finding {
+	findings, _ := r.runFallible(ctx, facts)
+	return findings
+}
+
+func (r ExpressionRule) runFallible(_ context.Context, facts *BucketFacts) ([]Finding, error) {
+	matched, err := r.matches(facts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to evaluate expression for %s: %w", r.Check, err)
+	}
+
+	if !matched {
+		return nil, nil
+	}
+
+	return []Finding{{Check: r.Check, Severity: r.Severity, Message: r.Message}}, nil
+}
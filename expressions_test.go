@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestExpressionRuleMatches(t *testing.T) {
+	prod := map[string]string{"Stage": "PROD"}
+
+	tests := []struct {
+		name       string
+		expression string
+		facts      *BucketFacts
+		want       bool
+	}{
+		{"no tags or encryption", `has(bucket.tags.Stage) && bucket.tags.Stage == "PROD" && !(has(bucket.encryption.kms) && bucket.encryption.kms)`, &BucketFacts{}, false},
+		{"prod without encryption", `has(bucket.tags.Stage) && bucket.tags.Stage == "PROD" && !(has(bucket.encryption.kms) && bucket.encryption.kms)`, &BucketFacts{Tags: prod}, true},
+		{"prod with kms", `has(bucket.tags.Stage) && bucket.tags.Stage == "PROD" && !(has(bucket.encryption.kms) && bucket.encryption.kms)`, &BucketFacts{Tags: prod, Encryption: &Encryption{KMS: true}}, false},
+		{"missing tag", `bucket.tags["Stage"] == "PROD"`, &BucketFacts{}, false},
+		{"missing encryption", `!bucket.encryption.kms`, &BucketFacts{Tags: prod}, false},
+		{"present tag", `bucket.tags["Stage"] == "PROD"`, &BucketFacts{Tags: prod}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := compileExpressions([]ExpressionRule{{Check: "prod-kms", Expression: tt.expression}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := rules[0].matches(tt.facts)
+			if err != nil {
+				t.Fatalf("matches() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpressionRuleErrors(t *testing.T) {
+	rules, err := compileExpressions([]ExpressionRule{{Check: "not-bool", Expression: `bucket.name`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rules[0].runFallible(t.Context(), &BucketFacts{Name: "b"}); err == nil {
+		t.Error("runFallible() error = nil for an expression returning a string")
+	}
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/guardian/s3-audit/awsapi"
+	"github.com/guardian/s3-audit/policy"
+)
+
+// BucketFacts is everything collected about a bucket, for checks and custom
+// rules to evaluate. Optional configuration that isn't set on the bucket is
+// nil.
+type BucketFacts struct {
+	Account             string                      `json:"account"`
+	AccountID           string                      `json:"accountId"`
+	Name                string                      `json:"name"`
+	Region              string                      `json:"region"`
+	Created             *time.Time                  `json:"created"`
+	Size                *bucketSize                 `json:"size"` // nil without CloudWatch storage metrics
+	Exposure            Exposure                    `json:"exposure"`
+	Policy              *policy.Policy              `json:"policy"`
+	PolicyMetrics       *policy.Metrics             `json:"policyMetrics"`
+	ACL                 *ACL                        `json:"acl"`
+	ObjectOwnership     string                      `json:"objectOwnership"` // BucketOwnerEnforced disables ACLs; "" if unknown
+	PublicAccessBlock   *PublicAccessBlock          `json:"publicAccessBlock"`
+	Encryption          *Encryption                 `json:"encryption"`
+	KMSKey              *kmsKey                     `json:"kmsKey"` // the default encryption key, if KMS
+	Tags                map[string]string           `json:"tags"`
+	Owner               string                      `json:"owner"`            // owning team, "" if unknown
+	DataEventTrails     []string                    `json:"dataEventTrails"`  // trails logging object-level events
+	SensitiveData       []macieFinding              `json:"sensitiveData"`    // Macie sensitive data findings
+	ConfigCompliance    map[string]configEvaluation `json:"configCompliance"` // by public-access Config rule name
+	TrustedAdvisor      *trustedAdvisorResult       `json:"trustedAdvisor"`
+	GuardDutyFindings   []guardDutyFinding          `json:"guardDutyFindings"` // recent GuardDuty S3 findings
+	StorageLens         *storageLensMetrics         `json:"storageLens"`       // nil without a dashboard publishing to CloudWatch
+	CloudFrontOrigins   []cloudFrontOrigin          `json:"cloudFrontOrigins"`
+	LogSources          []string                    `json:"logSources"`    // what delivers logs to the bucket, only with the log-bucket check
+	StateBucket         string                      `json:"stateBucket"`   // why the bucket looks like it holds infrastructure state, only with the state-bucket check
+	Egress              *egressEstimate             `json:"egress"`        // only for buckets found public
+	PublicObjects       *publicObjects              `json:"publicObjects"` // only with -inventory
+	Activity            *bucketActivity             `json:"activity"`      // only with the unused-buckets check
+	Tiering             *storageTiering             `json:"tiering"`       // only with the storage-cost check, for buckets over its size
+	Typosquats          []string                    `json:"typosquats"`    // lookalike names registered elsewhere, only with the typosquatting check
+	Acceleration        *acceleration               `json:"acceleration"`
+	NotificationTargets []notificationTarget        `json:"notificationTargets"`
+	RequesterPays       bool                        `json:"requesterPays"`
+	ObjectLock          *objectLock                 `json:"objectLock"`  // nil unless enabled
+	Replication         []replicationRule           `json:"replication"` // only with the replication check
+}
+
+// Exposure is how the bucket was found to be public.
+type Exposure struct {
+	AnonymousRead  bool `json:"anonymousRead"`  // the probe object could be read without credentials
+	AccessAnalyzer bool `json:"accessAnalyzer"` // Access Analyzer reports the bucket as public
+	Policy         bool `json:"policy"`         // the bucket policy is public by S3's definition
+}
+
+func (e Exposure) any() bool {
+	return e.AnonymousRead || e.AccessAnalyzer || e.Policy
+}
+
+func (e Exposure) String() string {
+	return fmt.Sprintf("public: %v, awspublic: %v, policypublic: %v", e.AnonymousRead, e.AccessAnalyzer, e.Policy)
+}
+
+type ACL struct {
+	Owner  string  `json:"owner"`
+	Grants []Grant `json:"grants"`
+}
+
+// Grant is an ACL grant. Grantee is the canonical user ID, email address or
+// group URI, depending on Type.
+type Grant struct {
+	Type       string `json:"type"`
+	Grantee    string `json:"grantee"`
+	Permission string `json:"permission"`
+}
+
+type PublicAccessBlock struct {
+	BlockPublicAcls       bool `json:"blockPublicAcls"`
+	IgnorePublicAcls      bool `json:"ignorePublicAcls"`
+	BlockPublicPolicy     bool `json:"blockPublicPolicy"`
+	RestrictPublicBuckets bool `json:"restrictPublicBuckets"`
+}
+
+type Encryption struct {
+	Algorithm        string `json:"algorithm"`
+	KMS              bool   `json:"kms"`
+	KMSKeyID         string `json:"kmsKeyId,omitempty"`
+	BucketKeyEnabled bool   `json:"bucketKeyEnabled"`
+}
+
+// collectFacts gathers the bucket's configuration. Failures to read any one
+// part are recorded as scan errors and leave that part nil rather than
+// failing the bucket.
+func collectFacts(ctx context.Context, as *accountScan, bucket s3types.Bucket) *BucketFacts {
+	name := aws.ToString(bucket.Name)
+	region := aws.ToString(bucket.BucketRegion)
+	client := as.client
+	logger := as.log.With("bucket", name)
+
+	facts := &BucketFacts{
+		Account:           as.Profile,
+		AccountID:         as.ID,
+		Name:              name,
+		Region:            region,
+		Created:           bucket.CreationDate,
+		Tags:              map[string]string{},
+		DataEventTrails:   dataEventTrails(as.dataEventSelectors, name),
+		SensitiveData:     as.macieFindings[name],
+		ConfigCompliance:  as.configCompliance[name],
+		GuardDutyFindings: as.guardDutyFindings[name],
+		CloudFrontOrigins: as.cloudFrontOrigins[name],
+		LogSources:        as.logTargets[name],
+	}
+
+	if size, ok := as.bucketSizes[name]; ok {
+		facts.Size = &size
+	}
+
+	if m, ok := as.storageLens[name]; ok {
+		facts.StorageLens = &m
+	}
+
+	if ta, ok := as.trustedAdvisor[name]; ok {
+		facts.TrustedAdvisor = &ta
+	}
+
+	// Tags and Object Ownership are read for every bucket, for its owner and
+	// the ACL report; the rest only for the checks that use them.
+	var err error
+	exposure := as.needs(exposureChecks...)
+	if as.needs(slices.Concat(exposureChecks, policyChecks)...) {
+		if facts.Policy, err = getBucketPolicy(ctx, client, name, region); err != nil {
+			as.fail(name, fmt.Errorf("unable to get policy: %w", err))
+		}
+	}
+	if facts.Policy != nil {
+		metrics := facts.Policy.Metrics()
+		facts.PolicyMetrics = &metrics
+	}
+	if as.needs("public-access", "config-disagreement", "log-bucket", "requester-pays") {
+		if facts.ACL, err = getBucketACL(ctx, client, name, region); err != nil {
+			as.fail(name, fmt.Errorf("unable to get ACL: %w", err))
+		}
+	}
+	if facts.ObjectOwnership, err = getObjectOwnership(ctx, client, name, region); err != nil {
+		as.fail(name, fmt.Errorf("unable to get Object Ownership: %w", err))
+	}
+	if as.needs("public-access", "public-objects", "replication") {
+		if facts.PublicAccessBlock, err = getPublicAccessBlock(ctx, client, name, region); err != nil {
+			as.fail(name, fmt.Errorf("unable to get public access block: %w", err))
+		}
+	}
+	if as.needs("kms-key-policy", "state-bucket", "replication") {
+		if facts.Encryption, err = getEncryption(ctx, client, name, region); err != nil {
+			as.fail(name, fmt.Errorf("unable to get encryption: %w", err))
+		}
+	}
+	if enc := facts.Encryption; enc != nil && enc.KMS && enc.KMSKeyID != "" && !as.s3Compatible && as.needs("kms-key-policy") {
+		if facts.KMSKey, err = as.kmsKeys.get(ctx, enc.KMSKeyID, region); err != nil {
+			as.fail(name, fmt.Errorf("unable to get KMS key: %w", err))
+		}
+	}
+	if facts.Tags, err = getTags(ctx, client, name, region); err != nil {
+		as.fail(name, fmt.Errorf("unable to get tags: %w", err))
+	}
+	if as.needs("requester-pays") {
+		if facts.RequesterPays, err = getRequesterPays(ctx, client, name, region); err != nil {
+			as.fail(name, fmt.Errorf("unable to get Requester Pays: %w", err))
+		}
+	}
+	if as.needs("object-lock", "replication") {
+		if facts.ObjectLock, err = getObjectLock(ctx, client, name, region); err != nil {
+			as.fail(name, fmt.Errorf("unable to get Object Lock: %w", err))
+		}
+	}
+	if as.replication {
+		if facts.Replication, err = getReplication(ctx, client, name, region); err != nil {
+			as.fail(name, fmt.Errorf("unable to get replication: %w", err))
+		}
+	}
+	if as.needs("notification-targets") {
+		if facts.NotificationTargets, err = as.notificationTargets.get(ctx, client, name, region); err != nil {
+			as.fail(name, fmt.Errorf("unable to get event notifications: %w", err))
+		}
+	}
+
+	if as.needs("transfer-acceleration") {
+		if facts.Acceleration, err = getAcceleration(ctx, client, name, region); err != nil {
+			as.fail(name, fmt.Errorf("unable to get Transfer Acceleration: %w", err))
+		}
+	}
+	accelerated := facts.Acceleration != nil && facts.Acceleration.Enabled
+
+	// Without writes there's no probe object, leaving Access Analyzer and the
+	// policy to tell whether the bucket is public.
+	anonymousRead, accelerateRead := false, false
+	if !noWrite && exposure {
+		anonymousRead, accelerateRead = canGetObject(ctx, logger, client, name, accelerated)
+	}
+	if accelerated {
+		facts.Acceleration.AnonymousRead = accelerateRead
+	}
+	facts.Exposure = Exposure{
+		AnonymousRead:  anonymousRead,
+		AccessAnalyzer: as.publicByAccessAnalyzer[name],
+		Policy:         facts.Policy != nil && facts.Policy.IsPublic(),
+	}
+	if (facts.Exposure.AnonymousRead || facts.Exposure.Policy) && !as.s3Compatible && as.needs("public-access") {
+		if facts.Egress, err = estimateEgress(ctx, as.config, name, region, as.egressPricePerGB); err != nil {
+			as.fail(name, fmt.Errorf("unable to estimate egress: %w", err))
+		}
+	}
+	if p := as.typosquatting; p != nil && p.probes(facts) {
+		if facts.Typosquats, err = findTyposquats(ctx, name, as.bucketNames, p.MaxVariants); err != nil {
+			as.fail(name, fmt.Errorf("unable to check for typosquatting: %w", err))
+		}
+	}
+	if as.inventory && as.needs("public-objects") {
+		if facts.PublicObjects, err = findPublicObjects(ctx, client, name, region); err != nil {
+			as.fail(name, fmt.Errorf("unable to audit inventory: %w", err))
+		}
+	}
+	if p := as.storageCost; p != nil && facts.Size != nil && facts.Size.StandardBytes >= int64(p.MinSizeGB*(1<<30)) {
+		if facts.Tiering, err = getStorageTiering(ctx, client, name, region); err != nil {
+			as.fail(name, err)
+		}
+	}
+	if p := as.stateBuckets; p != nil {
+		if facts.StateBucket, err = findStateFiles(ctx, client, name, region, p); err != nil {
+			as.fail(name, fmt.Errorf("unable to look for state files: %w", err))
+		}
+	}
+	if p := as.activity; p != nil {
+		if facts.Activity, err = getBucketActivity(ctx, as.config, client, name, region, p.Days, p.SampleSize); err != nil {
+			as.fail(name, fmt.Errorf("unable to get activity: %w", err))
+		}
+	}
+
+	return facts
+}
+
+// needs reports whether facts for any of the checks are wanted: whether one
+// of them is selected, or custom rules or plugins are run, which may use any
+// fact.
+func (as *accountScan) needs(checks ...string) bool {
+	return as.custom || slices.ContainsFunc(checks, as.selection.enabled)
+}
+
+// getBucketPolicy returns the parsed bucket policy, or nil if the bucket has
+// none.
+func getBucketPolicy(ctx context.Context, client awsapi.S3, bucketName string, region string) (*policy.Policy, error) {
+	out, err := client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{Bucket: &bucketName}, inRegion(region))
+	if isErrorCode(err, "NoSuchBucketPolicy") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.Parse(aws.ToString(out.Policy))
+}
+
+func getBucketACL(ctx context.Context, client awsapi.S3, bucketName string, region string) (*ACL, error) {
+	out, err := client.GetBucketAcl(ctx, &s3.GetBucketAclInput{Bucket: &bucketName}, inRegion(region))
+	if err != nil {
+		return nil, err
+	}
+
+	acl := &ACL{Grants: []Grant{}}
+	if out.Owner != nil {
+		acl.Owner = aws.ToString(out.Owner.ID)
+	}
+
+	for _, grant := range out.Grants {
+		if grant.Grantee == nil {
+			continue
+		}
+
+		grantee := aws.ToString(grant.Grantee.ID)
+		switch grant.Grantee.Type {
+		case s3types.TypeGroup:
+			grantee = aws.ToString(grant.Grantee.URI)
+		case s3types.TypeAmazonCustomerByEmail:
+			grantee = aws.ToString(grant.Grantee.EmailAddress)
+		}
+
+		acl.Grants = append(acl.Grants, Grant{
+			Type:       string(grant.Grantee.Type),
+			Grantee:    grantee,
+			Permission: string(grant.Permission),
+		})
+	}
+
+	return acl, nil
+}
+
+// getObjectOwnership returns the bucket's Object Ownership setting. Buckets
+// without ownership controls behave as ObjectWriter.
+func getObjectOwnership(ctx context.Context, client awsapi.S3, bucketName string, region string) (string, error) {
+	out, err := client.GetBucketOwnershipControls(ctx, &s3.GetBucketOwnershipControlsInput{Bucket: &bucketName}, inRegion(region))
+	if isErrorCode(err, "OwnershipControlsNotFoundError") {
+		return string(s3types.ObjectOwnershipObjectWriter), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if out.OwnershipControls == nil || len(out.OwnershipControls.Rules) == 0 {
+		return string(s3types.ObjectOwnershipObjectWriter), nil
+	}
+
+	return string(out.OwnershipControls.Rules[0].ObjectOwnership), nil
+}
+
+// getPublicAccessBlock returns the bucket-level settings, or nil if none are
+// configured.
+func getPublicAccessBlock(ctx context.Context, client awsapi.S3, bucketName string, region string) (*PublicAccessBlock, error) {
+	out, err := client.GetPublicAccessBlock(ctx, &s3.GetPublicAccessBlockInput{Bucket: &bucketName}, inRegion(region))
+	if isErrorCode(err, "NoSuchPublicAccessBlockConfiguration") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conf := out.PublicAccessBlockConfiguration
+	return &PublicAccessBlock{
+		BlockPublicAcls:       aws.ToBool(conf.BlockPublicAcls),
+		IgnorePublicAcls:      aws.ToBool(conf.IgnorePublicAcls),
+		BlockPublicPolicy:     aws.ToBool(conf.BlockPublicPolicy),
+		RestrictPublicBuckets: aws.ToBool(conf.RestrictPublicBuckets),
+	}, nil
+}
+
+// getEncryption returns the default encryption rule, or nil if there is
+// none.
+func getEncryption(ctx context.Context, client awsapi.S3, bucketName string, region string) (*Encryption, error) {
+	out, err := client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: &bucketName}, inRegion(region))
+	if isErrorCode(err, "ServerSideEncryptionConfigurationNotFoundError") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if out.ServerSideEncryptionConfiguration == nil {
+		return nil, nil
+	}
+
+	for _, rule := range out.ServerSideEncryptionConfiguration.Rules {
+		if rule.ApplyServerSideEncryptionByDefault == nil {
+			continue
+		}
+
+		algorithm := rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm
+		return &Encryption{
+			Algorithm:        string(algorithm),
+			KMS:              algorithm == s3types.ServerSideEncryptionAwsKms || algorithm == s3types.ServerSideEncryptionAwsKmsDsse,
+			KMSKeyID:         aws.ToString(rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID),
+			BucketKeyEnabled: aws.ToBool(rule.BucketKeyEnabled),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func getTags(ctx context.Context, client awsapi.S3, bucketName string, region string) (map[string]string, error) {
+	tags := map[string]string{}
+
+	out, err := client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: &bucketName}, inRegion(region))
+	if isErrorCode(err, "NoSuchTagSet") {
+		return tags, nil
+	}
+	if err != nil {
+		return tags, err
+	}
+
+	for _, tag := range out.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	return tags, nil
+}
+
+// getRequesterPays returns whether requesters, rather than the bucket owner,
+// pay for requests and downloads.
+func getRequesterPays(ctx context.Context, client awsapi.S3, bucketName string, region string) (bool, error) {
+	out, err := client.GetBucketRequestPayment(ctx, &s3.GetBucketRequestPaymentInput{Bucket: &bucketName}, inRegion(region))
+	if err != nil {
+		return false, err
+	}
+
+	return out.Payer == s3types.PayerRequester, nil
+}
+
+// inRegion directs an S3 request to the bucket's own region, which is
+// required for most bucket-level operations.
+func inRegion(region string) func(*s3.Options) {
+	return func(o *s3.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	}
+}
+
+// bucketRegion returns the region a bucket is in, which GetBucketLocation
+// gives as empty for us-east-1.
+func bucketRegion(ctx context.Context, client awsapi.S3, bucket string) (string, error) {
+	location, err := client.GetBucketLocation(ctx, &s3.GetBucketLocationInput{Bucket: &bucket})
+	if err != nil {
+		return "", fmt.Errorf("unable to get location of %s: %w", bucket, err)
+	}
+
+	if location.LocationConstraint == "" {
+		return "us-east-1", nil
+	}
+	return string(location.LocationConstraint), nil
+}
+
+func isErrorCode(err error, code string) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == code
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/guardian/s3-audit/awsapi/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+// withS3Endpoint points anonymous probe reads at a test server answering
+// with status, as when scanning an S3-compatible store.
+func withS3Endpoint(t *testing.T, status int) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	saved := endpoints
+	endpoints = serviceEndpoints{"s3": srv.URL}
+	t.Cleanup(func() { endpoints = saved })
+}
+
+func newTestAccountScan(client *mocks.MockS3) *accountScan {
+	as := &accountScan{
+		log:                 slog.New(slog.DiscardHandler),
+		client:              client,
+		notificationTargets: newNotificationTargets(aws.Config{}),
+		s3Compatible:        true,
+	}
+	as.Profile = "test"
+	as.ID = "123456789012"
+
+	return as
+}
+
+// expectBucketConfig expects the reads collectFacts makes of a bucket with
+// a public policy, a public-read ACL and nothing else configured, except
+// for any failing with the error in fail.
+func expectBucketConfig(client *mocks.MockS3, fail map[string]error) {
+	notFound := func(code string) error { return &smithy.GenericAPIError{Code: code} }
+
+	client.EXPECT().GetBucketPolicy(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s3.GetBucketPolicyOutput{Policy: aws.String(`{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::public/*"}]}`)}, fail["GetBucketPolicy"])
+	client.EXPECT().GetBucketAcl(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s3.GetBucketAclOutput{
+			Owner: &s3types.Owner{ID: aws.String("owner")},
+			Grants: []s3types.Grant{{
+				Grantee:    &s3types.Grantee{Type: s3types.TypeGroup, URI: aws.String("http://acs.amazonaws.com/groups/global/AllUsers")},
+				Permission: s3types.PermissionRead,
+			}},
+		}, fail["GetBucketAcl"])
+	client.EXPECT().GetBucketOwnershipControls(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, notFound("OwnershipControlsNotFoundError"))
+	client.EXPECT().GetPublicAccessBlock(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, notFound("NoSuchPublicAccessBlockConfiguration"))
+	client.EXPECT().GetBucketEncryption(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, notFound("ServerSideEncryptionConfigurationNotFoundError"))
+	client.EXPECT().GetBucketTagging(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s3.GetBucketTaggingOutput{TagSet: []s3types.Tag{{Key: aws.String("Owner"), Value: aws.String("web")}}}, nil)
+	client.EXPECT().GetBucketRequestPayment(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s3.GetBucketRequestPaymentOutput{Payer: s3types.PayerBucketOwner}, nil)
+	client.EXPECT().GetObjectLockConfiguration(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, notFound("ObjectLockConfigurationNotFoundError"))
+	client.EXPECT().GetBucketNotificationConfiguration(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s3.GetBucketNotificationConfigurationOutput{}, nil)
+	client.EXPECT().GetBucketAccelerateConfiguration(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s3.GetBucketAccelerateConfigurationOutput{}, nil)
+}
+
+func TestCollectFacts(t *testing.T) {
+	withS3Endpoint(t, http.StatusOK)
+	ctrl := gomock.NewController(t)
+	client := mocks.NewMockS3(ctrl)
+	expectBucketConfig(client, nil)
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any(), gomock.Any()).Return(&s3.PutObjectOutput{}, nil)
+	client.EXPECT().DeleteObject(gomock.Any(), gomock.Any(), gomock.Any()).Return(&s3.DeleteObjectOutput{}, nil)
+
+	as := newTestAccountScan(client)
+	facts := collectFacts(context.Background(), as, s3types.Bucket{Name: aws.String("public"), BucketRegion: aws.String("eu-west-1")})
+
+	if len(as.errors) > 0 {
+		t.Fatalf("scan errors: %v", as.errors)
+	}
+	if facts.Account != "test" || facts.AccountID != "123456789012" || facts.Name != "public" || facts.Region != "eu-west-1" {
+		t.Errorf("facts identify %s (%s) %s in %s", facts.Account, facts.AccountID, facts.Name, facts.Region)
+	}
+	if want := (Exposure{AnonymousRead: true, Policy: true}); facts.Exposure != want {
+		t.Errorf("Exposure = %+v, want %+v", facts.Exposure, want)
+	}
+	if facts.PolicyMetrics == nil {
+		t.Error("PolicyMetrics not set with a policy")
+	}
+	if facts.ACL == nil || len(facts.ACL.Grants) != 1 || facts.ACL.Grants[0].Grantee != "http://acs.amazonaws.com/groups/global/AllUsers" {
+		t.Errorf("ACL = %+v, want the AllUsers grant", facts.ACL)
+	}
+	if facts.ObjectOwnership != string(s3types.ObjectOwnershipObjectWriter) {
+		t.Errorf("ObjectOwnership = %q, want ObjectWriter without ownership controls", facts.ObjectOwnership)
+	}
+	if facts.PublicAccessBlock != nil || facts.Encryption != nil || facts.ObjectLock != nil {
+		t.Error("configuration missing from the bucket isn't nil")
+	}
+	if facts.Tags["Owner"] != "web" {
+		t.Errorf("Tags = %v", facts.Tags)
+	}
+}
+
+func TestCollectFactsErrors(t *testing.T) {
+	withS3Endpoint(t, http.StatusForbidden)
+	ctrl := gomock.NewController(t)
+	client := mocks.NewMockS3(ctrl)
+	expectBucketConfig(client, map[string]error{"GetBucketAcl": errors.New("AccessDenied")})
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any(), gomock.Any()).Return(&s3.PutObjectOutput{}, nil)
+	client.EXPECT().DeleteObject(gomock.Any(), gomock.Any(), gomock.Any()).Return(&s3.DeleteObjectOutput{}, nil)
+
+	as := newTestAccountScan(client)
+	facts := collectFacts(context.Background(), as, s3types.Bucket{Name: aws.String("public")})
+
+	if facts.ACL != nil {
+		t.Errorf("ACL = %+v after failing to read it", facts.ACL)
+	}
+	if len(as.errors) != 1 || as.errors[0].Bucket != "public" {
+		t.Errorf("scan errors = %v, want the ACL error for the bucket", as.errors)
+	}
+	if facts.Policy == nil || facts.Exposure.AnonymousRead {
+		t.Error("failing to read the ACL affected the rest of the bucket")
+	}
+}
+
+func TestCollectFactsSelection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := mocks.NewMockS3(ctrl)
+	client.EXPECT().GetBucketOwnershipControls(gomock.Any(), gomock.Any(), gomock.Any()).Return(&s3.GetBucketOwnershipControlsOutput{}, nil)
+	client.EXPECT().GetBucketTagging(gomock.Any(), gomock.Any(), gomock.Any()).Return(&s3.GetBucketTaggingOutput{}, nil)
+	client.EXPECT().GetObjectLockConfiguration(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s3.GetObjectLockConfigurationOutput{ObjectLockConfiguration: &s3types.ObjectLockConfiguration{ObjectLockEnabled: s3types.ObjectLockEnabledEnabled}}, nil)
+
+	// Anything else, including the probe's write, fails the test.
+	as := newTestAccountScan(client)
+	as.selection = checkSelection{only: []string{"object-lock"}}
+	facts := collectFacts(context.Background(), as, s3types.Bucket{Name: aws.String("archive")})
+
+	if facts.ObjectLock == nil || !facts.ObjectLock.Enabled {
+		t.Errorf("ObjectLock = %+v, want enabled", facts.ObjectLock)
+	}
+	if facts.Exposure.any() {
+		t.Errorf("Exposure = %+v without the probe or a policy", facts.Exposure)
+	}
+}
+
+func TestCanGetObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		putErr   error
+		status   int
+		want     bool
+		deletion bool
+	}{
+		{"anonymous read", nil, http.StatusOK, true, true},
+		{"denied", nil, http.StatusForbidden, false, true},
+		{"write fails", errors.New("AccessDenied"), http.StatusOK, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withS3Endpoint(t, tt.status)
+			ctrl := gomock.NewController(t)
+			client := mocks.NewMockS3(ctrl)
+
+			var written string
+			client.EXPECT().PutObject(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+					written = aws.ToString(in.Key)
+					return &s3.PutObjectOutput{}, tt.putErr
+				})
+			if tt.deletion {
+				client.EXPECT().DeleteObject(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+						if aws.ToString(in.Key) != written {
+							t.Errorf("deleted %q, not the probe object %q", aws.ToString(in.Key), written)
+						}
+						return &s3.DeleteObjectOutput{}, nil
+					})
+			}
+
+			public, accelerated := canGetObject(context.Background(), slog.New(slog.DiscardHandler), client, "bucket", true)
+			if public != tt.want {
+				t.Errorf("canGetObject() = %v, want %v", public, tt.want)
+			}
+			if accelerated {
+				t.Error("read through Transfer Acceleration with a custom endpoint")
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// control is a requirement in a compliance framework.
+type control struct {
+	ID    string
+	Title string
+}
+
+// frameworks lists the controls the built-in checks map to, by framework.
+var frameworks = map[string][]control{
+	"cis": {
+		{"2.1.4", "Ensure that S3 Buckets are configured with 'Block public access (bucket settings)'"},
+		{"3.8", "Ensure that Object-level logging for write events is enabled for S3 bucket"},
+		{"3.9", "Ensure that Object-level logging for read events is enabled for S3 bucket"},
+	},
+	"fsbp": {
+		{"S3.2", "S3 general purpose buckets should block public read access"},
+		{"S3.3", "S3 general purpose buckets should block public write access"},
+		{"S3.6", "S3 general purpose bucket policies should restrict access to other AWS accounts"},
+		{"S3.8", "S3 general purpose buckets should block public access"},
+		{"S3.19", "S3 access points should have block public access settings enabled"},
+		{"S3.22", "S3 general purpose buckets should log object-level write events"},
+		{"S3.23", "S3 general purpose buckets should log object-level read events"},
+		{"CloudFront.13", "CloudFront distributions should use origin access control"},
+		{"GuardDuty.10", "GuardDuty S3 Protection should be enabled"},
+	},
+	"soc2": {
+		{"CC6.1", "Logical access security over protected information assets"},
+		{"CC6.3", "Access is authorised, modified or removed based on least privilege"},
+		{"CC6.6", "Logical access security measures against threats from outside system boundaries"},
+		{"CC7.2", "System components are monitored for anomalies indicative of malicious acts"},
+		{"CC8.1", "Changes to infrastructure are authorised, tested and approved"},
+	},
+}
+
+// checkControls maps each built-in check to the controls it provides
+// evidence for, as "framework:control". Checks comparing our results with
+// other tools aren't mapped, as they're not controls in their own right.
+var checkControls = map[string][]string{
+	"public-access":              {"cis:2.1.4", "fsbp:S3.2", "fsbp:S3.3", "fsbp:S3.8", "soc2:CC6.1", "soc2:CC6.6"},
+	"public-objects":             {"cis:2.1.4", "fsbp:S3.2", "soc2:CC6.1"},
+	"broad-actions":              {"fsbp:S3.6", "soc2:CC6.3"},
+	"negated-elements":           {"fsbp:S3.6", "soc2:CC6.3"},
+	"confused-deputy":            {"soc2:CC6.1"},
+	"policy-complexity":          {"soc2:CC6.3"},
+	"policy-drift":               {"soc2:CC8.1"},
+	"data-events":                {"cis:3.8", "cis:3.9", "fsbp:S3.22", "fsbp:S3.23", "soc2:CC7.2"},
+	"log-bucket":                 {"fsbp:S3.2", "soc2:CC6.1", "soc2:CC7.2"},
+	"state-bucket":               {"soc2:CC6.1"},
+	"cloudfront-origin":          {"fsbp:CloudFront.13", "soc2:CC6.6"},
+	"kms-key-policy":             {"soc2:CC6.1"},
+	"object-lambda-access-point": {"fsbp:S3.19", "soc2:CC6.1"},
+	"guardduty-s3-protection":    {"fsbp:GuardDuty.10", "soc2:CC7.2"},
+	"identity-policy":            {"soc2:CC6.3"},
+	"vpc-endpoint-policy":        {"soc2:CC6.6"},
+}
+
+// accountChecks are checks of account-wide settings rather than buckets.
+var accountChecks = map[string]bool{
+	"guardduty-s3-protection": true,
+	"identity-policy":         true,
+	"vpc-endpoint-policy":     true,
+}
+
+// controlsFor returns the check's control IDs in the framework.
+func controlsFor(check string, framework string) []string {
+	ids := []string{}
+	for _, c := range checkControls[check] {
+		if id, ok := strings.CutPrefix(c, framework+":"); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// printFrameworkReport writes the result of each of the framework's
+// controls. A bucket fails a control if any check mapped to it has a
+// finding for the bucket, and every other scanned bucket passes. Controls
+// only covered by account checks are counted in accounts instead.
+func printFrameworkReport(w io.Writer, framework string, findings findingStream, accounts int, buckets int) error {
+	controls, ok := frameworks[framework]
+	if !ok {
+		return fmt.Errorf("unknown framework %q", framework)
+	}
+
+	failing := map[string][]string{} // control ID to failing resources
+	err := findings(func(f Finding) error {
+		resource := f.Account
+		if f.Bucket != "" {
+			resource += "/" + f.Bucket
+		}
+
+		for _, id := range controlsFor(f.Check, framework) {
+			if !slices.Contains(failing[id], resource) {
+				failing[id] = append(failing[id], resource)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range controls {
+		failed := len(failing[c.ID])
+		status := "PASS"
+		if failed > 0 {
+			status = "FAIL"
+		}
+
+		total := buckets
+		if isAccountControl(framework + ":" + c.ID) {
+			total = accounts
+		}
+
+		fmt.Fprintf(w, "%-14s\t%s\t%4d passed\t%4d failed\t%s\n", c.ID, status, max(total-failed, 0), failed, c.Title)
+		for _, resource := range failing[c.ID] {
+			fmt.Fprintf(w, "\t\t%s\n", resource)
+		}
+	}
+
+	return nil
+}
+
+func isAccountControl(id string) bool {
+	for check, controls := range checkControls {
+		if slices.Contains(controls, id) && !accountChecks[check] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// The Grafana API serves the schedules' saved scans as flat rows, which the
+// JSON API data source (marcusolsson-json-datasource) turns into frames with
+// a JSONPath per field. Each endpoint takes an optional schedule query
+// parameter to limit it to one schedule.
+
+// grafanaFinding is a row of GET /grafana/findings, a finding from the
+// latest scan of a schedule.
+type grafanaFinding struct {
+	Schedule string `json:"schedule"`
+	Time     string `json:"time"`
+	Account  string `json:"account"`
+	Bucket   string `json:"bucket"`
+	Owner    string `json:"owner"`
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Rank     int    `json:"rank"` // the severity as a number to sort by, 0 for LOW
+	Score    int    `json:"score"`
+	Message  string `json:"message"`
+}
+
+// grafanaScore is a row of GET /grafana/scores, an account's posture score
+// and findings in one scan, for plotting over time.
+type grafanaScore struct {
+	Schedule string `json:"schedule"`
+	Time     string `json:"time"`
+	Account  string `json:"account"`
+	Score    int    `json:"score"`
+	Buckets  int    `json:"buckets"`
+	Findings int    `json:"findings"`
+	Critical int    `json:"critical"`
+	High     int    `json:"high"`
+	Medium   int    `json:"medium"`
+	Low      int    `json:"low"`
+}
+
+// grafanaHandler serves the Grafana API from the schedules' history
+// directories under historyDir.
+func grafanaHandler(historyDir string, schedules []Schedule) http.Handler {
+	mux := http.NewServeMux()
+
+	// The JSON API data source tests the connection with the base URL.
+	mux.HandleFunc("GET /grafana", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /grafana/schedules", func(w http.ResponseWriter, r *http.Request) {
+		names := []string{}
+		for _, s := range schedules {
+			names = append(names, s.Name)
+		}
+		writeJSONResponse(w, names)
+	})
+	mux.HandleFunc("GET /grafana/findings", func(w http.ResponseWriter, r *http.Request) {
+		rows := []grafanaFinding{}
+		for _, s := range grafanaSchedules(r, schedules) {
+			latest, err := latestReport(filepath.Join(historyDir, s.Name))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if latest != nil {
+				rows = append(rows, grafanaFindings(s.Name, latest)...)
+			}
+		}
+		writeJSONResponse(w, rows)
+	})
+	mux.HandleFunc("GET /grafana/scores", func(w http.ResponseWriter, r *http.Request) {
+		rows := []grafanaScore{}
+		for _, s := range grafanaSchedules(r, schedules) {
+			reports, err := loadReports(filepath.Join(historyDir, s.Name))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for _, report := range reports {
+				rows = append(rows, grafanaScores(s.Name, report)...)
+			}
+		}
+		slices.SortStableFunc(rows, func(a, b grafanaScore) int { return cmp.Compare(a.Time, b.Time) })
+		writeJSONResponse(w, rows)
+	})
+
+	return mux
+}
+
+// grafanaSchedules returns the schedule asked for, or all of them.
+func grafanaSchedules(r *http.Request, schedules []Schedule) []Schedule {
+	name := r.URL.Query().Get("schedule")
+	if name == "" || name == "All" {
+		return schedules
+	}
+
+	return slices.DeleteFunc(slices.Clone(schedules), func(s Schedule) bool { return s.Name != name })
+}
+
+func grafanaFindings(schedule string, r *savedReport) []grafanaFinding {
+	rows := []grafanaFinding{}
+	for _, f := range r.Findings {
+		rows = append(rows, grafanaFinding{
+			Schedule: schedule,
+			Time:     r.Time.UTC().Format(time.RFC3339),
+			Account:  f.Account,
+			Bucket:   f.Bucket,
+			Owner:    f.Owner,
+			Check:    f.Check,
+			Severity: f.Severity.String(),
+			Rank:     int(f.Severity),
+			Score:    f.Score,
+			Message:  f.Message,
+		})
+	}
+
+	return rows
+}
+
+func grafanaScores(schedule string, r *savedReport) []grafanaScore {
+	byAccount := map[string][]Finding{}
+	for _, f := range r.Findings {
+		byAccount[f.Account] = append(byAccount[f.Account], f)
+	}
+
+	rows := []grafanaScore{}
+	for _, profile := range r.Profiles {
+		counts := severityCounts(byAccount[profile])
+		rows = append(rows, grafanaScore{
+			Schedule: schedule,
+			Time:     r.Time.UTC().Format(time.RFC3339),
+			Account:  profile,
+			Score:    r.Scores[profile],
+			Buckets:  r.Buckets[profile],
+			Findings: len(byAccount[profile]),
+			Critical: counts[SeverityCritical],
+			High:     counts[SeverityHigh],
+			Medium:   counts[SeverityMedium],
+			Low:      counts[SeverityLow],
+		})
+	}
+
+	return rows
+}
+
+// grafanaDashboardMain prints a Grafana dashboard for the Grafana API of
+// s3-audit serve, to import with a JSON API data source pointing at it.
+func grafanaDashboardMain(args []string) {
+	fs := flag.NewFlagSet("grafana-dashboard", flag.ExitOnError)
+	title := fs.String("title", "S3 audit", "dashboard title")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit grafana-dashboard > dashboard.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	check(enc.Encode(grafanaDashboard(*title)), "unable to write dashboard")
+}
+
+// grafanaDashboard is a dashboard of posture scores over time, findings by
+// severity in the latest scans, and a table of the findings. The data
+// source is an input chosen on import.
+func grafanaDashboard(title string) map[string]any {
+	datasource := map[string]any{"type": "marcusolsson-json-datasource", "uid": "${DS_S3_AUDIT}"}
+	target := func(path string, groupBy string, fields ...[2]string) map[string]any {
+		jsonFields := []map[string]any{}
+		for _, f := range fields {
+			jsonFields = append(jsonFields, map[string]any{"jsonPath": "$[*]." + f[0], "type": f[1], "name": f[0]})
+		}
+		t := map[string]any{
+			"refId":                "A",
+			"datasource":           datasource,
+			"urlPath":              path,
+			"method":               "GET",
+			"params":               [][]string{{"schedule", "$schedule"}},
+			"fields":               jsonFields,
+			"cacheDurationSeconds": 60,
+		}
+		if groupBy != "" {
+			t["experimentalGroupByField"] = groupBy
+		}
+		return t
+	}
+	panel := func(id int, typ string, title string, x, y, w, h int, t map[string]any) map[string]any {
+		return map[string]any{
+			"id":         id,
+			"type":       typ,
+			"title":      title,
+			"datasource": datasource,
+			"gridPos":    map[string]int{"x": x, "y": y, "w": w, "h": h},
+			"targets":    []any{t},
+		}
+	}
+
+	scores := panel(1, "timeseries", "Posture score by account", 0, 0, 16, 8,
+		target("/grafana/scores", "account", [2]string{"time", "time"}, [2]string{"score", "number"}, [2]string{"account", "string"}))
+	scores["fieldConfig"] = map[string]any{"defaults": map[string]any{"min": 0, "max": 100}}
+
+	severities := panel(2, "barchart", "Findings by severity, latest scans", 16, 0, 8, 8,
+		target("/grafana/scores", "", [2]string{"account", "string"}, [2]string{"critical", "number"}, [2]string{"high", "number"}, [2]string{"medium", "number"}, [2]string{"low", "number"}))
+	severities["options"] = map[string]any{"stacking": "normal", "xField": "account"}
+	severities["transformations"] = []any{
+		map[string]any{"id": "groupBy", "options": map[string]any{"fields": map[string]any{
+			"account":  map[string]any{"aggregations": []string{}, "operation": "groupby"},
+			"critical": map[string]any{"aggregations": []string{"last"}, "operation": "aggregate"},
+			"high":     map[string]any{"aggregations": []string{"last"}, "operation": "aggregate"},
+			"medium":   map[string]any{"aggregations": []string{"last"}, "operation": "aggregate"},
+			"low":      map[string]any{"aggregations": []string{"last"}, "operation": "aggregate"},
+		}}},
+	}
+
+	findings := panel(3, "table", "Findings, latest scans", 0, 8, 24, 12,
+		target("/grafana/findings", "",
+			[2]string{"severity", "string"}, [2]string{"rank", "number"}, [2]string{"account", "string"}, [2]string{"bucket", "string"},
+			[2]string{"owner", "string"}, [2]string{"check", "string"}, [2]string{"score", "number"}, [2]string{"message", "string"}))
+	findings["options"] = map[string]any{"sortBy": []any{map[string]any{"displayName": "rank", "desc": true}}}
+
+	return map[string]any{
+		"__inputs": []any{map[string]any{
+			"name":     "DS_S3_AUDIT",
+			"label":    "s3-audit",
+			"type":     "datasource",
+			"pluginId": "marcusolsson-json-datasource",
+		}},
+		"title":         title,
+		"uid":           "s3-audit",
+		"schemaVersion": 39,
+		"time":          map[string]string{"from": "now-90d", "to": "now"},
+		"templating": map[string]any{"list": []any{map[string]any{
+			"name":       "schedule",
+			"type":       "query",
+			"datasource": datasource,
+			"includeAll": true,
+			"allValue":   "All",
+			"current":    map[string]any{"text": "All", "value": "All"},
+			"query":      map[string]any{"urlPath": "/grafana/schedules", "fields": []any{map[string]any{"jsonPath": "$[*]"}}},
+		}}},
+		"panels": []any{scores, severities, findings},
+	}
+}
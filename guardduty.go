@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/guardduty"
+	gdtypes "github.com/aws/aws-sdk-go-v2/service/guardduty/types"
+)
+
+// guardDutyLookback is how far back GuardDuty findings are considered recent.
+const guardDutyLookback = 30 * 24 * time.Hour
+
+type guardDutyFinding struct {
+	ID   string `json:"id"`
+	Type string `json:"type"` // e.g. Policy:S3/BucketAnonymousAccessGranted
+}
+
+// guardDutyRegion is GuardDuty's state in a region.
+type guardDutyRegion struct {
+	Region       string
+	Enabled      bool
+	S3Protection bool
+}
+
+// getGuardDuty reports whether GuardDuty and its S3 Protection are enabled
+// in each region, and maps bucket names to recent unarchived S3 findings.
+func getGuardDuty(ctx context.Context, config aws.Config, regions []string) ([]guardDutyRegion, map[string][]guardDutyFinding, error) {
+	errs := []error{}
+	statuses := []guardDutyRegion{}
+	findings := map[string][]guardDutyFinding{}
+
+	for _, region := range regions {
+		client := guardduty.NewFromConfig(config, func(o *guardduty.Options) { o.Region = region })
+		status := guardDutyRegion{Region: region}
+
+		detectors, err := client.ListDetectors(ctx, &guardduty.ListDetectorsInput{})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to list GuardDuty detectors in %s: %w", region, err))
+			continue
+		}
+
+		if len(detectors.DetectorIds) == 0 {
+			statuses = append(statuses, status)
+			continue
+		}
+
+		detectorID := detectors.DetectorIds[0] // there is at most one per region
+		detector, err := client.GetDetector(ctx, &guardduty.GetDetectorInput{DetectorId: &detectorID})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to get GuardDuty detector in %s: %w", region, err))
+			continue
+		}
+
+		status.Enabled = detector.Status == gdtypes.DetectorStatusEnabled
+		status.S3Protection = status.Enabled && hasS3Protection(detector)
+		statuses = append(statuses, status)
+
+		regionFindings, err := getGuardDutyS3Findings(ctx, client, detectorID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w in %s", err, region))
+		}
+		for bucket, fs := range regionFindings {
+			findings[bucket] = append(findings[bucket], fs...)
+		}
+	}
+
+	return statuses, findings, errors.Join(errs...)
+}
+
+// hasS3Protection checks the feature list, falling back to the older data
+// sources field for detectors that predate features.
+func hasS3Protection(detector *guardduty.GetDetectorOutput) bool {
+	for _, feature := range detector.Features {
+		if feature.Name == gdtypes.DetectorFeatureResultS3DataEvents {
+			return feature.Status == gdtypes.FeatureStatusEnabled
+		}
+	}
+
+	return detector.DataSources != nil && detector.DataSources.S3Logs != nil &&
+		detector.DataSources.S3Logs.Status == gdtypes.DataSourceStatusEnabled
+}
+
+func getGuardDutyS3Findings(ctx context.Context, client *guardduty.Client, detectorID string) (map[string][]guardDutyFinding, error) {
+	errs := []error{}
+	findings := map[string][]guardDutyFinding{}
+	since := time.Now().Add(-guardDutyLookback).UnixMilli()
+
+	ids := []string{}
+	paginator := guardduty.NewListFindingsPaginator(client, &guardduty.ListFindingsInput{
+		DetectorId: &detectorID,
+		FindingCriteria: &gdtypes.FindingCriteria{
+			Criterion: map[string]gdtypes.Condition{
+				"resource.resourceType": {Equals: []string{"S3Bucket"}},
+				"service.archived":      {Equals: []string{"false"}},
+				"updatedAt":             {GreaterThanOrEqual: aws.Int64(since)},
+			},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to list GuardDuty findings: %w", err))
+			break
+		}
+		ids = append(ids, page.FindingIds...)
+	}
+
+	// GetFindings accepts at most 50 IDs per call.
+	for start := 0; start < len(ids); start += 50 {
+		end := min(start+50, len(ids))
+
+		out, err := client.GetFindings(ctx, &guardduty.GetFindingsInput{DetectorId: &detectorID, FindingIds: ids[start:end]})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to get GuardDuty findings: %w", err))
+			break
+		}
+
+		for _, f := range out.Findings {
+			if f.Resource == nil {
+				continue
+			}
+
+			for _, b := range f.Resource.S3BucketDetails {
+				bucket := aws.ToString(b.Name)
+				findings[bucket] = append(findings[bucket], guardDutyFinding{ID: aws.ToString(f.Id), Type: aws.ToString(f.Type)})
+			}
+		}
+	}
+
+	return findings, errors.Join(errs...)
+}
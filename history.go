@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// savedReport is a completed scan, kept in the history directory so later
+// scans can be compared with it.
+type savedReport struct {
+	Time     time.Time           `json:"time"`
+	Profiles []string            `json:"profiles"`
+	Buckets  map[string]int      `json:"buckets"` // buckets scanned per profile
+	Findings []Finding           `json:"findings"`
+	Scores   map[string]int      `json:"scores"`         // posture score per profile, see scoreAccounts
+	Errors   []scanError         `json:"errors"`         // parts of the scan that couldn't be read
+	ACLs     map[string]aclCount `json:"acls,omitempty"` // buckets with ACLs enabled and disabled per profile
+}
+
+func (r savedReport) bucketCount() int {
+	n := 0
+	for _, count := range r.Buckets {
+		n += count
+	}
+
+	return n
+}
+
+func defaultHistoryDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "s3-audit-history")
+}
+
+// saveReport writes the report to dir, named by its time so that names sort
+// chronologically.
+func saveReport(dir string, r savedReport) (string, error) {
+	return saveStreamedReport(dir, r, sliceStream(r.Findings))
+}
+
+// saveStreamedReport saves the report as saveReport does, with the findings
+// streamed (e.g. from a findingStore) in place of r.Findings, so that they
+// needn't all be held in memory.
+func saveStreamedReport(dir string, r savedReport, findings findingStream) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	// Reports are written under a temporary name so that a failed save
+	// isn't taken for the latest report.
+	path := filepath.Join(dir, r.Time.UTC().Format("20060102T150405Z")+".json")
+	file, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(path + ".tmp")
+	defer file.Close()
+
+	if err := writeStreamedReport(file, r, findings); err != nil {
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	return path, os.Rename(path+".tmp", path)
+}
+
+// writeStreamedReport writes the report as JSON with the findings streamed
+// in place of r.Findings, one to a line.
+func writeStreamedReport(out io.Writer, r savedReport, findings findingStream) error {
+	// The findings are spliced into the rest of the report. Strings are
+	// escaped, so the first "findings" key is the report's own.
+	r.Findings = []Finding{}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	head, tail, _ := bytes.Cut(data, []byte(`"findings":[`))
+
+	w := bufio.NewWriter(out)
+	w.Write(head)
+	w.WriteString(`"findings":[`)
+	first := true
+	err = findings(func(f Finding) error {
+		data, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		if !first {
+			w.WriteByte(',')
+		}
+		first = false
+		w.WriteByte('\n')
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.Write(tail)
+	w.WriteByte('\n')
+
+	return w.Flush()
+}
+
+func loadReport(path string) (*savedReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r savedReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+
+	return &r, nil
+}
+
+// loadReportSummary loads a saved report without its findings, reading
+// past them rather than holding them, for reports that only need its
+// scores, counts of buckets and the like.
+func loadReportSummary(path string) (*savedReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fields := map[string]json.RawMessage{}
+	dec := json.NewDecoder(bufio.NewReader(file))
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid report %s: %w", path, err)
+		}
+		if key == "findings" {
+			err = skipJSONValue(dec)
+		} else {
+			var value json.RawMessage
+			err = dec.Decode(&value)
+			fields[fmt.Sprint(key)] = value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid report %s: %w", path, err)
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var r savedReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+
+	return &r, nil
+}
+
+// skipJSONValue reads past the next value a token at a time.
+func skipJSONValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// latestReport returns the most recent saved report, or nil if there are
+// none.
+func latestReport(dir string) (*savedReport, error) {
+	paths, err := reportPaths(dir)
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+
+	return loadReport(paths[len(paths)-1])
+}
+
+// latestReportSummary returns the most recent saved report without its
+// findings, or nil if there are none.
+func latestReportSummary(dir string) (*savedReport, error) {
+	paths, err := reportPaths(dir)
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+
+	return loadReportSummary(paths[len(paths)-1])
+}
+
+// loadReports loads every saved report, oldest first.
+func loadReports(dir string) ([]*savedReport, error) {
+	return loadAllReports(dir, loadReport)
+}
+
+// loadReportSummaries loads every saved report without its findings,
+// oldest first.
+func loadReportSummaries(dir string) ([]*savedReport, error) {
+	return loadAllReports(dir, loadReportSummary)
+}
+
+func loadAllReports(dir string, load func(string) (*savedReport, error)) ([]*savedReport, error) {
+	paths, err := reportPaths(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []*savedReport{}
+	for _, path := range paths {
+		r, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+
+	return reports, nil
+}
+
+// reportPaths lists the saved reports in dir, oldest first.
+func reportPaths(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// findingID is a hash of the finding's key, for formats that want a short
+// stable ID.
+func findingID(f Finding) string {
+	id := sha256.Sum256([]byte(findingKey(f)))
+	return hex.EncodeToString(id[:])
+}
+
+// findingKey identifies a finding across scans. Its message isn't part of
+// it, so that a finding whose message changes, e.g. with the number of
+// accounts granted, is the same finding changed.
+func findingKey(f Finding) string {
+	account := f.AccountID
+	if account == "" {
+		account = f.Account
+	}
+
+	return account + "\x00" + f.Bucket + "\x00" + f.Check + "\x00" + f.Subject
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/guardian/s3-audit/policy"
+)
+
+// iamPolicyMain prints the least-privilege IAM policy for the audit role:
+// the actions the selected checks need and, with -remediation, those of
+// s3-audit sweep.
+func iamPolicyMain(args []string) {
+	fs := flag.NewFlagSet("iam-policy", flag.ExitOnError)
+	onlyChecks := fs.String("checks", "", "comma-separated checks the role will run; all if empty")
+	skipChecks := fs.String("skip-checks", "", "comma-separated checks the role won't run")
+	remediation := fs.Bool("remediation", false, "include the permissions s3-audit sweep needs")
+	roleARN := fs.String("role-arn", "*", "the S3 Batch Operations role sweep passes, to limit iam:PassRole to")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit iam-policy [-checks checks] [-remediation]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	sel, err := newCheckSelection(*onlyChecks, *skipChecks, nil)
+	check(err, "invalid checks")
+
+	data, err := json.MarshalIndent(leastPrivilegePolicy(sel, *remediation, *roleARN), "", "  ")
+	check(err, "unable to marshal policy")
+	fmt.Println(string(data))
+}
+
+func leastPrivilegePolicy(sel checkSelection, remediation bool, roleARN string) policy.Policy {
+	p := policy.Policy{
+		Version: "2012-10-17",
+		Statement: []policy.Statement{{
+			Sid:      "S3AuditChecks",
+			Effect:   "Allow",
+			Action:   sel.permissions(),
+			Resource: policy.StringList{"*"},
+		}},
+	}
+
+	if remediation {
+		p.Statement = append(p.Statement,
+			policy.Statement{Sid: "S3AuditSweep", Effect: "Allow", Action: sweepPermissions, Resource: policy.StringList{"*"}},
+			policy.Statement{
+				Sid:       "S3AuditSweepPassRole",
+				Effect:    "Allow",
+				Action:    policy.StringList{"iam:PassRole"},
+				Resource:  policy.StringList{roleARN},
+				Condition: policy.Conditions{"StringEquals": {"iam:PassedToService": {"batchoperations.s3.amazonaws.com"}}},
+			},
+		)
+	}
+
+	return p
+}
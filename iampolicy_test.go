@@ -0,0 +1,53 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLeastPrivilegePolicy(t *testing.T) {
+	tests := []struct {
+		checks string
+		want   []string
+	}{
+		{"public-access", []string{"s3:ListAllMyBuckets", "s3:GetBucketPolicy", "s3:PutObject", "access-analyzer:ListFindings", "s3:ListStorageLensConfigurations", "s3:GetStorageLensConfiguration", "cloudwatch:ListMetrics"}},
+		{"cloudfront-origin", []string{"cloudfront:ListDistributions", "access-analyzer:ListAnalyzers", "s3:GetBucketPolicy", "s3:PutObject", "s3:DeleteObject"}},
+		{"typosquatting", []string{"access-analyzer:ListFindings", "s3:PutObject"}},
+		{"public-objects", []string{"s3:GetBucketLocation", "s3:GetBucketPublicAccessBlock"}},
+		{"object-lock", []string{"s3:GetBucketTagging", "s3:GetBucketObjectLockConfiguration"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.checks, func(t *testing.T) {
+			sel, err := newCheckSelection(tt.checks, "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actions := leastPrivilegePolicy(sel, false, "*").Statement[0].Action
+			for _, action := range tt.want {
+				if !slices.Contains(actions, action) {
+					t.Errorf("policy for %s is missing %s", tt.checks, action)
+				}
+			}
+		})
+	}
+}
+
+// Every check that needs to know whether a bucket is public is allowed to
+// find out.
+func TestExposureChecksPermissions(t *testing.T) {
+	for _, id := range exposureChecks {
+		i := slices.IndexFunc(registry, func(c checkInfo) bool { return c.ID == id })
+		if i < 0 {
+			t.Errorf("exposure check %s isn't registered", id)
+			continue
+		}
+
+		for _, action := range exposurePermissions {
+			if !slices.Contains(registry[i].Permissions, action) {
+				t.Errorf("%s is missing %s", id, action)
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/guardian/s3-audit/policy"
+)
+
+// identityPolicy is an inline or managed policy attached to a role, user or
+// group.
+type identityPolicy struct {
+	Identity string // e.g. "role deploy"
+	Policy   string // the inline policy's name or the managed policy's ARN
+	Document *policy.Policy
+}
+
+// getIdentityPolicies lists the policies of the account's roles, users and
+// groups. Service-linked roles are left out: AWS defines their policies and
+// they can't be changed.
+func getIdentityPolicies(ctx context.Context, client *iam.Client) ([]identityPolicy, error) {
+	type attachment struct{ identity, arn string }
+	policies := []identityPolicy{}
+	attached := []attachment{}
+	managed := map[string]*policy.Policy{}
+	errs := []string{}
+
+	add := func(identity string, name string, doc *string) {
+		p, err := parseIdentityPolicy(doc)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s policy %s: %v", identity, name, err))
+			return
+		}
+		policies = append(policies, identityPolicy{Identity: identity, Policy: name, Document: p})
+	}
+	attach := func(identity string, list []iamtypes.AttachedPolicy) {
+		for _, a := range list {
+			attached = append(attached, attachment{identity, aws.ToString(a.PolicyArn)})
+		}
+	}
+
+	paginator := iam.NewGetAccountAuthorizationDetailsPaginator(client, &iam.GetAccountAuthorizationDetailsInput{
+		Filter: []iamtypes.EntityType{
+			iamtypes.EntityTypeRole,
+			iamtypes.EntityTypeUser,
+			iamtypes.EntityTypeGroup,
+			iamtypes.EntityTypeLocalManagedPolicy,
+			iamtypes.EntityTypeAWSManagedPolicy,
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get account authorization details: %w", err)
+		}
+
+		for _, role := range page.RoleDetailList {
+			if strings.HasPrefix(aws.ToString(role.Path), "/aws-service-role/") {
+				continue
+			}
+			identity := "role " + aws.ToString(role.RoleName)
+			for _, p := range role.RolePolicyList {
+				add(identity, aws.ToString(p.PolicyName), p.PolicyDocument)
+			}
+			attach(identity, role.AttachedManagedPolicies)
+		}
+		for _, user := range page.UserDetailList {
+			identity := "user " + aws.ToString(user.UserName)
+			for _, p := range user.UserPolicyList {
+				add(identity, aws.ToString(p.PolicyName), p.PolicyDocument)
+			}
+			attach(identity, user.AttachedManagedPolicies)
+		}
+		for _, group := range page.GroupDetailList {
+			identity := "group " + aws.ToString(group.GroupName)
+			for _, p := range group.GroupPolicyList {
+				add(identity, aws.ToString(p.PolicyName), p.PolicyDocument)
+			}
+			attach(identity, group.AttachedManagedPolicies)
+		}
+
+		for _, mp := range page.Policies {
+			for _, version := range mp.PolicyVersionList {
+				if !version.IsDefaultVersion {
+					continue
+				}
+				p, err := parseIdentityPolicy(version.Document)
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("policy %s: %v", aws.ToString(mp.Arn), err))
+					continue
+				}
+				managed[aws.ToString(mp.Arn)] = p
+			}
+		}
+	}
+
+	// Managed policies may come on a later page than the identities they're
+	// attached to.
+	for _, a := range attached {
+		if p, ok := managed[a.arn]; ok {
+			policies = append(policies, identityPolicy{Identity: a.identity, Policy: a.arn, Document: p})
+		}
+	}
+
+	if len(errs) > 0 {
+		return policies, fmt.Errorf("unable to parse identity policies: %s", strings.Join(errs, "; "))
+	}
+	return policies, nil
+}
+
+// parseIdentityPolicy parses a policy document as IAM returns it, URL
+// encoded.
+func parseIdentityPolicy(doc *string) (*policy.Policy, error) {
+	decoded, err := url.QueryUnescape(aws.ToString(doc))
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.Parse(decoded)
+}
+
+// objectReadScope describes which buckets a statement's resources let
+// objects be read from: every bucket, or those matching none of the
+// account's own. A bucket pattern matching one of the account's buckets
+// can't be told apart from a grant within the account, so isn't reported.
+func objectReadScope(resources []string, bucketNames map[string]bool) (every bool, outside []string) {
+	for _, resource := range resources {
+		if resource == "*" {
+			return true, nil
+		}
+
+		// Bucket and object ARNs have no region or account. Other resources,
+		// such as access points, are governed by their own policies too.
+		parts := strings.SplitN(resource, ":", 6)
+		if len(parts) < 6 || parts[0] != "arn" || parts[3] != "" || parts[4] != "" {
+			continue
+		}
+		if matched, _ := path.Match(strings.ToLower(parts[2]), "s3"); !matched {
+			continue
+		}
+
+		// A bucket ARN without a key covers the bucket but none of its objects.
+		bucket, _, hasKey := strings.Cut(parts[5], "/")
+		if !hasKey && !strings.HasSuffix(bucket, "*") {
+			continue
+		}
+		if bucket == "*" {
+			return true, nil
+		}
+
+		owned := false
+		for name := range bucketNames {
+			if matched, _ := path.Match(bucket, name); matched {
+				owned = true
+				break
+			}
+		}
+		if !owned && !slices.Contains(outside, bucket) {
+			outside = append(outside, bucket)
+		}
+	}
+
+	return false, outside
+}
+
+// identityPolicyFindings reports identity policies letting their role, user
+// or group read objects in every bucket, or in buckets outside the account.
+// Bucket policies only show who a bucket lets in; these show who is let in
+// from the other side. Reading every bucket is HIGH with every S3 action and
+// otherwise MEDIUM, as is reading other accounts' buckets; a condition on
+// the statement lowers either by one.
+func identityPolicyFindings(as *accountScan, policies []identityPolicy) []Finding {
+	findings := []Finding{}
+
+	for _, p := range policies {
+		for _, read := range p.Document.ObjectReads() {
+			every, outside := objectReadScope(read.Resources, as.bucketNames)
+
+			finding := Finding{
+				Account:   as.Profile,
+				AccountID: as.ID,
+				Check:     "identity-policy",
+				Subject:   fmt.Sprintf("%s %s %s", p.Identity, p.Policy, cmp.Or(read.Sid, strings.Join(read.Resources, ","))),
+				Severity:  SeverityMedium,
+				Details:   []string{"actions: " + strings.Join(read.Actions, ", ")},
+			}
+			if read.Sid != "" {
+				finding.Details = append(finding.Details, "statement: "+read.Sid)
+			}
+
+			switch {
+			case every:
+				if read.FullAccess() {
+					finding.Severity = SeverityHigh
+				}
+				finding.Message = fmt.Sprintf("%s can read objects in every bucket through policy %s", p.Identity, p.Policy)
+			case len(outside) > 0:
+				finding.Message = fmt.Sprintf("%s can read objects in buckets outside the account (%s) through policy %s", p.Identity, strings.Join(outside, ", "), p.Policy)
+			default:
+				continue
+			}
+
+			if read.Conditional && finding.Severity > SeverityLow {
+				finding.Severity--
+				finding.Details = append(finding.Details, "the statement has conditions, which may limit it")
+			}
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/guardian/s3-audit/awsapi"
+)
+
+// maxPublicObjectKeys limits how many public object keys are kept as
+// examples; the count covers all of them.
+const maxPublicObjectKeys = 10
+
+// inventoryDate matches the dated folders S3 Inventory writes each report to.
+var inventoryDate = regexp.MustCompile(`/(\d{4}-\d{2}-\d{2}T\d{2}-\d{2}Z)/$`)
+
+// publicGroups are the ACL grantees that make an object public.
+var publicGroups = []string{
+	"http://acs.amazonaws.com/groups/global/AllUsers",
+	"http://acs.amazonaws.com/groups/global/AuthenticatedUsers",
+}
+
+// publicObjects summarises the publicly readable objects in an inventory
+// report.
+type publicObjects struct {
+	Report string   // date of the report
+	Count  int      // objects readable by AllUsers or AuthenticatedUsers
+	Keys   []string // the first few of them
+}
+
+type inventoryManifest struct {
+	FileFormat string `json:"fileFormat"`
+	FileSchema string `json:"fileSchema"`
+	Files      []struct {
+		Key string `json:"key"`
+	} `json:"files"`
+}
+
+// inventoryACL is the base64-encoded JSON in an inventory's
+// ObjectAccessControlList field.
+type inventoryACL struct {
+	Grants []struct {
+		Permission string `json:"permission"`
+		Type       string `json:"type"`
+		URI        string `json:"uri"`
+	} `json:"grants"`
+}
+
+// findPublicObjects reads the bucket's latest CSV inventory report that
+// includes object ACLs, returning nil if there is none. This finds objects
+// made public by their own ACL without calling GetObjectAcl on each.
+func findPublicObjects(ctx context.Context, client awsapi.S3, bucket string, region string) (*publicObjects, error) {
+	objects := &publicObjects{}
+	report, err := readPublicInventory(ctx, client, bucket, region, func(key string) {
+		objects.Count++
+		if len(objects.Keys) < maxPublicObjectKeys {
+			objects.Keys = append(objects.Keys, key)
+		}
+	})
+	if err != nil || report == "" {
+		return nil, err
+	}
+	objects.Report = report
+
+	return objects, nil
+}
+
+// readPublicInventory calls found with the key of each public object in
+// the bucket's latest inventory report, returning the report's date, or ""
+// if there is no report.
+func readPublicInventory(ctx context.Context, client awsapi.S3, bucket string, region string, found func(key string)) (string, error) {
+	inventory, err := findACLInventory(ctx, client, bucket, region)
+	if err != nil || inventory == nil {
+		return "", err
+	}
+
+	dest := inventory.Destination.S3BucketDestination
+	destBucket := strings.TrimPrefix(aws.ToString(dest.Bucket), "arn:aws:s3:::")
+
+	location, err := client.GetBucketLocation(ctx, &s3.GetBucketLocationInput{Bucket: &destBucket})
+	if err != nil {
+		return "", fmt.Errorf("unable to get location of inventory bucket %s: %w", destBucket, err)
+	}
+	destRegion := string(location.LocationConstraint)
+	if destRegion == "" {
+		destRegion = "us-east-1"
+	}
+
+	prefix := bucket + "/" + aws.ToString(inventory.Id) + "/"
+	if p := aws.ToString(dest.Prefix); p != "" {
+		prefix = strings.TrimSuffix(p, "/") + "/" + prefix
+	}
+
+	report, err := latestInventoryReport(ctx, client, destBucket, destRegion, prefix)
+	if err != nil || report == "" {
+		return "", err
+	}
+
+	manifest, err := getInventoryManifest(ctx, client, destBucket, destRegion, prefix+report+"/manifest.json")
+	if err != nil {
+		return "", err
+	}
+
+	schema := strings.Split(manifest.FileSchema, ",")
+	for i := range schema {
+		schema[i] = strings.TrimSpace(schema[i])
+	}
+	keyColumn := slices.Index(schema, "Key")
+	aclColumn := slices.Index(schema, "ObjectAccessControlList")
+	if manifest.FileFormat != "CSV" || keyColumn < 0 || aclColumn < 0 {
+		return "", fmt.Errorf("inventory report %s is not CSV with Key and ObjectAccessControlList columns", report)
+	}
+
+	for _, file := range manifest.Files {
+		err := scanInventoryFile(ctx, client, destBucket, destRegion, file.Key, keyColumn, aclColumn, found)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return report, nil
+}
+
+func findACLInventory(ctx context.Context, client awsapi.S3, bucket string, region string) (*s3types.InventoryConfiguration, error) {
+	input := &s3.ListBucketInventoryConfigurationsInput{Bucket: &bucket}
+	for {
+		out, err := client.ListBucketInventoryConfigurations(ctx, input, inRegion(region))
+		if err != nil {
+			return nil, fmt.Errorf("unable to list inventory configurations: %w", err)
+		}
+
+		for _, c := range out.InventoryConfigurationList {
+			isUsable := aws.ToBool(c.IsEnabled) &&
+				c.Destination != nil && c.Destination.S3BucketDestination != nil &&
+				c.Destination.S3BucketDestination.Format == s3types.InventoryFormatCsv &&
+				slices.Contains(c.OptionalFields, s3types.InventoryOptionalFieldObjectAccessControlList)
+			if isUsable {
+				return &c, nil
+			}
+		}
+
+		if !aws.ToBool(out.IsTruncated) {
+			return nil, nil
+		}
+		input.ContinuationToken = out.NextContinuationToken
+	}
+}
+
+// latestInventoryReport returns the most recent report folder under prefix,
+// e.g. 2024-01-31T01-00Z.
+func latestInventoryReport(ctx context.Context, client awsapi.S3, bucket string, region string, prefix string) (string, error) {
+	latest := ""
+
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket:    &bucket,
+		Prefix:    &prefix,
+		Delimiter: aws.String("/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx, inRegion(region))
+		if err != nil {
+			return "", fmt.Errorf("unable to list inventory reports in %s: %w", bucket, err)
+		}
+
+		for _, p := range page.CommonPrefixes {
+			m := inventoryDate.FindStringSubmatch(aws.ToString(p.Prefix))
+			if m != nil && m[1] > latest {
+				latest = m[1]
+			}
+		}
+	}
+
+	return latest, nil
+}
+
+func getInventoryManifest(ctx context.Context, client awsapi.S3, bucket string, region string, key string) (*inventoryManifest, error) {
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key}, inRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get inventory manifest s3://%s/%s: %w", bucket, key, err)
+	}
+	defer out.Body.Close()
+
+	var manifest inventoryManifest
+	if err := json.NewDecoder(out.Body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid inventory manifest s3://%s/%s: %w", bucket, key, err)
+	}
+
+	return &manifest, nil
+}
+
+// scanInventoryFile streams a gzipped CSV inventory file, calling found
+// with the key of each object with public grants.
+func scanInventoryFile(ctx context.Context, client awsapi.S3, bucket string, region string, key string, keyColumn int, aclColumn int, found func(key string)) error {
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key}, inRegion(region))
+	if err != nil {
+		return fmt.Errorf("unable to get inventory file s3://%s/%s: %w", bucket, key, err)
+	}
+	defer out.Body.Close()
+
+	gz, err := gzip.NewReader(out.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read inventory file s3://%s/%s: %w", bucket, key, err)
+	}
+
+	r := csv.NewReader(gz)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read inventory file s3://%s/%s: %w", bucket, key, err)
+		}
+
+		if max(keyColumn, aclColumn) >= len(record) || !isPublicInventoryACL(record[aclColumn]) {
+			continue
+		}
+
+		// Inventory keys are URL-encoded.
+		objectKey, err := url.QueryUnescape(record[keyColumn])
+		if err != nil {
+			objectKey = record[keyColumn]
+		}
+		found(objectKey)
+	}
+}
+
+func isPublicInventoryACL(field string) bool {
+	data, err := base64.StdEncoding.DecodeString(field)
+	if err != nil {
+		return false
+	}
+
+	var acl inventoryACL
+	if err := json.Unmarshal(data, &acl); err != nil {
+		return false
+	}
+
+	for _, grant := range acl.Grants {
+		isRead := grant.Permission == "READ" || grant.Permission == "FULL_CONTROL"
+		if isRead && slices.Contains(publicGroups, grant.URI) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/guardian/s3-audit/policy"
+)
+
+// kmsKey is a bucket's default encryption key. Policy is only fetched for
+// customer-managed keys, as AWS-managed key policies can't be changed.
+type kmsKey struct {
+	ARN             string         `json:"arn"`
+	CustomerManaged bool           `json:"customerManaged"`
+	Policy          *policy.Policy `json:"policy"`
+}
+
+// kmsKeys looks up keys for an account, caching them as many buckets
+// usually share a key.
+type kmsKeys struct {
+	config aws.Config
+	keys   map[string]*kmsKey
+}
+
+func newKMSKeys(config aws.Config) *kmsKeys {
+	return &kmsKeys{config: config, keys: map[string]*kmsKey{}}
+}
+
+// get describes the key, which may be given as an ID, alias or ARN. Key
+// ARNs name their own region; otherwise the key is in the bucket's region.
+func (k *kmsKeys) get(ctx context.Context, keyID string, region string) (*kmsKey, error) {
+	if parts := strings.SplitN(keyID, ":", 6); len(parts) == 6 && parts[0] == "arn" {
+		region = parts[3]
+	}
+
+	cacheKey := region + "/" + keyID
+	if key, ok := k.keys[cacheKey]; ok {
+		return key, nil
+	}
+
+	client := kms.NewFromConfig(k.config, func(o *kms.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	})
+
+	out, err := client.DescribeKey(ctx, &kms.DescribeKeyInput{KeyId: &keyID})
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe KMS key %s: %w", keyID, err)
+	}
+
+	key := &kmsKey{
+		ARN:             aws.ToString(out.KeyMetadata.Arn),
+		CustomerManaged: out.KeyMetadata.KeyManager == kmstypes.KeyManagerTypeCustomer,
+	}
+
+	if key.CustomerManaged {
+		doc, err := client.GetKeyPolicy(ctx, &kms.GetKeyPolicyInput{KeyId: out.KeyMetadata.KeyId})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get policy of KMS key %s: %w", key.ARN, err)
+		}
+
+		if key.Policy, err = policy.Parse(aws.ToString(doc.Policy)); err != nil {
+			return nil, fmt.Errorf("KMS key %s: %w", key.ARN, err)
+		}
+	}
+
+	k.keys[cacheKey] = key
+	return key, nil
+}
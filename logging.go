@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// setupLogging makes the default slog logger write text or JSON at the given
+// level (debug, info, warn or error). Log times use the report timezone, so
+// set it first.
+func setupLogging(w io.Writer, format string, level string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: lvl,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				a.Value = slog.TimeValue(a.Value.Time().In(reportLocation))
+			}
+			return a
+		},
+	}
+
+	switch strings.ToLower(format) {
+	case "text":
+		slog.SetDefault(slog.New(slog.NewTextHandler(w, opts)))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(w, opts)))
+	default:
+		return fmt.Errorf("invalid log format %q: must be text or json", format)
+	}
+
+	return nil
+}
+
+// component returns the default logger tagged with the part of the tool
+// logging, e.g. guardduty.
+func component(name string) *slog.Logger {
+	return slog.Default().With("component", name)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/guardian/s3-audit/awsapi"
+)
+
+// logTargets maps buckets that logs are delivered to onto what delivers
+// them, e.g. "CloudTrail trail org-trail".
+type logTargets map[string][]string
+
+func (t logTargets) add(bucket string, source string) {
+	if bucket != "" && !slices.Contains(t[bucket], source) {
+		t[bucket] = append(t[bucket], source)
+	}
+}
+
+// getLogTargets finds the buckets the account's S3 server access logs,
+// CloudTrail trails and load balancer logs are delivered to. Load balancers
+// log to buckets in their own region, so only the regions with buckets are
+// looked at. Whatever can't be read is skipped and its error returned along
+// with the rest.
+func getLogTargets(ctx context.Context, config aws.Config, client awsapi.S3, buckets []s3types.Bucket) (logTargets, error) {
+	errs := []error{}
+	targets := logTargets{}
+
+	for _, bucket := range buckets {
+		name := aws.ToString(bucket.Name)
+		out, err := client.GetBucketLogging(ctx, &s3.GetBucketLoggingInput{Bucket: bucket.Name}, inRegion(aws.ToString(bucket.BucketRegion)))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to get server access logging of %s: %w", name, err))
+			continue
+		}
+		if out.LoggingEnabled != nil {
+			targets.add(aws.ToString(out.LoggingEnabled.TargetBucket), "server access logs of "+name)
+		}
+	}
+
+	trails, err := cloudtrail.NewFromConfig(config).DescribeTrails(ctx, &cloudtrail.DescribeTrailsInput{IncludeShadowTrails: aws.Bool(true)})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("unable to list CloudTrail trails: %w", err))
+	} else {
+		for _, trail := range trails.TrailList {
+			targets.add(aws.ToString(trail.S3BucketName), "CloudTrail trail "+aws.ToString(trail.Name))
+		}
+	}
+
+	for _, region := range bucketRegions(buckets) {
+		if err := getLoadBalancerLogTargets(ctx, elb.NewFromConfig(config, func(o *elb.Options) { o.Region = region }), targets); err != nil {
+			errs = append(errs, fmt.Errorf("unable to get load balancer logging in %s: %w", region, err))
+		}
+	}
+
+	return targets, errors.Join(errs...)
+}
+
+// loadBalancerLogs are the load balancer attributes naming the buckets its
+// access and connection logs go to, by the attribute saying if they're on.
+var loadBalancerLogs = map[string]string{
+	"access_logs.s3.enabled":     "access_logs.s3.bucket",
+	"connection_logs.s3.enabled": "connection_logs.s3.bucket",
+}
+
+func getLoadBalancerLogTargets(ctx context.Context, client *elb.Client, targets logTargets) error {
+	paginator := elb.NewDescribeLoadBalancersPaginator(client, &elb.DescribeLoadBalancersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, lb := range page.LoadBalancers {
+			out, err := client.DescribeLoadBalancerAttributes(ctx, &elb.DescribeLoadBalancerAttributesInput{LoadBalancerArn: lb.LoadBalancerArn})
+			if err != nil {
+				return err
+			}
+
+			attrs := map[string]string{}
+			for _, a := range out.Attributes {
+				attrs[aws.ToString(a.Key)] = aws.ToString(a.Value)
+			}
+			for enabled, bucket := range loadBalancerLogs {
+				if attrs[enabled] == "true" {
+					targets.add(attrs[bucket], "load balancer "+aws.ToString(lb.LoadBalancerName))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// logBucketCheck holds buckets that logs are delivered to to a stricter
+// standard, as a leaked log bucket exposes the traffic of every system
+// logging to it: never public, service principals only granted with source
+// conditions, and ACLs disabled so that the bucket owner owns every log.
+type logBucketCheck struct{}
+
+func (logBucketCheck) Name() string { return "log-bucket" }
+
+func (c logBucketCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	if len(facts.LogSources) == 0 {
+		return nil
+	}
+
+	sources := []string{fmt.Sprintf("logs delivered here: %s", strings.Join(facts.LogSources, ", "))}
+	findings := []Finding{}
+
+	publicACL := facts.ACL != nil && slices.ContainsFunc(facts.ACL.Grants, func(g Grant) bool { return slices.Contains(publicGroups, g.Grantee) })
+	if facts.Exposure.any() || publicACL {
+		findings = append(findings, Finding{
+			Check:    c.Name(),
+			Subject:  "public",
+			Severity: SeverityCritical,
+			Message:  "log bucket is public, exposing the traffic of everything logging to it",
+			Details:  append([]string{facts.Exposure.String()}, sources...),
+		})
+	}
+
+	if facts.Policy != nil {
+		if deputies := facts.Policy.ConfusedDeputies(); len(deputies) > 0 {
+			finding := Finding{
+				Check:    c.Name(),
+				Subject:  "confused-deputy",
+				Severity: SeverityHigh,
+				Message:  fmt.Sprintf("log bucket grants %d service principal statement(s) without source conditions, so other accounts' logs can be delivered here", len(deputies)),
+			}
+			for _, d := range deputies {
+				finding.Details = append(finding.Details, d.String())
+			}
+			finding.Details = append(finding.Details, sources...)
+			findings = append(findings, finding)
+		}
+	}
+
+	if facts.ObjectOwnership != "" && facts.ObjectOwnership != string(s3types.ObjectOwnershipBucketOwnerEnforced) {
+		findings = append(findings, Finding{
+			Check:    c.Name(),
+			Subject:  "object-ownership",
+			Severity: SeverityMedium,
+			Message:  fmt.Sprintf("log bucket has Object Ownership %s rather than BucketOwnerEnforced, so logs may be owned, and their access granted, by the writer", facts.ObjectOwnership),
+			Details:  sources,
+		})
+	}
+
+	return findings
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/macie2"
+	macietypes "github.com/aws/aws-sdk-go-v2/service/macie2/types"
+)
+
+// macieFinding is a Macie sensitive data discovery finding for a bucket.
+type macieFinding struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// isPersonalOrSecret reports whether the finding is for personal, financial
+// or credential data, as opposed to a team's own custom identifiers.
+func (f macieFinding) isPersonalOrSecret() bool {
+	switch macietypes.FindingType(f.Type) {
+	case macietypes.FindingTypeSensitiveDataS3ObjectPersonal,
+		macietypes.FindingTypeSensitiveDataS3ObjectCredentials,
+		macietypes.FindingTypeSensitiveDataS3ObjectFinancial,
+		macietypes.FindingTypeSensitiveDataS3ObjectMultiple:
+		return true
+	default:
+		return false
+	}
+}
+
+// getMacieFindings maps bucket names to Macie's unarchived sensitive data
+// findings across regions. Macie is regional and often only enabled in some
+// regions, so a region that fails is skipped and its error returned along
+// with the others' results.
+func getMacieFindings(ctx context.Context, config aws.Config, regions []string) (map[string][]macieFinding, error) {
+	errs := []error{}
+	findings := map[string][]macieFinding{}
+
+	for _, region := range regions {
+		client := macie2.NewFromConfig(config, func(o *macie2.Options) { o.Region = region })
+
+		ids := []string{}
+		paginator := macie2.NewListFindingsPaginator(client, &macie2.ListFindingsInput{
+			FindingCriteria: &macietypes.FindingCriteria{
+				Criterion: map[string]macietypes.CriterionAdditionalProperties{
+					"category": {Eq: []string{string(macietypes.FindingCategoryClassification)}},
+					"archived": {Eq: []string{"false"}},
+				},
+			},
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to list Macie findings in %s (is Macie enabled?): %w", region, err))
+				break
+			}
+			ids = append(ids, page.FindingIds...)
+		}
+
+		// GetFindings accepts at most 50 IDs per call.
+		for start := 0; start < len(ids); start += 50 {
+			end := min(start+50, len(ids))
+
+			out, err := client.GetFindings(ctx, &macie2.GetFindingsInput{FindingIds: ids[start:end]})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to get Macie findings in %s: %w", region, err))
+				break
+			}
+
+			for _, f := range out.Findings {
+				if f.ResourcesAffected == nil || f.ResourcesAffected.S3Bucket == nil {
+					continue
+				}
+
+				bucket := aws.ToString(f.ResourcesAffected.S3Bucket.Name)
+				findings[bucket] = append(findings[bucket], macieFinding{ID: aws.ToString(f.Id), Type: string(f.Type)})
+			}
+		}
+	}
+
+	return findings, errors.Join(errs...)
+}
@@ -3,18 +3,29 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"text/template"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer"
 	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"golang.org/x/exp/slices"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/guardian/s3-audit/awsapi"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func main() {
@@ -58,46 +69,442 @@ func main() {
 		for now.
 	*/
 
-	ctx := context.TODO()
+	// Scanning is the default; "scan" may be given for symmetry with the
+	// other subcommands.
+	if len(os.Args) > 1 && os.Args[1] == "scan" {
+		os.Args = slices.Delete(os.Args, 1, 2)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		verifyMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "list-checks" {
+		listChecksMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "sweep" {
+		sweepMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "explain" {
+		explainMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		diffMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "watch" {
+		watchMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "triage" {
+		triageMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "preflight" {
+		preflightMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "iam-policy" {
+		iamPolicyMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serveMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "coordinate" {
+		coordinateMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "work" {
+		workMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "glue-table" {
+		glueTableMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "grafana-dashboard" {
+		grafanaDashboardMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "inventory" {
+		inventoryMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		importMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "canary" {
+		canaryMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "completion" {
+		completionMain(os.Args[2:])
+		return
+	}
+
+	configFile := flag.String("config", "", "JSON config file")
+	profiles := flag.String("profiles", "deployTools", "comma-separated AWS profiles (one per account) to scan")
+	resume := flag.Bool("resume", false, "resume an interrupted scan from its checkpoint")
+	checkpointDir := flag.String("checkpoint-dir", defaultCheckpointDir(), "directory for scan progress and findings")
+	accountNamesFile := flag.String("account-names", "", "JSON file mapping account IDs to friendly names")
+	orgProfile := flag.String("org-profile", "", "AWS profile allowed to list Organizations accounts, used to name accounts")
+	rulesDir := flag.String("rules", "", "directory of custom Rego rules (package s3audit, deny set) to evaluate against each bucket")
+	templatesDir := flag.String("templates", "", "directory of approved bucket policy templates (*.json) to check for drift")
+	onlyChecks := flag.String("checks", "", "comma-separated checks to run, by ID (see s3-audit list-checks) or custom rule or plugin name; all if empty")
+	skipChecks := flag.String("skip-checks", "", "comma-separated checks not to run")
+	inventory := flag.Bool("inventory", false, "read each bucket's S3 Inventory report (CSV, with ObjectAccessControlList) to find publicly readable objects")
+	ownersFile := flag.String("owners", "", "JSON file mapping Stack and App tags, and bucket names, to owning teams")
+	minSeverity := flag.String("min-severity", "LOW", "only report findings of this severity or above: LOW, MEDIUM, HIGH or CRITICAL")
+	onlyCheck := flag.String("only-check", "", "comma-separated checks to report findings from, by ID or the check, rule or plugin name of a custom finding; all that ran if empty. Unlike -checks, every check still runs and is saved")
+	owner := flag.String("owner", "", "only report findings for buckets owned by this team")
+	groupBy := flag.String("group-by", "", "group the report by \"owner\"")
+	framework := flag.String("framework", "", "report per control of a compliance framework (cis, fsbp or soc2) instead of per finding")
+	reportType := flag.String("report", "findings", "report to print: findings, executive for a one-page summary, scorecards for account scores over time, unused for decommission candidates, acls for buckets still using ACLs over time, or replication for where buckets replicate to")
+	triageFile := flag.String("triage", defaultTriageFile(), "file of findings suppressed with s3-audit triage, which are left out of the report: a path, s3://bucket/key or ssm:/parameter-name")
+	triageProfile := flag.String("triage-profile", "", "AWS profile to read an S3 or SSM triage file with; the first of -profiles if empty")
+	historyDir := flag.String("history-dir", defaultHistoryDir(), "directory where completed scans are saved for comparison")
+	reportTemplate := flag.String("template", "", "Go text/template file to render the report with, in place of the built-in reports")
+	sign := flag.Bool("sign", false, "write a SHA-256 digest alongside each saved report")
+	signKey := flag.String("sign-key", "", "asymmetric KMS key to sign saved reports with (implies -sign); check them with s3-audit verify")
+	timeout := flag.Duration("timeout", 0, "stop scanning after this long, e.g. 2h, and report what was found so far")
+	quiet := flag.Bool("quiet", false, "print only findings, without progress logs or the league table; scan errors go to stderr")
+	summaryOnly := flag.Bool("summary", false, "print only the number of findings from each check in each account")
+	emf := flag.Bool("emf", false, "write scan metrics (duration, buckets, errors, findings by severity) to stderr in CloudWatch Embedded Metric Format")
+	logFormat := flag.String("log-format", "text", "log as text or json")
+	logLevel := flag.String("log-level", "info", "least severe log level to write: debug, info, warn or error")
+	otlpEndpoint := flag.String("otlp-endpoint", "", "send OpenTelemetry traces of each account, bucket and AWS request to this OTLP/HTTP endpoint, e.g. http://localhost:4318 (or set OTEL_EXPORTER_OTLP_ENDPOINT)")
+	debug := flag.Bool("debug", false, "log every AWS request with its endpoint, status, request ID and retries (implies -log-level debug)")
+	var outputs stringList
+	flag.Var(&outputs, "output", "also send the findings to json=path (- for stdout), securityhub=profile, slack=webhook-url, ocsf=path, parquet=dir-or-s3-url or sns=topic-arn; may be repeated (see outputs in the config file)")
+	var onlyBuckets stringList
+	flag.Var(&onlyBuckets, "bucket", "only scan this bucket, skipping account-level checks; may be repeated (the report isn't saved to the history)")
+	noWriteFlag := flag.Bool("no-write", false, "make no changes in any account, skipping the anonymous read probe (also set by S3_AUDIT_NO_WRITE)")
+	actionLog := flag.String("action-log", "", "append every change made in an account, e.g. probe objects, to this file as JSON lines with request IDs")
+	endpointURL := flag.String("endpoint-url", "", "send AWS requests to this URL instead, e.g. http://localhost:4566 for LocalStack (see also endpoints in the config file)")
+	discovery := flag.String("discovery", "list-buckets", "how to find buckets: list-buckets, or resource-explorer to cross-check ListBuckets against a Resource Explorer search (needs resource-explorer-2:ListIndexes and resource-explorer-2:Search)")
+	var snapshotLocations stringList
+	flag.Var(&snapshotLocations, "config-snapshot", "scan the buckets recorded in AWS Config snapshots or exports instead of calling the accounts: a file, directory or s3://bucket/prefix (read with the first of -profiles); may be repeated. Only checks of the recorded configuration run")
+	s3Compatible := flag.Bool("s3-compatible", false, "the S3 endpoint is an S3-compatible store such as MinIO or Ceph: only call S3 and skip checks of AWS-only features (see s3Compatible in the config file)")
+	flag.Parse()
+
+	// Ctrl-C or the timeout cancels in-flight requests, after which the
+	// partial results are reported and the checkpoint kept for -resume.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
 
-	config, err := config.LoadDefaultConfig(
-		ctx,
-		config.WithRegion("eu-west-1"),
-		config.WithSharedConfigProfile("deployTools"),
-	)
-	check(err, "unable to load AWS config")
+	started := time.Now()
+	ctx = withScanID(ctx, started.UTC().Format("20060102T150405Z"))
+	accounts := strings.Split(*profiles, ",")
+	awsProfile := accounts[0] // for reading and writing outside the accounts scanned
+
+	conf, err := loadConfigFile(*configFile)
+	check(err, "unable to load config")
+
+	check(setReportTime(conf.Timezone, conf.TimeFormat), "invalid config")
+	check(setEndpoints(*endpointURL, conf.Endpoints), "invalid endpoints")
+	conf.S3Compatible = conf.S3Compatible || *s3Compatible
+	if *discovery != "list-buckets" && *discovery != discoveryResourceExplorer {
+		check(fmt.Errorf("unknown discovery %q: must be list-buckets or resource-explorer", *discovery), "invalid -discovery")
+	}
+	check(checkS3Compatible(conf), "invalid endpoints")
+	check(setWriteGuard(*noWriteFlag, *actionLog), "invalid -action-log")
+	for _, o := range outputs {
+		output, err := parseOutput(o)
+		check(err, "invalid -output")
+		conf.Outputs = append(conf.Outputs, output)
+	}
+	if *debug {
+		*logLevel = "debug"
+		debugRequests = true
+	}
+	if *quiet && !*debug {
+		*logLevel = "error"
+	}
+	check(setupLogging(os.Stderr, *logFormat, *logLevel), "invalid logging flags")
+
+	// An offline scan's accounts are those in the snapshots.
+	var snapshots configSnapshots
+	if len(snapshotLocations) > 0 {
+		snapshots, err = loadConfigSnapshots(ctx, snapshotLocations, awsProfile)
+		check(err, "unable to load Config snapshots")
+		accounts = snapshots.accounts()
+	}
 
-	client := s3.NewFromConfig(config)
-	buckets, err := client.ListBuckets(ctx, &s3.ListBucketsInput{})
-	check(err, "unable to list buckets")
+	shutdownTracing, err := setupTracing(ctx, *otlpEndpoint)
+	check(err, "unable to set up tracing")
+	ctx, span := tracer.Start(ctx, "scan", trace.WithAttributes(attribute.String("scan", scanID(ctx)), attribute.StringSlice("accounts", accounts)))
 
-	aaClient := accessanalyzer.NewFromConfig(config)
+	expressions, err := compileExpressions(conf.Expressions)
+	check(err, "unable to compile expressions")
+
+	plugins, err := startPlugins(ctx, conf.Plugins)
+	check(err, "unable to start plugins")
+	defer stopPlugins(plugins)
+
+	// A targeted scan keeps its own checkpoint, so as not to disturb an
+	// interrupted full scan.
+	if len(onlyBuckets) > 0 {
+		*checkpointDir = filepath.Join(*checkpointDir, "targeted")
+	}
+	cp, err := loadCheckpoint(*checkpointDir, accounts, *resume)
+	check(err, "unable to load checkpoint")
+
+	store, err := openFindingStore(cp.findingsPath(), cp.Offset)
+	check(err, "unable to open findings store")
+	defer store.Close()
+
+	org, err := loadOrganization(ctx, *orgProfile)
+	check(err, "unable to list organization accounts")
+
+	names, err := loadAccountNames(*accountNamesFile, org)
+	check(err, "unable to load account names")
+
+	templates, err := loadTemplates(*templatesDir)
+	check(err, "unable to load policy templates")
+
+	rules, err := loadRules(ctx, *rulesDir)
+	check(err, "unable to load rules")
+
+	var tmpl *template.Template
+	if *reportTemplate != "" {
+		tmpl, err = loadReportTemplate(*reportTemplate)
+		check(err, "unable to load report template")
+	}
+
+	var threshold Severity
+	check(threshold.UnmarshalText([]byte(*minSeverity)), "invalid -min-severity")
+	reported, err := newReportSelection(*onlyCheck, customCheckNames(rules, expressions, plugins), rules != nil || len(plugins) > 0)
+	check(err, "invalid -only-check")
+
+	teams, err := loadOwners(*ownersFile)
+	check(err, "unable to load owners")
+
+	scanner := &scanner{store: store, accountNames: names, organization: org, owners: teams, templates: templates, rules: rules, expressions: expressions, plugins: plugins, conf: conf, inventory: *inventory, clients: newAWSClients, buckets: onlyBuckets, progress: cp, s3Compatible: conf.S3Compatible, discovery: *discovery, snapshots: snapshots}
+	scanner.selection, err = newCheckSelection(*onlyChecks, *skipChecks, customCheckNames(rules, expressions, plugins))
+	check(err, "invalid checks")
+	scanner.selection.s3Compatible = conf.S3Compatible
+	scanner.selection.offline = snapshots != nil
+	if scanner.selection.enabled("replication") {
+		scanner.replication = cp.Replication
+		if scanner.replication == nil {
+			scanner.replication = newReplicationMap()
+		}
+	}
+	scanner.checks, err = scanner.builtinChecks()
+	check(err, "invalid config")
+
+	var partialErrors []scanError // from an account whose scan was cancelled
+	for _, profile := range accounts {
+		if cp.isCompleted(profile) {
+			slog.Info("skipping account, already scanned", "account", profile)
+			continue
+		}
+
+		buckets, errs, err := scanner.scanAccount(ctx, profile)
+		if ctx.Err() != nil {
+			partialErrors = errs
+			break
+		}
+		check(err, "unable to scan "+profile)
+
+		offset, err := store.Offset()
+		check(err, "unable to flush findings")
+		cp.Replication = scanner.replication
+		check(cp.markCompleted(profile, offset, buckets, scanner.acls[profile], errs), "unable to save checkpoint")
+	}
+
+	// Replicas are compared with their sources once every account is in.
+	if scanner.replication != nil && ctx.Err() == nil {
+		for _, f := range scanner.replication.findings() {
+			check(store.Add(f), "unable to record finding")
+		}
+	}
+
+	span.End()
+	if err := shutdownTracing(context.WithoutCancel(ctx)); err != nil {
+		slog.Warn("unable to send traces", "error", err)
+	}
+
+	// Findings are streamed from the store into the scores, the saved report
+	// and what is reported, rather than held in memory. Only the reports
+	// that need them all at once, templates and the executive summary,
+	// collect them.
+	current := savedReport{Time: time.Now(), Profiles: accounts, Buckets: cp.Buckets, ACLs: cp.ACLs, Findings: []Finding{}, Errors: slices.Concat(cp.Errors, partialErrors)}
+	lost := scoreLost{}
+	err = store.Each(func(f Finding) error {
+		lost.add(f)
+		return nil
+	})
+	check(err, "unable to read findings")
+	current.Scores = lost.scores(current.Buckets)
+
+	previous, err := latestReportSummary(*historyDir)
+	check(err, "unable to load previous report")
+
+	// A partial or targeted scan isn't saved, as it would look like buckets
+	// had been fixed when compared with the next one.
+	stopped := ctx.Err() != nil
+	if stopped {
+		slog.Warn("scan stopped early, reporting partial results; run again with -resume to finish", "reason", context.Cause(ctx))
+		stop()
+	}
+	for _, name := range onlyBuckets {
+		if !stopped && !scanner.found[name] {
+			slog.Warn("bucket not found in any account scanned", "bucket", name)
+		}
+	}
 
-	accessAnalyzerPublicBuckets := getAccessAnalyzerPublicBuckets(aaClient)
-	log.Println("aa buckets: ", accessAnalyzerPublicBuckets)
+	reportPath := ""
+	if !stopped && len(onlyBuckets) == 0 {
+		reportPath, err = saveStreamedReport(*historyDir, current, store.Each)
+		check(err, "unable to save report")
+	}
 
-	for _, bucket := range buckets.Buckets {
-		isPublic := canGetObject(client, *bucket.Name)
-		isAWSPublic := slices.Contains(accessAnalyzerPublicBuckets, *bucket.Name)
+	if reportPath != "" && (*sign || *signKey != "") {
+		config, err := loadConfig(ctx, awsProfile)
+		check(err, "unable to load AWS config")
+		check(signReport(ctx, reportPath, config, *signKey), "unable to sign report")
+		slog.Info("saved signed report", "path", reportPath)
+	}
 
-		if isPublic || isAWSPublic {
-			fmt.Printf("%-60s\t(public: %v, awspublic: %v)\n", *bucket.Name, isPublic, isAWSPublic)
+	// Suppressed findings are still saved, so they can be reviewed again.
+	if *triageProfile == "" {
+		*triageProfile = awsProfile
+	}
+	suppressions, err := loadTriage(context.WithoutCancel(ctx), triageLocation{path: *triageFile, profile: *triageProfile})
+	check(err, "unable to load triage file")
+	reportedFindings := reportFilter{suppressions, reported, threshold, *owner}.apply(store.Each)
+
+	switch {
+	case tmpl != nil:
+		current.Findings, err = reportedFindings.collect()
+		check(err, "unable to read findings")
+		check(printTemplateReport(os.Stdout, tmpl, current), "unable to render report")
+	case *framework != "":
+		err = printFrameworkReport(os.Stdout, *framework, reportedFindings, len(cp.Completed), current.bucketCount())
+		check(err, "unable to report")
+	case *summaryOnly:
+		check(printCheckCounts(os.Stdout, reportedFindings), "unable to report")
+	case *reportType == "executive":
+		current.Findings, err = reportedFindings.collect()
+		check(err, "unable to read findings")
+		previous, err = latestReport(*historyDir)
+		check(err, "unable to load previous report")
+		if previous != nil {
+			previous.Findings = suppressions.unsuppressed(previous.Findings)
+			if *owner != "" {
+				previous.Findings = ownedBy(previous.Findings, *owner)
+			}
+			previous.Findings = atLeast(reported.selected(previous.Findings), threshold)
+		}
+		printExecutiveSummary(os.Stdout, current, previous)
+	case *reportType == "scorecards":
+		history, err := loadReportSummaries(*historyDir)
+		check(err, "unable to load previous reports")
+		printScorecardHistory(os.Stdout, history)
+	case *reportType == "unused":
+		check(printDecommissionList(os.Stdout, reportedFindings), "unable to report")
+	case *reportType == "replication":
+		printReplicationMap(os.Stdout, scanner.replication)
+	case *reportType == "acls":
+		history, err := loadReportSummaries(*historyDir)
+		check(err, "unable to load previous reports")
+		if stopped || len(onlyBuckets) > 0 {
+			history = append(history, &current)
 		}
+		printACLReport(os.Stdout, history)
+	default:
+		check(printReport(os.Stdout, reportedFindings.filter(func(f Finding) bool { return !isCostFinding(f) }), *groupBy), "unable to report")
+		check(printCostFindings(os.Stdout, reportedFindings.filter(isCostFinding)), "unable to report")
+		if *quiet {
+			printErrors(os.Stderr, current.Errors)
+			break
+		}
+		fmt.Println()
+		printLeagueTable(os.Stdout, current, previous)
+		if len(current.Errors) > 0 {
+			fmt.Println()
+			printErrors(os.Stdout, current.Errors)
+		}
+	}
+
+	if *emf {
+		check(writeEMF(os.Stderr, current, reportedFindings, time.Since(started)), "unable to write metrics")
+	}
+
+	// A stopped scan's partial results are still sent.
+	check(sendOutputs(context.WithoutCancel(ctx), conf.Outputs, current, reportedFindings), "unable to send findings")
+	check(routeFindings(context.WithoutCancel(ctx), conf.Routing, current, reportedFindings), "unable to send findings to their owners")
+
+	if stopped {
+		os.Exit(1)
 	}
+
+	check(cp.clear(), "unable to remove checkpoint")
+
+	// Reported findings fail the scan, so it can gate CI; -min-severity and
+	// -only-check choose which.
+	counts, err := reportedFindings.severityCounts()
+	check(err, "unable to read findings")
+	if totalCount(counts) > 0 {
+		os.Exit(3)
+	}
+}
+
+// reportFilter chooses the findings a scan reports, of those it saves: not
+// suppressed in triage, from the checks chosen with -only-check, at or above
+// -min-severity and, with -owner, on the owner's buckets.
+type reportFilter struct {
+	triage    *triage
+	checks    checkSelection
+	threshold Severity
+	owner     string
+}
+
+func (rf reportFilter) apply(findings findingStream) findingStream {
+	return findings.filter(func(f Finding) bool {
+		return !rf.triage.isSuppressed(f) && (rf.owner == "" || isOwnedBy(f, rf.owner)) && rf.checks.reports(f) && f.Severity >= rf.threshold
+	})
 }
 
-func getAccessAnalyzerPublicBuckets(client *accessanalyzer.Client) []string {
-	ctx := context.TODO()
+// listBuckets returns every bucket in the account. ListBuckets is only
+// paginated when MaxBuckets is set; without it large accounts are truncated.
+func listBuckets(ctx context.Context, client s3.ListBucketsAPIClient) ([]s3types.Bucket, error) {
+	paginator := s3.NewListBucketsPaginator(client, &s3.ListBucketsInput{
+		MaxBuckets: aws.Int32(1000),
+	})
+
+	buckets := []s3types.Bucket{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
 
+		buckets = append(buckets, page.Buckets...)
+	}
+
+	return buckets, nil
+}
+
+func getAccessAnalyzerPublicBuckets(ctx context.Context, client awsapi.AccessAnalyzer) (map[string]bool, error) {
 	analyzers, err := client.ListAnalyzers(ctx, &accessanalyzer.ListAnalyzersInput{})
 	if err != nil {
-		log.Printf("unable to list analysers: %v\n", err)
-		return []string{}
+		return map[string]bool{}, fmt.Errorf("unable to list analysers: %w", err)
 	}
 
 	if len(analyzers.Analyzers) < 1 {
-		log.Println("no analysers found in account")
-		return []string{}
+		return map[string]bool{}, errors.New("no analysers found in account")
 	}
 
 	analyzer := analyzers.Analyzers[0] // just take first - we assume this is the console one
@@ -110,38 +517,64 @@ func getAccessAnalyzerPublicBuckets(client *accessanalyzer.Client) []string {
 		},
 	})
 
-	buckets := []string{}
+	buckets := map[string]bool{}
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			log.Printf("pagination error for list findings: %v", err)
-			continue
+			return buckets, fmt.Errorf("unable to list Access Analyzer findings: %w", err)
 		}
 
 		for _, finding := range page.Findings {
 			bucketName := strings.TrimPrefix(*finding.Resource, "arn:aws:s3:::")
-			buckets = append(buckets, bucketName)
+			buckets[bucketName] = true
 		}
 	}
 
-	return buckets
+	return buckets, nil
 }
 
-func canGetObject(client *s3.Client, bucketName string) bool {
-	key, err := putObject(client, bucketName, strings.NewReader("test-please-delete-this-file"))
+// canGetObject writes a probe object and reports whether it can be read
+// anonymously, and if accelerated, whether it can also be read through the
+// Transfer Acceleration endpoint.
+func canGetObject(ctx context.Context, logger *slog.Logger, client awsapi.S3, bucketName string, accelerated bool) (bool, bool) {
+	key, err := putObject(ctx, client, bucketName, strings.NewReader("test-please-delete-this-file"))
 	if err != nil {
-		//log.Printf("unable to write to %s: %v", bucketName, err)
-		return false
+		logger.Debug("unable to write probe object", "error", err)
+		return false, false
+	}
+	defer func() {
+		// The probe object is deleted even if the scan has been cancelled.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), probeCleanupTimeout)
+		defer cancel()
+
+		if _, err := deleteObject(ctx, client, bucketName, key); err != nil {
+			logger.Warn("unable to delete probe object", "key", key, "error", err)
+		}
+	}()
+
+	public := true
+	if err := headObject(ctx, objectURL(bucketName, key)); err != nil {
+		logger.Debug("unable to read probe object anonymously", "key", key, "error", err)
+		public = false
 	}
-	defer deleteObject(client, bucketName, key)
 
-	return headObject(client, bucketName, key) == nil
+	// Emulators don't have an accelerate endpoint.
+	acceleratedPublic := false
+	if accelerated && endpoints.s3Endpoint() == "" {
+		acceleratedPublic = true
+		if err := headObject(ctx, accelerateURL(bucketName, key)); err != nil {
+			logger.Debug("unable to read probe object anonymously through Transfer Acceleration", "key", key, "error", err)
+			acceleratedPublic = false
+		}
+	}
+
+	return public, acceleratedPublic
 }
 
-func putObject(client *s3.Client, bucketName string, data io.Reader) (string, error) {
+func putObject(ctx context.Context, client awsapi.S3, bucketName string, data io.Reader) (string, error) {
 	randKey := "sldkfjsldkfjslkdjfsdlkfjiwe"
 
-	_, err := client.PutObject(context.TODO(), &s3.PutObjectInput{
+	_, err := client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket: &bucketName,
 		Key:    &randKey,
 		Body:   data,
@@ -150,31 +583,57 @@ func putObject(client *s3.Client, bucketName string, data io.Reader) (string, er
 	return randKey, err
 }
 
-func headObject(client *s3.Client, bucketName string, key string) error {
-	url := fmt.Sprintf("https://%s.s3.eu-west-1.amazonaws.com/%s", bucketName, key)
-	resp, err := http.Head(url)
+// objectURL is the object's URL on the standard endpoint, or the custom S3
+// endpoint if set.
+func objectURL(bucketName string, key string) string {
+	if endpoint := endpoints.s3Endpoint(); endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(endpoint, "/"), bucketName, key)
+	}
+
+	return fmt.Sprintf("https://%s.s3.eu-west-1.amazonaws.com/%s", bucketName, key)
+}
+
+// headObject requests url without credentials.
+func headObject(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		//log.Printf("unable to get s3://%s/%s: %v", bucketName, key, resp.StatusCode)
 		return errors.New(strconv.Itoa(resp.StatusCode))
 	}
 
-	return err
+	return nil
+}
 
-	/*
-		 	return client.HeadObject(context.TODO(), &s3.HeadObjectInput{
-				Bucket: &bucketName,
-				Key:    &key,
-			})
-	*/
+func deleteObject(ctx context.Context, client awsapi.S3, bucketName string, key string) (*s3.DeleteObjectOutput, error) {
+	return client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: &bucketName, Key: &key})
 }
 
-func deleteObject(client *s3.Client, bucketName string, key string) (*s3.DeleteObjectOutput, error) {
-	return client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{Bucket: &bucketName, Key: &key})
+// probeCleanupTimeout bounds deleting a probe object after the scan has
+// been cancelled.
+const probeCleanupTimeout = 10 * time.Second
+
+// stringList is a flag that may be repeated, e.g. -bucket a -bucket b.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
 }
 
 func check(err error, msg string) {
 	if err != nil {
-		log.Fatalf("%s: %v", msg, err)
+		slog.Error(msg, "error", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/guardian/s3-audit/awsapi/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestListBuckets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := mocks.NewMockS3(ctrl)
+
+	pages := map[string]*s3.ListBucketsOutput{
+		"": {
+			Buckets:           []s3types.Bucket{{Name: aws.String("a")}, {Name: aws.String("b")}},
+			ContinuationToken: aws.String("page-2"),
+		},
+		"page-2": {
+			Buckets:           []s3types.Bucket{{Name: aws.String("c")}},
+			ContinuationToken: aws.String("page-3"),
+		},
+		"page-3": {
+			Buckets: []s3types.Bucket{{Name: aws.String("d")}, {Name: aws.String("e")}},
+		},
+	}
+	client.EXPECT().ListBuckets(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, in *s3.ListBucketsInput, _ ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+			if aws.ToInt32(in.MaxBuckets) != 1000 {
+				t.Errorf("MaxBuckets = %d, want 1000", aws.ToInt32(in.MaxBuckets))
+			}
+			return pages[aws.ToString(in.ContinuationToken)], nil
+		}).
+		Times(3)
+
+	buckets, err := listBuckets(context.Background(), client)
+	if err != nil {
+		t.Fatalf("listBuckets: %v", err)
+	}
+
+	names := []string{}
+	for _, b := range buckets {
+		names = append(names, aws.ToString(b.Name))
+	}
+	if want := []string{"a", "b", "c", "d", "e"}; !slices.Equal(names, want) {
+		t.Errorf("listBuckets() = %v, want %v", names, want)
+	}
+}
+
+func TestListBucketsError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := mocks.NewMockS3(ctrl)
+
+	gomock.InOrder(
+		client.EXPECT().ListBuckets(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&s3.ListBucketsOutput{Buckets: []s3types.Bucket{{Name: aws.String("a")}}, ContinuationToken: aws.String("page-2")}, nil),
+		client.EXPECT().ListBuckets(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("AccessDenied")),
+	)
+
+	if _, err := listBuckets(context.Background(), client); err == nil {
+		t.Error("listBuckets() succeeded after a page failed")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// bucketMetadata is context attached to every finding on a bucket, so the
+// report can be triaged without the console. Objects and SizeBytes are
+// CloudWatch's daily storage metrics, zero if none have been published.
+type bucketMetadata struct {
+	Created   *time.Time        `json:"created,omitempty"`
+	Region    string            `json:"region"`
+	Objects   int64             `json:"objects"`
+	SizeBytes int64             `json:"sizeBytes"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+func (m bucketMetadata) String() string {
+	created := "unknown"
+	if m.Created != nil {
+		created = formatTime(*m.Created)
+	}
+
+	s := fmt.Sprintf("created %s in %s, %d objects, %s", created, m.Region, m.Objects, formatBytes(m.SizeBytes))
+	if len(m.Tags) > 0 {
+		tags := []string{}
+		for _, k := range slices.Sorted(maps.Keys(m.Tags)) {
+			tags = append(tags, k+"="+m.Tags[k])
+		}
+		s += ", tags " + strings.Join(tags, " ")
+	}
+
+	return s
+}
+
+// bucketSize is a bucket's object count and size from CloudWatch.
+type bucketSize struct {
+	Objects       int64 `json:"objects"`
+	Bytes         int64 `json:"bytes"`
+	StandardBytes int64 `json:"standardBytes"` // in the S3 Standard storage class
+}
+
+// storageMetricsLookback covers CloudWatch's daily S3 storage metrics,
+// which are published once a day and only listed for two weeks.
+const storageMetricsLookback = 3 * 24 * time.Hour
+
+// getBucketSizes returns the latest storage metrics for the buckets in each
+// region. BucketSizeBytes is published per storage class, so those are
+// summed.
+func getBucketSizes(ctx context.Context, config aws.Config, regions []string) (map[string]bucketSize, error) {
+	errs := []error{}
+	sizes := map[string]bucketSize{}
+
+	for _, region := range regions {
+		client := cloudwatch.NewFromConfig(config, func(o *cloudwatch.Options) { o.Region = region })
+
+		queries := []cwtypes.MetricDataQuery{}
+		buckets := map[string]string{} // query ID to bucket
+		for _, name := range []string{"BucketSizeBytes", "NumberOfObjects"} {
+			paginator := cloudwatch.NewListMetricsPaginator(client, &cloudwatch.ListMetricsInput{
+				Namespace:  aws.String("AWS/S3"),
+				MetricName: aws.String(name),
+			})
+			for paginator.HasMorePages() {
+				page, err := paginator.NextPage(ctx)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("unable to list S3 storage metrics in %s: %w", region, err))
+					break
+				}
+
+				for _, metric := range page.Metrics {
+					bucket, ok := dimension(metric.Dimensions, "BucketName")
+					if !ok {
+						continue
+					}
+
+					// Sizes are labelled with their storage class, e.g.
+					// StandardStorage.
+					label := name
+					if storageType, ok := dimension(metric.Dimensions, "StorageType"); ok && name == "BucketSizeBytes" {
+						label = storageType
+					}
+
+					id := fmt.Sprintf("m%d", len(queries))
+					buckets[id] = bucket
+					queries = append(queries, cwtypes.MetricDataQuery{
+						Id:         aws.String(id),
+						Label:      aws.String(label),
+						MetricStat: &cwtypes.MetricStat{Metric: &metric, Period: aws.Int32(86400), Stat: aws.String("Average")},
+					})
+				}
+			}
+		}
+
+		end := time.Now()
+		start := end.Add(-storageMetricsLookback)
+
+		for i := 0; i < len(queries); i += 500 {
+			out, err := client.GetMetricData(ctx, &cloudwatch.GetMetricDataInput{
+				MetricDataQueries: queries[i:min(i+500, len(queries))],
+				StartTime:         &start,
+				EndTime:           &end,
+				ScanBy:            cwtypes.ScanByTimestampDescending,
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to get S3 storage metrics in %s: %w", region, err))
+				break
+			}
+
+			for _, result := range out.MetricDataResults {
+				if len(result.Values) == 0 {
+					continue
+				}
+
+				bucket := buckets[aws.ToString(result.Id)]
+				size := sizes[bucket]
+				switch aws.ToString(result.Label) {
+				case "NumberOfObjects":
+					size.Objects += int64(result.Values[0])
+				case "StandardStorage":
+					size.StandardBytes += int64(result.Values[0])
+					size.Bytes += int64(result.Values[0])
+				default:
+					size.Bytes += int64(result.Values[0])
+				}
+				sizes[bucket] = size
+			}
+		}
+	}
+
+	return sizes, errors.Join(errs...)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/guardian/s3-audit/awsapi"
+	"github.com/guardian/s3-audit/policy"
+)
+
+// notificationTarget is an SNS topic, SQS queue or Lambda function the
+// bucket sends event notifications to.
+type notificationTarget struct {
+	Type    string `json:"type"` // SNS, SQS or Lambda
+	ARN     string `json:"arn"`
+	Account string `json:"account"`
+	Exists  *bool  `json:"exists"` // nil if it couldn't be checked, usually for lack of access
+}
+
+// notificationTargets checks whether targets exist for an account, caching
+// them as buckets often notify the same topic or queue.
+type notificationTargets struct {
+	config aws.Config
+	exists map[string]*bool
+}
+
+func newNotificationTargets(config aws.Config) *notificationTargets {
+	return &notificationTargets{config: config, exists: map[string]*bool{}}
+}
+
+// get returns the bucket's notification targets, or nil if it has none.
+func (n *notificationTargets) get(ctx context.Context, client awsapi.S3, bucketName string, region string) ([]notificationTarget, error) {
+	out, err := client.GetBucketNotificationConfiguration(ctx, &s3.GetBucketNotificationConfigurationInput{Bucket: &bucketName}, inRegion(region))
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []notificationTarget
+	add := func(kind string, arn *string) error {
+		target := notificationTarget{Type: kind, ARN: aws.ToString(arn), Account: policy.AccountID(aws.ToString(arn))}
+
+		var err error
+		if target.Exists, err = n.check(ctx, target); err != nil {
+			return fmt.Errorf("unable to check %s target %s: %w", kind, target.ARN, err)
+		}
+		targets = append(targets, target)
+
+		return nil
+	}
+
+	for _, conf := range out.TopicConfigurations {
+		if err := add("SNS", conf.TopicArn); err != nil {
+			return nil, err
+		}
+	}
+	for _, conf := range out.QueueConfigurations {
+		if err := add("SQS", conf.QueueArn); err != nil {
+			return nil, err
+		}
+	}
+	for _, conf := range out.LambdaFunctionConfigurations {
+		if err := add("Lambda", conf.LambdaFunctionArn); err != nil {
+			return nil, err
+		}
+	}
+
+	return targets, nil
+}
+
+// check looks the target up in its own region. Being denied access, as is
+// usual for other accounts' targets, leaves its existence unknown.
+func (n *notificationTargets) check(ctx context.Context, target notificationTarget) (*bool, error) {
+	if exists, ok := n.exists[target.ARN]; ok {
+		return exists, nil
+	}
+
+	parts := strings.SplitN(target.ARN, ":", 7)
+	if len(parts) < 6 {
+		return nil, errors.New("invalid ARN")
+	}
+	region, name := parts[3], parts[5]
+
+	var err error
+	switch target.Type {
+	case "SNS":
+		client := sns.NewFromConfig(n.config, func(o *sns.Options) { o.Region = region })
+		_, err = client.GetTopicAttributes(ctx, &sns.GetTopicAttributesInput{TopicArn: &target.ARN})
+	case "SQS":
+		client := sqs.NewFromConfig(n.config, func(o *sqs.Options) { o.Region = region })
+		_, err = client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: &name, QueueOwnerAWSAccountId: &target.Account})
+	case "Lambda":
+		client := lambda.NewFromConfig(n.config, func(o *lambda.Options) { o.Region = region })
+		_, err = client.GetFunction(ctx, &lambda.GetFunctionInput{FunctionName: &target.ARN})
+	}
+
+	var (
+		exists        *bool
+		topicNotFound *snstypes.NotFoundException
+		queueNotFound *sqstypes.QueueDoesNotExist
+		funcNotFound  *lambdatypes.ResourceNotFoundException
+	)
+	switch {
+	case err == nil:
+		exists = aws.Bool(true)
+	case errors.As(err, &topicNotFound), errors.As(err, &queueNotFound), errors.As(err, &funcNotFound):
+		exists = aws.Bool(false)
+	case isAccessDenied(err):
+	default:
+		return nil, err
+	}
+
+	n.exists[target.ARN] = exists
+	return exists, nil
+}
+
+// isAccessDenied reports whether err is any of the services' access denied
+// errors.
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.ErrorCode() {
+	case "AccessDenied", "AccessDeniedException", "AuthorizationError":
+		return true
+	}
+
+	return false
+}
+
+// notificationTargetsCheck reports event notifications sent to other
+// accounts, which is data leaving the account, and to targets that no longer
+// exist, whose events are being dropped. A deleted target's name can also be
+// taken by someone else.
+type notificationTargetsCheck struct {
+	names accountNames
+}
+
+func (notificationTargetsCheck) Name() string { return "notification-targets" }
+
+func (c notificationTargetsCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	findings := []Finding{}
+	for _, target := range facts.NotificationTargets {
+		if target.Exists != nil && !*target.Exists {
+			findings = append(findings, Finding{
+				Check:    c.Name(),
+				Subject:  target.ARN,
+				Severity: SeverityMedium,
+				Message:  fmt.Sprintf("event notifications go to %s target %s, which no longer exists", target.Type, target.ARN),
+			})
+			continue
+		}
+
+		if target.Account != "" && target.Account != facts.AccountID {
+			finding := Finding{
+				Check:    c.Name(),
+				Subject:  target.ARN,
+				Severity: SeverityMedium,
+				Message:  fmt.Sprintf("event notifications go to %s target %s in another account", target.Type, c.names.resolve(target.ARN)),
+			}
+			if target.Exists == nil {
+				finding.Details = append(finding.Details, "unable to check the target exists")
+			}
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings
+}
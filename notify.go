@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// notifyState is what a notifying output has sent, kept between scans so a
+// flapping bucket (public, fixed, public again) doesn't page five times in
+// an hour.
+type notifyState struct {
+	Sent     []time.Time          `json:"sent"`     // notifications in the last hour
+	Notified map[string]time.Time `json:"notified"` // by finding key, when last notified
+	Folded   int                  `json:"folded"`   // held back since the last notification
+}
+
+func notifyStatePath(sink string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	sum := sha256.Sum256([]byte(sink))
+	return filepath.Join(dir, "s3-audit-notify", hex.EncodeToString(sum[:8])+".json")
+}
+
+func loadNotifyState(path string) *notifyState {
+	state := &notifyState{Notified: map[string]time.Time{}}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, state)
+	}
+	if state.Notified == nil {
+		state.Notified = map[string]time.Time{}
+	}
+
+	return state
+}
+
+func (s *notifyState) save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// notifySlack posts the summary to Slack, subject to the output's rate limit
+// and dedup window. Findings notified within the window are repeats, left
+// out of the summary; if there is nothing else the post is skipped, and so
+// is it if the rate limit has been reached. Either way it is folded into the
+// next post, whose findings include any held back by the rate limit.
+func (o Output) notifySlack(ctx context.Context, r savedReport, findings findingStream) error {
+	isCritical := func(f Finding) bool { return f.Severity >= SeverityCritical }
+	if o.RateLimit == 0 && o.DedupWindow == "" {
+		counts, err := findings.severityCounts()
+		if err != nil {
+			return err
+		}
+		critical, err := findings.filter(isCritical).collect()
+		if err != nil {
+			return err
+		}
+		return postSlack(ctx, o.WebhookURL, slackSummary(r, counts, critical, 0, 0))
+	}
+
+	now := time.Now()
+	path := notifyStatePath(o.WebhookURL)
+	state := loadNotifyState(path)
+	window, _ := time.ParseDuration(o.DedupWindow)
+
+	state.Sent = slices.DeleteFunc(state.Sent, func(t time.Time) bool { return now.Sub(t) >= time.Hour })
+	for key, at := range state.Notified {
+		if now.Sub(at) >= window {
+			delete(state.Notified, key)
+		}
+	}
+
+	notified := func(f Finding) bool {
+		_, ok := state.Notified[findingKey(f)]
+		return ok
+	}
+	repeats, err := findings.filter(notified).severityCounts()
+	if err != nil {
+		return err
+	}
+	fresh := findings.filter(func(f Finding) bool { return !notified(f) })
+	counts, err := fresh.severityCounts()
+	if err != nil {
+		return err
+	}
+	critical, err := fresh.filter(isCritical).collect()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case totalCount(counts) == 0 && totalCount(repeats) == 0:
+		component("notify").Info("not notifying, there are no findings")
+		return nil
+	case totalCount(counts) == 0:
+		component("notify").Info("not notifying, findings were notified within the dedup window", "findings", totalCount(repeats))
+		state.Folded++
+	case o.RateLimit > 0 && len(state.Sent) >= o.RateLimit:
+		component("notify").Info("not notifying, rate limit reached", "limit", o.RateLimit)
+		state.Folded++
+	default:
+		if err := postSlack(ctx, o.WebhookURL, slackSummary(r, counts, critical, totalCount(repeats), state.Folded)); err != nil {
+			return err
+		}
+		state.Sent = append(state.Sent, now)
+		state.Folded = 0
+		err := fresh(func(f Finding) error {
+			state.Notified[findingKey(f)] = now
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return state.save(path)
+}
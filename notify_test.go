@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slackRecorder is a webhook recording what is posted to it.
+func slackRecorder(t *testing.T) (string, *[]string) {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	posts := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		posts = append(posts, body["text"])
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL, &posts
+}
+
+func TestNotifySlackDedupWindow(t *testing.T) {
+	url, posts := slackRecorder(t)
+	o := Output{Type: "slack", WebhookURL: url, DedupWindow: "1h"}
+	r := savedReport{Time: time.Now(), Profiles: []string{"a"}}
+	public := Finding{Account: "a", Bucket: "flapping", Check: "public-access", Severity: SeverityHigh, Message: "public"}
+	other := Finding{Account: "a", Bucket: "other", Check: "public-access", Severity: SeverityCritical, Message: "public too"}
+
+	for _, findings := range [][]Finding{{public}, {public}, {}, {public, other}} {
+		if err := o.notifySlack(t.Context(), r, sliceStream(findings)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(*posts) != 2 {
+		t.Fatalf("posted %d times, want 2: %q", len(*posts), *posts)
+	}
+	if !strings.Contains((*posts)[0], "1 findings") || !strings.Contains((*posts)[0], "HIGH: 1") {
+		t.Errorf("first post = %q, want the HIGH finding counted", (*posts)[0])
+	}
+	second := (*posts)[1]
+	for _, want := range []string{"1 findings", "HIGH: 0", "a/other public-access", "1 more were notified within the dedup window", "includes 1 earlier notifications"} {
+		if !strings.Contains(second, want) {
+			t.Errorf("second post = %q, want it to contain %q", second, want)
+		}
+	}
+}
+
+func TestNotifySlackRateLimit(t *testing.T) {
+	url, posts := slackRecorder(t)
+	o := Output{Type: "slack", WebhookURL: url, RateLimit: 1}
+	r := savedReport{Time: time.Now(), Profiles: []string{"a"}}
+	first := Finding{Account: "a", Bucket: "one", Check: "public-access", Severity: SeverityCritical, Message: "public"}
+	second := Finding{Account: "a", Bucket: "two", Check: "public-access", Severity: SeverityCritical, Message: "public"}
+
+	for _, f := range []Finding{first, second} {
+		if err := o.notifySlack(t.Context(), r, sliceStream([]Finding{f})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*posts) != 1 {
+		t.Fatalf("posted %d times within the rate limit, want 1", len(*posts))
+	}
+
+	// An hour on, the held back finding is sent.
+	path := notifyStatePath(url)
+	state := loadNotifyState(path)
+	for i := range state.Sent {
+		state.Sent[i] = state.Sent[i].Add(-time.Hour)
+	}
+	if err := state.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.notifySlack(t.Context(), r, sliceStream([]Finding{second})); err != nil {
+		t.Fatal(err)
+	}
+	if len(*posts) != 2 {
+		t.Fatalf("posted %d times after the rate limit reset, want 2", len(*posts))
+	}
+	if !strings.Contains((*posts)[1], "a/two") || !strings.Contains((*posts)[1], "includes 1 earlier notifications") {
+		t.Errorf("post = %q, want the held back finding folded in", (*posts)[1])
+	}
+}
+
+func TestNotifyState(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	if notifyStatePath("https://hooks.slack.com/a") == notifyStatePath("https://hooks.slack.com/b") {
+		t.Error("two sinks share a state file")
+	}
+
+	tests := []struct {
+		name     string
+		contents string // written to the state file first, if set
+		notified int
+		folded   int
+	}{
+		{"missing", "", 0, 0},
+		{"corrupt", "{", 0, 0},
+		{"no findings", `{"sent": [], "folded": 2}`, 0, 2},
+		{"saved", `{"sent": ["2026-10-01T00:00:00Z"], "notified": {"key": "2026-10-01T00:00:00Z"}, "folded": 1}`, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := notifyStatePath(tt.name)
+			if tt.contents != "" {
+				if err := (&notifyState{}).save(path); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(tt.contents), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			state := loadNotifyState(path)
+			if state.Notified == nil || len(state.Notified) != tt.notified || state.Folded != tt.folded {
+				t.Fatalf("loadNotifyState() = %+v, want %d notified and %d folded", state, tt.notified, tt.folded)
+			}
+
+			state.Notified["other"] = at
+			if err := state.save(path); err != nil {
+				t.Fatal(err)
+			}
+			if saved := loadNotifyState(path); !saved.Notified["other"].Equal(at) {
+				t.Errorf("saved state = %+v, want other notified at %s", saved, at)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	"github.com/guardian/s3-audit/awsapi"
+	"github.com/guardian/s3-audit/policy"
+)
+
+// objectLambdaAccessPoint is an Object Lambda access point and the ordinary
+// access point it reads through. Policies are nil where none is set.
+type objectLambdaAccessPoint struct {
+	Name                    string
+	Region                  string
+	Policy                  *policy.Policy
+	PolicyPublic            bool // S3's own policy status
+	SupportingAccessPoint   string
+	SupportingAccount       string
+	SupportingPolicy        *policy.Policy
+	SupportingPolicyPublic  bool
+	SupportingNetworkOrigin string
+	Bucket                  string // the bucket behind the supporting access point, if in this account
+}
+
+// getObjectLambdaAccessPoints lists the account's Object Lambda access points
+// in the given regions, with their policies and supporting access points.
+func getObjectLambdaAccessPoints(ctx context.Context, client awsapi.S3Control, accountID string, regions []string) ([]objectLambdaAccessPoint, error) {
+	errs := []error{}
+	accessPoints := []objectLambdaAccessPoint{}
+
+	for _, region := range regions {
+		inRegion := func(o *s3control.Options) { o.Region = region }
+
+		paginator := s3control.NewListAccessPointsForObjectLambdaPaginator(client, &s3control.ListAccessPointsForObjectLambdaInput{AccountId: &accountID})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx, inRegion)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to list Object Lambda access points in %s: %w", region, err))
+				break
+			}
+
+			for _, olap := range page.ObjectLambdaAccessPointList {
+				ap := objectLambdaAccessPoint{Name: aws.ToString(olap.Name), Region: region}
+				if err := describeObjectLambdaAccessPoint(ctx, client, accountID, &ap, inRegion); err != nil {
+					errs = append(errs, fmt.Errorf("unable to describe Object Lambda access point %s: %w", ap.Name, err))
+				}
+				accessPoints = append(accessPoints, ap)
+			}
+		}
+	}
+
+	return accessPoints, errors.Join(errs...)
+}
+
+func describeObjectLambdaAccessPoint(ctx context.Context, client awsapi.S3Control, accountID string, ap *objectLambdaAccessPoint, inRegion func(*s3control.Options)) error {
+	conf, err := client.GetAccessPointConfigurationForObjectLambda(ctx, &s3control.GetAccessPointConfigurationForObjectLambdaInput{AccountId: &accountID, Name: &ap.Name}, inRegion)
+	if err != nil {
+		return fmt.Errorf("unable to get configuration: %w", err)
+	}
+	ap.SupportingAccessPoint = aws.ToString(conf.Configuration.SupportingAccessPoint)
+
+	doc, err := client.GetAccessPointPolicyForObjectLambda(ctx, &s3control.GetAccessPointPolicyForObjectLambdaInput{AccountId: &accountID, Name: &ap.Name}, inRegion)
+	if err != nil && !isErrorCode(err, "NoSuchAccessPointPolicy") {
+		return fmt.Errorf("unable to get policy: %w", err)
+	}
+	if err == nil {
+		if ap.Policy, err = policy.Parse(aws.ToString(doc.Policy)); err != nil {
+			return err
+		}
+
+		status, err := client.GetAccessPointPolicyStatusForObjectLambda(ctx, &s3control.GetAccessPointPolicyStatusForObjectLambdaInput{AccountId: &accountID, Name: &ap.Name}, inRegion)
+		if err != nil {
+			return fmt.Errorf("unable to get policy status: %w", err)
+		}
+		ap.PolicyPublic = status.PolicyStatus != nil && status.PolicyStatus.IsPublic
+	}
+
+	// The supporting access point is given as an ARN, and may belong to
+	// another account, in which case we can't look inside it.
+	parts := strings.SplitN(ap.SupportingAccessPoint, ":", 6)
+	if len(parts) != 6 {
+		return fmt.Errorf("unexpected supporting access point %q", ap.SupportingAccessPoint)
+	}
+	ap.SupportingAccount = parts[4]
+	if ap.SupportingAccount != accountID {
+		return nil
+	}
+
+	name := strings.TrimPrefix(parts[5], "accesspoint/")
+	supportingRegion := func(o *s3control.Options) { o.Region = parts[3] }
+
+	supporting, err := client.GetAccessPoint(ctx, &s3control.GetAccessPointInput{AccountId: &accountID, Name: &name}, supportingRegion)
+	if err != nil {
+		return fmt.Errorf("unable to get supporting access point: %w", err)
+	}
+	ap.Bucket = aws.ToString(supporting.Bucket)
+	ap.SupportingNetworkOrigin = string(supporting.NetworkOrigin)
+
+	supportingDoc, err := client.GetAccessPointPolicy(ctx, &s3control.GetAccessPointPolicyInput{AccountId: &accountID, Name: &name}, supportingRegion)
+	if isErrorCode(err, "NoSuchAccessPointPolicy") {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get supporting access point policy: %w", err)
+	}
+	if ap.SupportingPolicy, err = policy.Parse(aws.ToString(supportingDoc.Policy)); err != nil {
+		return err
+	}
+
+	status, err := client.GetAccessPointPolicyStatus(ctx, &s3control.GetAccessPointPolicyStatusInput{AccountId: &accountID, Name: &name}, supportingRegion)
+	if err != nil {
+		return fmt.Errorf("unable to get supporting access point policy status: %w", err)
+	}
+	ap.SupportingPolicyPublic = status.PolicyStatus != nil && status.PolicyStatus.IsPublic
+
+	return nil
+}
+
+// objectLambdaFindings reports public and cross-account exposure through
+// the access point or its supporting access point.
+func (s *scanner) objectLambdaFindings(as *accountScan, ap objectLambdaAccessPoint) []Finding {
+	findings := []Finding{}
+	newFinding := func(subject string, severity Severity, format string, args ...any) Finding {
+		return Finding{
+			Account:   as.Profile,
+			AccountID: as.ID,
+			Bucket:    ap.Bucket,
+			Check:     "object-lambda-access-point",
+			Subject:   ap.Name + " " + subject,
+			Severity:  severity,
+			Message:   fmt.Sprintf("Object Lambda access point %s (%s) ", ap.Name, ap.Region) + fmt.Sprintf(format, args...),
+		}
+	}
+
+	if ap.PolicyPublic || (ap.Policy != nil && ap.Policy.IsPublic()) {
+		findings = append(findings, newFinding("public", SeverityHigh, "has a public policy"))
+	}
+	if ap.Policy != nil {
+		if external := ap.Policy.ExternalAccounts(as.ID); len(external) > 0 {
+			finding := newFinding("cross-account", SeverityMedium, "grants access to other accounts")
+			finding.Grantees = s.accountNames.resolveAll(external)
+			findings = append(findings, finding)
+		}
+	}
+
+	if ap.SupportingAccount != "" && ap.SupportingAccount != as.ID {
+		finding := newFinding("supporting-cross-account", SeverityMedium, "reads through access point %s in another account", ap.SupportingAccessPoint)
+		finding.Grantees = s.accountNames.resolveAll([]string{ap.SupportingAccount})
+		findings = append(findings, finding)
+	}
+
+	if ap.SupportingPolicyPublic || (ap.SupportingPolicy != nil && ap.SupportingPolicy.IsPublic()) {
+		finding := newFinding("supporting-public", SeverityHigh, "reads through access point %s, which has a public policy", ap.SupportingAccessPoint)
+		finding.Details = []string{"network origin is " + ap.SupportingNetworkOrigin}
+		findings = append(findings, finding)
+	}
+	if ap.SupportingPolicy != nil {
+		if external := ap.SupportingPolicy.ExternalAccounts(as.ID); len(external) > 0 {
+			finding := newFinding("supporting-policy-cross-account", SeverityMedium, "reads through access point %s, which grants access to other accounts", ap.SupportingAccessPoint)
+			finding.Grantees = s.accountNames.resolveAll(external)
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/guardian/s3-audit/awsapi"
+)
+
+// objectLock is the bucket's Object Lock configuration. Mode and Days are
+// the default retention, empty and 0 if there is none.
+type objectLock struct {
+	Enabled bool   `json:"enabled"`
+	Mode    string `json:"mode"` // GOVERNANCE or COMPLIANCE
+	Days    int    `json:"days"`
+}
+
+// getObjectLock returns the Object Lock configuration, or nil if Object
+// Lock isn't enabled.
+func getObjectLock(ctx context.Context, client awsapi.S3, bucketName string, region string) (*objectLock, error) {
+	out, err := client.GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{Bucket: &bucketName}, inRegion(region))
+	if isErrorCode(err, "ObjectLockConfigurationNotFoundError") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conf := out.ObjectLockConfiguration
+	if conf == nil || conf.ObjectLockEnabled != s3types.ObjectLockEnabledEnabled {
+		return nil, nil
+	}
+
+	lock := &objectLock{Enabled: true}
+	if conf.Rule != nil && conf.Rule.DefaultRetention != nil {
+		retention := conf.Rule.DefaultRetention
+		lock.Mode = string(retention.Mode)
+		lock.Days = int(aws.ToInt32(retention.Days)) + 365*int(aws.ToInt32(retention.Years))
+	}
+
+	return lock, nil
+}
+
+// objectLockCheck reports buckets tagged as holding compliance or archive
+// data without the Object Lock retention policy requires. Governance mode
+// retention can be lifted by anyone with s3:BypassGovernanceRetention, so
+// isn't enough where compliance mode is required.
+type objectLockCheck struct {
+	params objectLockParams
+}
+
+func (objectLockCheck) Name() string { return "object-lock" }
+
+func (c objectLockCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	if !hasTag(facts.Tags, c.params.Tags) {
+		return nil
+	}
+
+	finding := Finding{Check: c.Name(), Severity: SeverityMedium}
+	lock := facts.ObjectLock
+	switch {
+	case lock == nil:
+		finding.Severity = SeverityHigh
+		finding.Message = "bucket is tagged for compliance or archive data but doesn't have Object Lock enabled"
+	case lock.Mode == "":
+		finding.Message = "Object Lock is enabled but there is no default retention, so objects aren't locked unless uploaded with retention"
+	case lock.Mode != c.params.Mode && c.params.Mode == string(s3types.ObjectLockRetentionModeCompliance):
+		finding.Message = fmt.Sprintf("default retention is %s mode, which can be bypassed, but policy requires %s mode", lock.Mode, c.params.Mode)
+	case lock.Days < c.params.MinDays:
+		finding.Message = fmt.Sprintf("default retention is %d days, less than the %d days required", lock.Days, c.params.MinDays)
+	default:
+		return nil
+	}
+
+	return []Finding{finding}
+}
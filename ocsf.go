@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ocsfFinding is an OCSF Detection Finding as Prowler writes them with
+// --output-formats json-ocsf, with only the fields we read or write. Files
+// are a JSON array of them.
+type ocsfFinding struct {
+	Message      string          `json:"message"`
+	Metadata     ocsfMetadata    `json:"metadata"`
+	Severity     string          `json:"severity"`
+	SeverityID   int             `json:"severity_id"`
+	Status       string          `json:"status"`
+	StatusCode   string          `json:"status_code"`
+	StatusDetail string          `json:"status_detail"`
+	FindingInfo  ocsfFindingInfo `json:"finding_info"`
+	Resources    []ocsfResource  `json:"resources"`
+	Cloud        ocsfCloud       `json:"cloud"`
+	Time         int64           `json:"time"`
+	TimeDT       string          `json:"time_dt"`
+	ActivityID   int             `json:"activity_id"`
+	ActivityName string          `json:"activity_name"`
+	CategoryUID  int             `json:"category_uid"`
+	CategoryName string          `json:"category_name"`
+	ClassUID     int             `json:"class_uid"`
+	ClassName    string          `json:"class_name"`
+	TypeUID      int             `json:"type_uid"`
+	TypeName     string          `json:"type_name"`
+	Unmapped     *ocsfUnmapped   `json:"unmapped,omitempty"`
+}
+
+type ocsfMetadata struct {
+	EventCode string      `json:"event_code"` // the check ID
+	Product   ocsfProduct `json:"product"`
+	Version   string      `json:"version"` // of the OCSF schema
+}
+
+type ocsfProduct struct {
+	Name       string `json:"name"`
+	VendorName string `json:"vendor_name"`
+}
+
+type ocsfFindingInfo struct {
+	UID           string `json:"uid"`
+	Title         string `json:"title"`
+	Desc          string `json:"desc"`
+	CreatedTime   int64  `json:"created_time"`
+	CreatedTimeDT string `json:"created_time_dt"`
+}
+
+type ocsfResource struct {
+	UID    string    `json:"uid"`
+	Name   string    `json:"name"`
+	Type   string    `json:"type"`
+	Region string    `json:"region"`
+	Group  ocsfGroup `json:"group"`
+}
+
+type ocsfGroup struct {
+	Name string `json:"name"`
+}
+
+type ocsfCloud struct {
+	Account  ocsfAccount `json:"account"`
+	Region   string      `json:"region"`
+	Provider string      `json:"provider"`
+}
+
+type ocsfAccount struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ocsfUnmapped holds what OCSF has no field for, so that our findings
+// survive being exported and imported again.
+type ocsfUnmapped struct {
+	Subject string   `json:"subject,omitempty"`
+	Owner   string   `json:"owner,omitempty"`
+	Details []string `json:"details,omitempty"`
+	Score   int      `json:"score,omitempty"`
+	Signals []string `json:"signals,omitempty"`
+}
+
+// ocsfProductName marks the findings we export, which are imported with
+// their check IDs as they are. Other products' checks are prefixed with the
+// product name, e.g. prowler:s3_bucket_public_access.
+const ocsfProductName = "s3-audit"
+
+// OCSF severity IDs. Informational findings are imported as LOW.
+var ocsfSeverities = map[Severity]int{SeverityLow: 2, SeverityMedium: 3, SeverityHigh: 4, SeverityCritical: 5}
+
+// toOCSF converts a finding to an OCSF Detection Finding. Like toASFF, its
+// UID is stable across scans.
+func toOCSF(f Finding, at time.Time) ocsfFinding {
+	name := strings.ToUpper(f.Severity.String()[:1]) + strings.ToLower(f.Severity.String()[1:])
+	region := ""
+	if f.Metadata != nil {
+		region = f.Metadata.Region
+	}
+	description := ""
+	if i := slices.IndexFunc(registry, func(c checkInfo) bool { return c.ID == f.Check }); i >= 0 {
+		description = registry[i].Description
+	}
+
+	resource := ocsfResource{UID: "arn:aws:iam::" + f.AccountID + ":root", Name: f.AccountID, Type: "AwsAccount", Region: region, Group: ocsfGroup{Name: "account"}}
+	if f.Bucket != "" {
+		resource = ocsfResource{UID: "arn:aws:s3:::" + f.Bucket, Name: f.Bucket, Type: "AwsS3Bucket", Region: region, Group: ocsfGroup{Name: "s3"}}
+	}
+
+	finding := ocsfFinding{
+		Message:      f.Message,
+		Metadata:     ocsfMetadata{EventCode: f.Check, Product: ocsfProduct{Name: ocsfProductName, VendorName: "Guardian"}, Version: "1.4.0"},
+		Severity:     name,
+		SeverityID:   ocsfSeverities[f.Severity],
+		Status:       "New",
+		StatusCode:   "FAIL",
+		StatusDetail: f.Message,
+		FindingInfo: ocsfFindingInfo{
+			UID:           "s3-audit-" + findingID(f),
+			Title:         f.Check + ": " + f.Bucket,
+			Desc:          description,
+			CreatedTime:   at.Unix(),
+			CreatedTimeDT: at.UTC().Format(time.RFC3339),
+		},
+		Resources:    []ocsfResource{resource},
+		Cloud:        ocsfCloud{Account: ocsfAccount{UID: f.AccountID, Name: f.Account, Type: "AWS Account"}, Region: region, Provider: "aws"},
+		Time:         at.Unix(),
+		TimeDT:       at.UTC().Format(time.RFC3339),
+		ActivityID:   1,
+		ActivityName: "Create",
+		CategoryUID:  2,
+		CategoryName: "Findings",
+		ClassUID:     2004,
+		ClassName:    "Detection Finding",
+		TypeUID:      200401,
+		TypeName:     "Detection Finding: Create",
+	}
+	if f.Owner != "" || len(f.Details) > 0 || f.Score > 0 {
+		finding.Unmapped = &ocsfUnmapped{Subject: f.Subject, Owner: f.Owner, Details: f.Details, Score: f.Score, Signals: f.Signals}
+	}
+
+	return finding
+}
+
+// writeOCSFOutput writes the findings as a JSON array of OCSF findings, one
+// to a line.
+func writeOCSFOutput(path string, r savedReport, findings findingStream) error {
+	if path == "-" {
+		return writeOCSF(os.Stdout, r, findings)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := writeOCSF(file, r, findings); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func writeOCSF(out io.Writer, r savedReport, findings findingStream) error {
+	w := bufio.NewWriter(out)
+	w.WriteByte('[')
+	first := true
+	err := findings(func(f Finding) error {
+		data, err := json.Marshal(toOCSF(f, r.Time))
+		if err != nil {
+			return err
+		}
+		if !first {
+			w.WriteByte(',')
+		}
+		first = false
+		w.WriteByte('\n')
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.WriteString("\n]\n")
+
+	return w.Flush()
+}
+
+// fromOCSF converts an OCSF finding on an S3 bucket to one of ours. Passed
+// and muted checks, and findings on anything but buckets, aren't findings
+// to us and are skipped.
+func fromOCSF(o ocsfFinding) (Finding, bool) {
+	if o.StatusCode != "FAIL" || o.Status == "Suppressed" || len(o.Resources) == 0 || o.Resources[0].Type != "AwsS3Bucket" {
+		return Finding{}, false
+	}
+
+	f := Finding{
+		Account:   o.Cloud.Account.Name,
+		AccountID: o.Cloud.Account.UID,
+		Bucket:    strings.TrimPrefix(o.Resources[0].UID, "arn:aws:s3:::"),
+		Check:     o.Metadata.EventCode,
+		Message:   o.Message,
+		Metadata:  &bucketMetadata{Region: o.Resources[0].Region},
+	}
+	if f.Account == "" {
+		f.Account = f.AccountID
+	}
+	switch {
+	case o.Metadata.Product.Name != ocsfProductName:
+		f.Check = strings.ToLower(o.Metadata.Product.Name) + ":" + f.Check
+		if o.FindingInfo.Title != "" {
+			f.Details = append(f.Details, o.FindingInfo.Title)
+		}
+	case o.Unmapped != nil:
+		f.Subject, f.Owner, f.Details, f.Score, f.Signals = o.Unmapped.Subject, o.Unmapped.Owner, o.Unmapped.Details, o.Unmapped.Score, o.Unmapped.Signals
+	}
+
+	f.Severity = SeverityLow
+	for sev, id := range ocsfSeverities {
+		if o.SeverityID == id {
+			f.Severity = sev
+		}
+	}
+
+	return f, true
+}
+
+// importMain converts Prowler's OCSF findings on S3 buckets into a report
+// of ours, optionally merged into one of our saved reports, so they can be
+// diffed, reported on and sent to outputs alongside our own.
+func importMain(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	merge := fs.String("merge", "", "saved report to add the imported findings to")
+	output := fs.String("output", "-", "file to write the report to, or - for stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit import [-merge report.json] [-output report.json] findings.ocsf.json...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	log := component("import")
+
+	r := &savedReport{Time: time.Now(), Profiles: []string{}, Buckets: map[string]int{}, Findings: []Finding{}, Errors: []scanError{}}
+	if *merge != "" {
+		var err error
+		r, err = loadReport(*merge)
+		check(err, "unable to load report")
+	}
+
+	seen := map[string]bool{}
+	for _, f := range r.Findings {
+		seen[findingKey(f)] = true
+	}
+
+	buckets := map[string]map[string]bool{}
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		check(err, "unable to read findings")
+
+		var findings []ocsfFinding
+		check(json.Unmarshal(data, &findings), "invalid OCSF findings in "+path)
+
+		imported := 0
+		for _, o := range findings {
+			f, ok := fromOCSF(o)
+			if !ok || seen[findingKey(f)] {
+				continue
+			}
+			seen[findingKey(f)] = true
+
+			r.Findings = append(r.Findings, f)
+			if !slices.Contains(r.Profiles, f.Account) {
+				r.Profiles = append(r.Profiles, f.Account)
+			}
+			if buckets[f.Account] == nil {
+				buckets[f.Account] = map[string]bool{}
+			}
+			buckets[f.Account][f.Bucket] = true
+			imported++
+		}
+		log.Info("imported findings", "file", path, "findings", imported, "skipped", len(findings)-imported)
+	}
+
+	// Accounts only in the imported findings count the buckets with
+	// findings, as the buckets that passed aren't known.
+	for account, names := range buckets {
+		if _, ok := r.Buckets[account]; !ok {
+			r.Buckets[account] = len(names)
+		}
+	}
+	r.Scores = scoreAccounts(r.Findings, r.Buckets)
+
+	check(writeJSONOutput(*output, *r, sliceStream(r.Findings)), "unable to write report")
+}
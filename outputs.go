@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub"
+	shtypes "github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Output is somewhere the findings are sent as well as the report printed
+// to stdout, configured in the config file, e.g.
+//
+//	{"type": "json", "path": "findings.json"}
+//	{"type": "securityhub", "profile": "security", "region": "eu-west-1"}
+//	{"type": "slack", "webhookURL": "https://hooks.slack.com/services/..."}
+//	{"type": "ocsf", "path": "findings.ocsf.json"}
+//	{"type": "parquet", "path": "s3://bucket/findings", "profile": "archive"}
+//	{"type": "sns", "topicARN": "arn:aws:sns:eu-west-1:123456789012:s3-audit"}
+//
+// or with -output type=target (path, profile, webhook URL, path, path or
+// topic ARN respectively).
+type Output struct {
+	// Type is json to write the report as saved in the history, securityhub
+	// to import the findings into Security Hub as ASFF, slack to post a
+	// summary, ocsf to write the findings as OCSF Detection Findings like
+	// Prowler's json-ocsf output, parquet to archive them for Athena (see
+	// s3-audit glue-table), or sns to publish a summary to a topic.
+	Type string `json:"type"`
+
+	Path       string `json:"path"`       // json, ocsf; parquet, a directory or s3://bucket/prefix
+	Profile    string `json:"profile"`    // securityhub, parquet, sns; the first scanned if empty
+	Region     string `json:"region"`     // securityhub; the profile's if empty
+	WebhookURL string `json:"webhookURL"` // slack
+	TopicARN   string `json:"topicARN"`   // sns
+
+	// RateLimit is the most notifications a slack output posts an hour, and
+	// DedupWindow (e.g. 1h) how long a finding isn't notified again for.
+	// Findings held back are folded into the next notification. See
+	// notifyState. Other outputs send every report, so reject them.
+	RateLimit   int    `json:"rateLimit"`
+	DedupWindow string `json:"dedupWindow"`
+}
+
+// parseOutput parses an -output flag, type=target.
+func parseOutput(s string) (Output, error) {
+	typ, target, _ := strings.Cut(s, "=")
+	o := Output{Type: typ}
+	switch typ {
+	case "json", "ocsf", "parquet":
+		o.Path = target
+	case "securityhub":
+		o.Profile = target
+	case "slack":
+		o.WebhookURL = target
+	case "sns":
+		o.TopicARN = target
+	}
+
+	return o, o.validate()
+}
+
+func (o Output) validate() error {
+	switch {
+	case (o.Type == "json" || o.Type == "ocsf" || o.Type == "parquet") && o.Path == "":
+		return fmt.Errorf("%s output needs a path", o.Type)
+	case o.Type == "slack" && o.WebhookURL == "":
+		return errors.New("slack output needs a webhook URL")
+	case o.Type == "sns" && !strings.HasPrefix(o.TopicARN, "arn:aws:sns:"):
+		return errors.New("sns output needs a topic ARN")
+	case !slices.Contains([]string{"json", "securityhub", "slack", "ocsf", "parquet", "sns"}, o.Type):
+		return fmt.Errorf("unknown output type %q: must be json, securityhub, slack, ocsf, parquet or sns", o.Type)
+	case o.RateLimit < 0:
+		return errors.New("rateLimit must be positive")
+	case o.Type != "slack" && (o.RateLimit != 0 || o.DedupWindow != ""):
+		return fmt.Errorf("%s output doesn't support rateLimit or dedupWindow, only slack does", o.Type)
+	}
+
+	if o.DedupWindow != "" {
+		if _, err := time.ParseDuration(o.DedupWindow); err != nil {
+			return fmt.Errorf("invalid dedupWindow: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// sendOutputs sends the report to each output, with the findings streamed in
+// place of r.Findings. A failure doesn't stop the others being sent.
+func sendOutputs(ctx context.Context, outputs []Output, r savedReport, findings findingStream) error {
+	errs := []error{}
+	for _, o := range outputs {
+		var err error
+		switch o.Type {
+		case "json":
+			err = writeJSONOutput(o.Path, r, findings)
+		case "securityhub":
+			profile := o.Profile
+			if profile == "" {
+				profile = r.Profiles[0]
+			}
+			err = importToSecurityHub(ctx, profile, o.Region, findings, r.Time)
+		case "slack":
+			err = o.notifySlack(ctx, r, findings)
+		case "ocsf":
+			err = writeOCSFOutput(o.Path, r, findings)
+		case "parquet":
+			profile := o.Profile
+			if profile == "" {
+				profile = r.Profiles[0]
+			}
+			err = writeParquetOutput(ctx, o.Path, profile, r, findings)
+		case "sns":
+			profile := o.Profile
+			if profile == "" {
+				profile = r.Profiles[0]
+			}
+			err = publishSNS(ctx, profile, o.TopicARN, r, findings)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s output: %w", o.Type, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// writeJSONOutput writes the report as saved in the history, with the
+// findings streamed in place of r.Findings.
+func writeJSONOutput(path string, r savedReport, findings findingStream) error {
+	if path == "-" {
+		return writeStreamedReport(os.Stdout, r, findings)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := writeStreamedReport(file, r, findings); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// securityHubBatchSize is the most findings BatchImportFindings accepts at
+// once.
+const securityHubBatchSize = 100
+
+// importToSecurityHub imports the findings into Security Hub through its
+// default product for the account. Findings for buckets in other accounts
+// are only accepted if it is the Security Hub administrator account.
+func importToSecurityHub(ctx context.Context, profile string, region string, findings findingStream, at time.Time) error {
+	config, err := loadConfig(ctx, profile)
+	if err != nil {
+		return fmt.Errorf("unable to load AWS config: %w", err)
+	}
+	if region != "" {
+		config.Region = region
+	}
+
+	identity, err := sts.NewFromConfig(config).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return fmt.Errorf("unable to get caller identity: %w", err)
+	}
+	account := aws.ToString(identity.Account)
+
+	client := securityhub.NewFromConfig(config)
+	productARN := fmt.Sprintf("arn:aws:securityhub:%s:%s:product/%s/default", config.Region, account, account)
+	asff := []shtypes.AwsSecurityFinding{}
+	flush := func() error {
+		out, err := client.BatchImportFindings(ctx, &securityhub.BatchImportFindingsInput{Findings: asff})
+		if err != nil {
+			return fmt.Errorf("unable to import findings: %w", err)
+		}
+		if n := aws.ToInt32(out.FailedCount); n > 0 {
+			return fmt.Errorf("%d findings were not imported, the first because %s", n, aws.ToString(out.FailedFindings[0].ErrorMessage))
+		}
+		asff = asff[:0]
+		return nil
+	}
+
+	err = findings(func(f Finding) error {
+		asff = append(asff, toASFF(f, productARN, account, config.Region, at))
+		if len(asff) == securityHubBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil || len(asff) == 0 {
+		return err
+	}
+
+	return flush()
+}
+
+// toASFF converts a finding to the AWS Security Finding Format. Its ID is
+// stable across scans, so re-importing updates it.
+func toASFF(f Finding, productARN string, defaultAccount string, region string, at time.Time) shtypes.AwsSecurityFinding {
+	account := f.AccountID
+	if account == "" {
+		account = defaultAccount
+	}
+	timestamp := at.UTC().Format(time.RFC3339)
+
+	resource := shtypes.Resource{Type: aws.String("AwsAccount"), Id: aws.String("AWS::::Account:" + account)}
+	if f.Bucket != "" {
+		resource = shtypes.Resource{Type: aws.String("AwsS3Bucket"), Id: aws.String("arn:aws:s3:::" + f.Bucket)}
+	}
+	if f.Metadata != nil && f.Metadata.Region != "" {
+		region = f.Metadata.Region
+	}
+	resource.Region = aws.String(region)
+
+	// Account-level findings are titled with what they are about, or failing
+	// that the account.
+	target := cmp.Or(f.Bucket, f.Subject, account)
+
+	return shtypes.AwsSecurityFinding{
+		SchemaVersion: aws.String("2018-10-08"),
+		Id:            aws.String("s3-audit/" + findingID(f)),
+		ProductArn:    aws.String(productARN),
+		GeneratorId:   aws.String("s3-audit/" + f.Check),
+		AwsAccountId:  aws.String(account),
+		Types:         []string{"Software and Configuration Checks/AWS Security Best Practices"},
+		CreatedAt:     aws.String(timestamp),
+		UpdatedAt:     aws.String(timestamp),
+		Severity:      &shtypes.Severity{Label: shtypes.SeverityLabel(f.Severity.String())},
+		Title:         aws.String(f.Check + ": " + target),
+		Description:   aws.String(truncate(f.Message, 1024)),
+		Resources:     []shtypes.Resource{resource},
+	}
+}
+
+// slackSummary is the number of findings per severity and the critical ones,
+// noting how many findings were left out as notified recently and how many
+// notifications were folded into it.
+func slackSummary(r savedReport, counts map[Severity]int, critical []Finding, repeats int, folded int) string {
+	text := &strings.Builder{}
+	fmt.Fprintf(text, "*S3 audit, %s*: %d findings from %d buckets in %d accounts\n", formatTime(r.Time), totalCount(counts), r.bucketCount(), len(r.Profiles))
+	for sev := SeverityCritical; sev >= SeverityLow; sev-- {
+		fmt.Fprintf(text, "%s: %d\n", sev, counts[sev])
+	}
+	for _, f := range critical {
+		fmt.Fprintf(text, "• %s/%s %s: %s\n", f.Account, f.Bucket, f.Check, f.Message)
+	}
+	if repeats > 0 {
+		fmt.Fprintf(text, "(%d more were notified within the dedup window)\n", repeats)
+	}
+	if folded > 0 {
+		fmt.Fprintf(text, "(includes %d earlier notifications held back by the rate limit or dedup window)\n", folded)
+	}
+
+	return text.String()
+}
+
+// postSlack posts the text to a Slack incoming webhook.
+func postSlack(ctx context.Context, webhookURL string, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
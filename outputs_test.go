@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"bucket", 10, "bucket"},
+		{"bucket", 6, "bucket"},
+		{"bucket", 3, "buc"},
+		{"naïve", 3, "naï"},
+		{"日本語のバケット", 4, "日本語の"},
+		{"", 5, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := truncate(tt.s, tt.width); got != tt.want {
+				t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToASFF(t *testing.T) {
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		finding Finding
+		title   string
+	}{
+		{"bucket", Finding{Check: "public-access", AccountID: "111111111111", Bucket: "logs"}, "public-access: logs"},
+		{"subject", Finding{Check: "iam-users", AccountID: "111111111111", Subject: "deploy"}, "iam-users: deploy"},
+		{"account", Finding{Check: "account-bpa", AccountID: "111111111111"}, "account-bpa: 111111111111"},
+		{"default account", Finding{Check: "account-bpa"}, "account-bpa: 222222222222"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toASFF(tt.finding, "arn:aws:securityhub:eu-west-1:222222222222:product/222222222222/default", "222222222222", "eu-west-1", at)
+			if title := aws.ToString(got.Title); title != tt.title {
+				t.Errorf("Title = %q, want %q", title, tt.title)
+			}
+		})
+	}
+
+	long := Finding{Check: "public-access", Bucket: "logs", Message: strings.Repeat("é", 1500)}
+	description := aws.ToString(toASFF(long, "", "222222222222", "eu-west-1", at).Description)
+	if !utf8.ValidString(description) || utf8.RuneCountInString(description) != 1024 {
+		t.Errorf("Description is %d runes, valid UTF-8 %v; want 1024 valid runes", utf8.RuneCountInString(description), utf8.ValidString(description))
+	}
+}
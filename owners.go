@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+)
+
+// unowned groups findings for buckets no owner could be found for.
+const unowned = "(unowned)"
+
+// owners resolves buckets to the teams that own them. Buckets are
+// attributed by their Stack tag, then their App tag, and otherwise by name.
+// Stacks and Apps map tag values to teams; unmapped values are used as the
+// team name as they are.
+type owners struct {
+	Stacks  map[string]string `json:"stacks"`
+	Apps    map[string]string `json:"apps"`
+	Buckets map[string]string `json:"buckets"` // bucket name or glob pattern to team
+}
+
+func loadOwners(file string) (*owners, error) {
+	o := &owners{}
+	if file == "" {
+		return o, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, o); err != nil {
+		return nil, fmt.Errorf("invalid owners file %s: %w", file, err)
+	}
+
+	for pattern := range o.Buckets {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid bucket pattern %q in owners file: %w", pattern, err)
+		}
+	}
+
+	return o, nil
+}
+
+// resolve returns the bucket's owning team, or "" if unknown.
+func (o *owners) resolve(bucket string, tags map[string]string) string {
+	if stack := tags["Stack"]; stack != "" {
+		return mappedOr(o.Stacks, stack)
+	}
+
+	if app := tags["App"]; app != "" {
+		return mappedOr(o.Apps, app)
+	}
+
+	if team, ok := o.Buckets[bucket]; ok {
+		return team
+	}
+
+	for _, pattern := range slices.Sorted(maps.Keys(o.Buckets)) {
+		if matched, _ := path.Match(pattern, bucket); matched {
+			return o.Buckets[pattern]
+		}
+	}
+
+	return ""
+}
+
+func mappedOr(m map[string]string, key string) string {
+	if v, ok := m[key]; ok {
+		return v
+	}
+
+	return key
+}
+
+// ownedBy returns the findings for buckets owned by team, which may be
+// unowned to select those with no known owner.
+func ownedBy(findings []Finding, team string) []Finding {
+	owned := []Finding{}
+	for _, f := range findings {
+		if isOwnedBy(f, team) {
+			owned = append(owned, f)
+		}
+	}
+
+	return owned
+}
+
+func isOwnedBy(f Finding, team string) bool {
+	return f.Owner == team || (team == unowned && f.Owner == "")
+}
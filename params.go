@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// checkParams holds the default parameters of each check that has any. They
+// are overridden per check under checks in the config file, e.g.
+//
+//	"checks": {"policy-complexity": {"maxStatements": 30}}
+var checkParams = map[string]any{
+	"policy-complexity": defaultPolicyComplexityParams,
+	"kms-key-policy":    kmsKeyPolicyParams{AllowedKeys: []string{}},
+	"unused-buckets":    unusedBucketsParams{Days: 90, SampleSize: 1000},
+	"storage-cost":      storageCostParams{MinSizeGB: 1024},
+	"typosquatting":     typosquattingParams{MaxVariants: 50, Buckets: []string{}},
+	"state-bucket": stateBucketParams{
+		NamePatterns: []string{`tfstate`, `terraform-state`, `^cdk-[a-z0-9]+-assets-`, `^cf-templates-`},
+		KeySuffixes:  []string{".tfstate", ".tfstate.backup"},
+	},
+	"object-lock": objectLockParams{
+		Tags:    map[string][]string{"DataRetention": {"compliance", "archive"}},
+		Mode:    "COMPLIANCE",
+		MinDays: 365,
+	},
+}
+
+// validateCheckParams rejects parameters for checks that don't take any.
+func (c *Config) validateCheckParams() error {
+	for id := range c.Checks {
+		if _, ok := checkParams[id]; !ok {
+			return fmt.Errorf("check %q has no parameters", id)
+		}
+	}
+
+	return nil
+}
+
+// paramsFor returns the check's parameters: its defaults, overridden by any
+// set in the config file. Unknown parameters are an error so that a typo
+// doesn't silently leave the default in place.
+func paramsFor[T any](c *Config, id string) (T, error) {
+	params := checkParams[id].(T)
+
+	raw, ok := c.Checks[id]
+	if !ok {
+		return params, nil
+	}
+
+	// Slices are copied so that overriding one doesn't change the default.
+	params = clone(params)
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&params); err != nil {
+		return params, fmt.Errorf("invalid parameters for %s: %w", id, err)
+	}
+
+	return params, nil
+}
+
+func clone[T any](v T) T {
+	data, _ := json.Marshal(v)
+
+	var copied T
+	json.Unmarshal(data, &copied)
+	return copied
+}
+
+// describeCheckParams lists the check's parameters and their defaults, for
+// list-checks.
+func describeCheckParams(id string) string {
+	params, ok := checkParams[id]
+	if !ok {
+		return ""
+	}
+
+	data, _ := json.Marshal(params)
+	return string(data)
+}
+
+// policyComplexityParams are the thresholds above which a policy is hard
+// enough to reason about that it should be reviewed.
+type policyComplexityParams struct {
+	MaxSizeRatio  float64 `json:"maxSizeRatio"` // of the 20KB policy size limit
+	MaxStatements int     `json:"maxStatements"`
+	MaxPrincipals int     `json:"maxPrincipals"`
+}
+
+var defaultPolicyComplexityParams = policyComplexityParams{
+	MaxSizeRatio:  0.8,
+	MaxStatements: 20,
+	MaxPrincipals: 25,
+}
+
+// kmsKeyPolicyParams restricts which KMS keys buckets may be encrypted with.
+type kmsKeyPolicyParams struct {
+	AllowedKeys []string `json:"allowedKeys"` // key ARNs, IDs or aliases; any key if empty
+}
+
+func (p kmsKeyPolicyParams) allowed(keyIDs ...string) bool {
+	if len(p.AllowedKeys) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(keyIDs, func(id string) bool { return slices.Contains(p.AllowedKeys, id) })
+}
+
+// unusedBucketsParams set how long a bucket must go unused to be reported,
+// and how many objects are listed looking for recent writes.
+type unusedBucketsParams struct {
+	Days       int `json:"days"`
+	SampleSize int `json:"sampleSize"`
+}
+
+// objectLockParams are the tags marking buckets that must have Object Lock,
+// and the default retention they must have.
+type objectLockParams struct {
+	Tags    map[string][]string `json:"tags"`
+	Mode    string              `json:"mode"` // COMPLIANCE, or GOVERNANCE to accept either
+	MinDays int                 `json:"minDays"`
+}
+
+// storageCostParams set how much a bucket must hold in S3 Standard for its
+// storage classes to be worth reviewing.
+type storageCostParams struct {
+	MinSizeGB float64 `json:"minSizeGB"`
+}
+
+// typosquattingParams limit how many lookalikes of each bucket name are
+// probed, and name private buckets to probe as well as public ones.
+type typosquattingParams struct {
+	MaxVariants int      `json:"maxVariants"`
+	Buckets     []string `json:"buckets"`
+}
+
+// stateBucketParams identify infrastructure state buckets, by regular
+// expressions matched against the bucket name, or key suffixes looked for in
+// the first 1000 objects listed.
+type stateBucketParams struct {
+	NamePatterns []string `json:"namePatterns"`
+	KeySuffixes  []string `json:"keySuffixes"`
+
+	names []*regexp.Regexp
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetFinding is a finding as a row of the Parquet archive. The scan date
+// and account are the partitions, in the path rather than the file.
+type parquetFinding struct {
+	ScanTime time.Time `parquet:"scan_time,timestamp(millisecond)"`
+	Account  string    `parquet:"account_name"`
+	Bucket   string    `parquet:"bucket"`
+	Region   string    `parquet:"region"`
+	Owner    string    `parquet:"owner"`
+	Check    string    `parquet:"check_id"`
+	Severity string    `parquet:"severity"`
+	Score    int32     `parquet:"score"`
+	Message  string    `parquet:"message"`
+	Details  []string  `parquet:"details,list"`
+	Signals  []string  `parquet:"signals,list"`
+}
+
+// parquetColumns are the Glue columns matching parquetFinding, and
+// parquetPartitions the partition keys in its path.
+var (
+	parquetColumns = [][2]string{
+		{"scan_time", "timestamp"},
+		{"account_name", "string"},
+		{"bucket", "string"},
+		{"region", "string"},
+		{"owner", "string"},
+		{"check_id", "string"},
+		{"severity", "string"},
+		{"score", "int"},
+		{"message", "string"},
+		{"details", "array<string>"},
+		{"signals", "array<string>"},
+	}
+	parquetPartitions = [][2]string{{"dt", "string"}, {"account", "string"}}
+)
+
+// parquetFiles splits the findings into a Parquet file per account, keyed by
+// their path under the archive's root:
+// dt=2006-01-02/account=123456789012/20060102T150405Z.parquet. Accounts are
+// partitioned by ID, or by profile where the ID isn't known. Rows are
+// encoded as they are streamed, so only the files are held in memory.
+func parquetFiles(r savedReport, findings findingStream) (map[string][]byte, error) {
+	type file struct {
+		buf *bytes.Buffer
+		w   *parquet.GenericWriter[parquetFinding]
+	}
+	files := map[string]file{}
+
+	err := findings(func(f Finding) error {
+		account := f.AccountID
+		if account == "" {
+			account = f.Account
+		}
+
+		row := parquetFinding{
+			ScanTime: r.Time.UTC(),
+			Account:  f.Account,
+			Bucket:   f.Bucket,
+			Owner:    f.Owner,
+			Check:    f.Check,
+			Severity: f.Severity.String(),
+			Score:    int32(f.Score),
+			Message:  f.Message,
+			Details:  f.Details,
+			Signals:  f.Signals,
+		}
+		if f.Metadata != nil {
+			row.Region = f.Metadata.Region
+		}
+
+		out, ok := files[account]
+		if !ok {
+			buf := &bytes.Buffer{}
+			out = file{buf, parquet.NewGenericWriter[parquetFinding](buf)}
+			files[account] = out
+		}
+		_, err := out.w.Write([]parquetFinding{row})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	written := map[string][]byte{}
+	for account, out := range files {
+		if err := out.w.Close(); err != nil {
+			return nil, err
+		}
+
+		key := path.Join("dt="+r.Time.UTC().Format(time.DateOnly), "account="+account, r.Time.UTC().Format("20060102T150405Z")+".parquet")
+		written[key] = out.buf.Bytes()
+	}
+
+	return written, nil
+}
+
+// writeParquetOutput writes the report's Parquet files under root, a local
+// directory or s3://bucket/prefix written with the profile.
+func writeParquetOutput(ctx context.Context, root string, profile string, r savedReport, findings findingStream) error {
+	files, err := parquetFiles(r, findings)
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(root, "s3://") {
+		for key, data := range files {
+			file := filepath.Join(root, filepath.FromSlash(key))
+			if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+				return err
+			}
+			if err := os.WriteFile(file, data, 0o600); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(root, "s3://"), "/")
+	config, err := loadConfig(ctx, profile)
+	if err != nil {
+		return fmt.Errorf("unable to load AWS config: %w", err)
+	}
+	client, err := regionalS3Client(ctx, config, bucket)
+	if err != nil {
+		return err
+	}
+
+	for key, data := range files {
+		key = path.Join(prefix, key)
+		if _, err := client.PutObject(ctx, &s3.PutObjectInput{Bucket: &bucket, Key: &key, Body: bytes.NewReader(data)}); err != nil {
+			return fmt.Errorf("unable to upload s3://%s/%s: %w", bucket, key, err)
+		}
+	}
+
+	return nil
+}
+
+// glueTableMain prints the Glue table definition for a Parquet archive, as
+// the table input of aws glue create-table.
+func glueTableMain(args []string) {
+	fs := flag.NewFlagSet("glue-table", flag.ExitOnError)
+	location := fs.String("location", "", "s3://bucket/prefix the parquet output writes to")
+	table := fs.String("table", "s3_audit_findings", "table name")
+	accounts := fs.String("accounts", "", "comma-separated account IDs (or profiles, where IDs aren't known) partitioned by, to use partition projection rather than MSCK REPAIR TABLE after each scan")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit glue-table -location s3://bucket/prefix > table.json")
+		fmt.Fprintln(fs.Output(), "       aws glue create-table --database-name db --table-input file://table.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if !strings.HasPrefix(*location, "s3://") {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	check(enc.Encode(glueTable(*table, *location, splitList(*accounts))), "unable to write table definition")
+}
+
+// glueTable is the Glue TableInput for the archive at location. With the
+// accounts known, partitions are projected, so new ones are queryable as
+// soon as they're written.
+func glueTable(name string, location string, accounts []string) map[string]any {
+	column := func(c [2]string) map[string]string { return map[string]string{"Name": c[0], "Type": c[1]} }
+	columns, partitions := []map[string]string{}, []map[string]string{}
+	for _, c := range parquetColumns {
+		columns = append(columns, column(c))
+	}
+	for _, c := range parquetPartitions {
+		partitions = append(partitions, column(c))
+	}
+
+	parameters := map[string]string{"classification": "parquet", "EXTERNAL": "TRUE"}
+	if len(accounts) > 0 {
+		parameters["projection.enabled"] = "true"
+		parameters["projection.dt.type"] = "date"
+		parameters["projection.dt.format"] = "yyyy-MM-dd"
+		parameters["projection.dt.range"] = "2020-01-01,NOW"
+		parameters["projection.account.type"] = "enum"
+		parameters["projection.account.values"] = strings.Join(accounts, ",")
+	}
+
+	return map[string]any{
+		"Name":          name,
+		"TableType":     "EXTERNAL_TABLE",
+		"Parameters":    parameters,
+		"PartitionKeys": partitions,
+		"StorageDescriptor": map[string]any{
+			"Columns":      columns,
+			"Location":     strings.TrimSuffix(location, "/") + "/",
+			"InputFormat":  "org.apache.hadoop.hive.ql.io.parquet.MapredParquetInputFormat",
+			"OutputFormat": "org.apache.hadoop.hive.ql.io.parquet.MapredParquetOutputFormat",
+			"SerdeInfo": map[string]any{
+				"SerializationLibrary": "org.apache.hadoop.hive.ql.io.parquet.serde.ParquetHiveSerDe",
+			},
+		},
+	}
+}
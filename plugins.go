@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Plugin is an external check, configured in the config file:
+//
+//	{"name": "naming-standard", "command": ["/opt/checks/naming", "-strict"], "timeout": "1m"}
+//
+// The command is started once per scan and speaks newline-delimited JSON.
+// For each bucket it is sent a line
+//
+//	{"bucket": <bucket facts, as seen by Rego and CEL rules>}
+//
+// and must reply with a single line
+//
+//	{"findings": [{"check": "...", "subject": "...", "severity": "HIGH", "message": "...", "details": ["..."]}]}
+//
+// with an empty list if there is nothing to report. Check defaults to the
+// plugin's name and severity to MEDIUM. A check named like a built-in one is
+// reported as <plugin>/<check>, so it can't be taken for, suppressed or
+// triaged as the built-in check. Subject tells apart several findings from
+// the check on a bucket across scans; without it, the message does.
+// Anything written to stderr is passed through to ours. Stdin is closed at
+// the end of the scan.
+//
+// A plugin not replying within its timeout (30s by default) is killed, as a
+// late reply would be taken for the next bucket's, and fails for the rest of
+// the scan.
+type Plugin struct {
+	Name    string   `json:"name"`
+	Command []string `json:"command"`
+	Timeout string   `json:"timeout"`
+}
+
+// plugin is a running Plugin.
+type plugin struct {
+	conf Plugin
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *bufio.Scanner
+	timeout time.Duration
+	mu      sync.Mutex // one request in flight at a time
+	err     error      // why the plugin was killed, if it was
+}
+
+type pluginRequest struct {
+	Bucket *BucketFacts `json:"bucket"`
+}
+
+type pluginResponse struct {
+	Findings []pluginFinding `json:"findings"`
+}
+
+type pluginFinding struct {
+	Check    string    `json:"check"`
+	Subject  string    `json:"subject"` // to tell apart several findings from the check; the message if not given
+	Severity *Severity `json:"severity"`
+	Message  string    `json:"message"`
+	Details  []string  `json:"details"`
+}
+
+// maxPluginResponse bounds a reply line, which could otherwise grow without
+// limit if a plugin never writes a newline.
+const maxPluginResponse = 4 << 20
+
+// defaultPluginTimeout is how long a plugin has to reply for a bucket if its
+// config doesn't say.
+const defaultPluginTimeout = 30 * time.Second
+
+// startPlugins starts every configured plugin, stopping any already started
+// if one fails. The plugins are killed if the context is cancelled.
+func startPlugins(ctx context.Context, configs []Plugin) ([]*plugin, error) {
+	plugins := []*plugin{}
+	for _, conf := range configs {
+		p, err := startPlugin(ctx, conf)
+		if err != nil {
+			stopPlugins(plugins)
+			return nil, fmt.Errorf("unable to start plugin %s: %w", conf.Name, err)
+		}
+		plugins = append(plugins, p)
+	}
+
+	return plugins, nil
+}
+
+func startPlugin(ctx context.Context, conf Plugin) (*plugin, error) {
+	if conf.Name == "" || len(conf.Command) == 0 {
+		return nil, errors.New("a plugin needs a name and command")
+	}
+
+	timeout := defaultPluginTimeout
+	if conf.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(conf.Timeout); err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q", conf.Timeout)
+		}
+	}
+
+	p := &plugin{conf: conf, cmd: exec.CommandContext(ctx, conf.Command[0], conf.Command[1:]...), timeout: timeout}
+	p.cmd.Stderr = os.Stderr
+
+	var err error
+	if p.stdin, err = p.cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	stdout, err := p.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	p.stdout = bufio.NewScanner(stdout)
+	p.stdout.Buffer(nil, maxPluginResponse)
+
+	return p, p.cmd.Start()
+}
+
+// stopPlugins closes each plugin's stdin and waits for it to exit.
+func stopPlugins(plugins []*plugin) {
+	for _, p := range plugins {
+		p.stdin.Close()
+		if err := p.cmd.Wait(); err != nil {
+			component("plugin").Warn("plugin exited with an error", "plugin", p.Name(), "error", err)
+		}
+	}
+}
+
+func (p *plugin) Name() string { return p.conf.Name }
+
+func (p *plugin) Run(ctx context.Context, facts *BucketFacts) []Finding {
+	findings, _ := p.runFallible(ctx, facts)
+	return findings
+}
+
+func (p *plugin) runFallible(ctx context.Context, facts *BucketFacts) ([]Finding, error) {
+	resp, err := p.call(ctx, facts)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w", p.Name(), err)
+	}
+
+	findings := []Finding{}
+	for _, f := range resp.Findings {
+		finding := Finding{Check: cmp.Or(f.Check, p.Name()), Subject: cmp.Or(f.Subject, f.Message), Severity: SeverityMedium, Message: f.Message, Details: f.Details}
+		if slices.ContainsFunc(registry, func(c checkInfo) bool { return c.ID == finding.Check }) {
+			finding.Check = p.Name() + "/" + finding.Check
+		}
+		if f.Severity != nil {
+			finding.Severity = *f.Severity
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings, nil
+}
+
+// call sends the plugin a bucket's facts and waits for its reply, killing
+// it if the reply doesn't come within the timeout or the context is
+// cancelled first.
+func (p *plugin) call(ctx context.Context, facts *BucketFacts) (*pluginResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	req, err := json.Marshal(pluginRequest{Bucket: facts})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, p.timeout, fmt.Errorf("no reply within %s", p.timeout))
+	defer cancel()
+
+	type result struct {
+		resp *pluginResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := p.exchange(req)
+		done <- result{resp, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.resp, r.err
+	case <-ctx.Done():
+		p.cmd.Process.Kill()
+		p.err = fmt.Errorf("plugin killed: %w", context.Cause(ctx))
+		return nil, p.err
+	}
+}
+
+// exchange writes a request line and reads the reply.
+func (p *plugin) exchange(req []byte) (*pluginResponse, error) {
+	if _, err := p.stdin.Write(append(req, '\n')); err != nil {
+		return nil, fmt.Errorf("unable to send facts: %w", err)
+	}
+
+	if !p.stdout.Scan() {
+		if err := p.stdout.Err(); err != nil {
+			return nil, fmt.Errorf("unable to read findings: %w", err)
+		}
+		return nil, errors.New("plugin exited")
+	}
+
+	var resp pluginResponse
+	if err := json.Unmarshal(p.stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+
+	return &resp, nil
+}
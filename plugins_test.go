@@ -0,0 +1,145 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// replyPlugin starts a plugin answering every bucket with reply.
+func replyPlugin(t *testing.T, name string, reply string) *plugin {
+	t.Helper()
+
+	return scriptPlugin(t, Plugin{Name: name}, "while read -r line; do echo '"+reply+"'; done")
+}
+
+// scriptPlugin starts a plugin running the shell script.
+func scriptPlugin(t *testing.T, conf Plugin, script string) *plugin {
+	t.Helper()
+
+	conf.Command = []string{"sh", "-c", script}
+	p, err := startPlugin(t.Context(), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { stopPlugins([]*plugin{p}) })
+
+	return p
+}
+
+func TestPluginCheckNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin string
+		reply  string
+		check  string
+	}{
+		{"own check", "naming-standard", `{"findings": [{"check": "bucket-name", "message": "m"}]}`, "bucket-name"},
+		{"default", "naming-standard", `{"findings": [{"message": "m"}]}`, "naming-standard"},
+		{"built-in check", "naming-standard", `{"findings": [{"check": "public-access", "message": "m"}]}`, "naming-standard/public-access"},
+		{"built-in plugin name", "log-bucket", `{"findings": [{"message": "m"}]}`, "log-bucket/log-bucket"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := replyPlugin(t, tt.plugin, tt.reply).runFallible(t.Context(), &BucketFacts{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(findings) != 1 || findings[0].Check != tt.check {
+				t.Errorf("runFallible() = %+v, want one finding from %s", findings, tt.check)
+			}
+		})
+	}
+}
+
+func TestPluginReplies(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    []Finding
+		wantErr string
+	}{
+		{"nothing to report", `{"findings": []}`, []Finding{}, ""},
+		{"defaults", `{"findings": [{"message": "name too long"}]}`,
+			[]Finding{{Check: "naming-standard", Subject: "name too long", Severity: SeverityMedium, Message: "name too long"}}, ""},
+		{"every field", `{"findings": [{"check": "prefix", "subject": "team", "severity": "HIGH", "message": "no team prefix", "details": ["expected ops-"]}]}`,
+			[]Finding{{Check: "prefix", Subject: "team", Severity: SeverityHigh, Message: "no team prefix", Details: []string{"expected ops-"}}}, ""},
+		{"several", `{"findings": [{"check": "a", "message": "m"}, {"check": "b", "message": "m"}]}`,
+			[]Finding{{Check: "a", Subject: "m", Severity: SeverityMedium, Message: "m"}, {Check: "b", Subject: "m", Severity: SeverityMedium, Message: "m"}}, ""},
+		{"unknown severity", `{"findings": [{"severity": "URGENT", "message": "m"}]}`, nil, "invalid response"},
+		{"not JSON", `ok`, nil, "invalid response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := replyPlugin(t, "naming-standard", tt.reply).runFallible(t.Context(), &BucketFacts{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("runFallible() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("runFallible() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.Check != w.Check || g.Subject != w.Subject || g.Severity != w.Severity || g.Message != w.Message || strings.Join(g.Details, "\n") != strings.Join(w.Details, "\n") {
+					t.Errorf("runFallible()[%d] = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestPluginFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    Plugin
+		script  string
+		wantErr string
+		again   bool // whether a later call can succeed
+	}{
+		{"exits", Plugin{}, "read -r line", "plugin exited", false},
+		{"times out", Plugin{Timeout: "100ms"}, "read -r line; exec sleep 5", "no reply within 100ms", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conf.Name = "naming-standard"
+			p := scriptPlugin(t, tt.conf, tt.script)
+
+			_, err := p.runFallible(t.Context(), &BucketFacts{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("runFallible() error = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := p.runFallible(t.Context(), &BucketFacts{}); (err == nil) != tt.again {
+				t.Errorf("second runFallible() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestStartPluginInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		conf Plugin
+	}{
+		{"no name", Plugin{Command: []string{"true"}}},
+		{"no command", Plugin{Name: "naming-standard"}},
+		{"invalid timeout", Plugin{Name: "naming-standard", Command: []string{"true"}, Timeout: "soon"}},
+		{"zero timeout", Plugin{Name: "naming-standard", Command: []string{"true"}, Timeout: "0s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if p, err := startPlugin(t.Context(), tt.conf); err == nil {
+				stopPlugins([]*plugin{p})
+				t.Error("startPlugin() passed an invalid config")
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// resourceConditionKeys limit which accounts' or organizations' resources a
+// request may be for.
+var resourceConditionKeys = map[string]bool{
+	"aws:resourceaccount":  true,
+	"s3:resourceaccount":   true,
+	"aws:resourceorgid":    true,
+	"aws:resourceorgpaths": true,
+}
+
+// AnyBucketGrant is a VPC endpoint policy statement letting requests
+// through to any bucket, including other accounts' buckets, which is a way
+// for data to leave through the endpoint.
+type AnyBucketGrant struct {
+	Sid     string   `json:"sid,omitempty"`
+	Actions []string `json:"actions"`
+	Anyone  bool     `json:"anyone"` // for any principal, with no conditions narrowing it
+}
+
+func (g AnyBucketGrant) String() string {
+	principals := "the principals it allows"
+	if g.Anyone {
+		principals = "anyone"
+	}
+
+	return fmt.Sprintf("statement %s allows %s on every bucket to %s", sidLabel(g.Sid), strings.Join(g.Actions, ", "), principals)
+}
+
+// AnyBucketGrants returns the statements of a VPC endpoint policy that
+// allow S3 actions on every bucket, without an aws:ResourceAccount,
+// s3:ResourceAccount or aws:ResourceOrgID condition keeping requests to
+// known buckets. The default endpoint policy is one.
+func (p *Policy) AnyBucketGrants() []AnyBucketGrant {
+	grants := []AnyBucketGrant{}
+
+	for _, stmt := range p.Statement {
+		if !stmt.IsAllow() || stmt.hasConditionOn(resourceConditionKeys) {
+			continue
+		}
+
+		actions := matching(stmt.Action, "s3:*")
+		if len(actions) == 0 {
+			actions = append(matching(stmt.Action, "s3:GetObject"), matching(stmt.Action, "s3:PutObject")...)
+			slices.Sort(actions)
+			actions = slices.Compact(actions)
+		}
+		if len(stmt.NotAction) > 0 {
+			actions = []string{"everything except " + strings.Join(stmt.NotAction, ", ")}
+		}
+		if len(actions) == 0 || !coversEveryBucket(stmt) {
+			continue
+		}
+
+		grants = append(grants, AnyBucketGrant{Sid: stmt.Sid, Actions: actions, Anyone: stmt.IsPublic()})
+	}
+
+	return grants
+}
+
+// coversEveryBucket reports whether the statement's resources include every
+// bucket, or its objects.
+func coversEveryBucket(s Statement) bool {
+	if len(s.NotResource) > 0 {
+		return true
+	}
+
+	for _, resource := range s.Resource {
+		if resource == "*" {
+			return true
+		}
+
+		parts := strings.SplitN(resource, ":", 6)
+		if len(parts) < 6 || parts[3] != "" || parts[4] != "" {
+			continue
+		}
+		if matched, _ := path.Match(strings.ToLower(parts[2]), "s3"); !matched {
+			continue
+		}
+		if bucket, _, _ := strings.Cut(parts[5], "/"); bucket == "*" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package policy
+
+import "strings"
+
+// ObjectRead is an identity policy statement allowing s3:GetObject, which
+// has no principal: it grants whoever the policy is attached to.
+type ObjectRead struct {
+	Sid         string   `json:"sid,omitempty"`
+	Actions     []string `json:"actions"`   // the patterns covering s3:GetObject
+	Resources   []string `json:"resources"` // "*" for a NotResource statement
+	Conditional bool     `json:"conditional"`
+}
+
+// FullAccess reports whether the grant is of every S3 action rather than
+// just reading.
+func (r ObjectRead) FullAccess() bool {
+	for _, a := range r.Actions {
+		if a == "*" || strings.EqualFold(a, "s3:*") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ObjectReads returns the statements of an identity policy that allow
+// reading objects. A NotAction statement allows it unless s3:GetObject is
+// among its exceptions.
+func (p *Policy) ObjectReads() []ObjectRead {
+	reads := []ObjectRead{}
+
+	for _, stmt := range p.Statement {
+		if !stmt.IsAllow() {
+			continue
+		}
+
+		actions := matching(stmt.Action, "s3:GetObject")
+		if len(stmt.NotAction) > 0 && len(matching(stmt.NotAction, "s3:GetObject")) == 0 {
+			actions = []string{"*"}
+		}
+		if len(actions) == 0 {
+			continue
+		}
+
+		resources := stmt.Resource
+		if len(stmt.NotResource) > 0 {
+			resources = StringList{"*"}
+		}
+
+		reads = append(reads, ObjectRead{
+			Sid:         stmt.Sid,
+			Actions:     actions,
+			Resources:   resources,
+			Conditional: len(stmt.Condition) > 0,
+		})
+	}
+
+	return reads
+}
@@ -0,0 +1,265 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// adminActions are bucket-administration actions worth calling out when a
+// wildcard quietly grants them alongside ordinary object access.
+var adminActions = []string{
+	"s3:PutBucketPolicy",
+	"s3:DeleteBucketPolicy",
+	"s3:PutBucketAcl",
+	"s3:PutObjectAcl",
+	"s3:PutBucketPublicAccessBlock",
+	"s3:PutBucketOwnershipControls",
+	"s3:PutEncryptionConfiguration",
+	"s3:PutLifecycleConfiguration",
+	"s3:PutReplicationConfiguration",
+	"s3:DeleteBucket",
+}
+
+// BroadGrant is a statement granting sweeping S3 actions to a wide
+// principal.
+type BroadGrant struct {
+	Sid        string   `json:"sid,omitempty"`
+	Principals []string `json:"principals"`
+	Actions    []string `json:"actions"`   // the broad action patterns
+	Covers     []string `json:"covers"`    // admin actions the patterns include
+	Suggested  []string `json:"suggested"` // object-level alternatives, to be trimmed to what is needed
+}
+
+func (g BroadGrant) String() string {
+	return fmt.Sprintf("statement %s grants %s to %s, which includes %s; consider only the needed subset of %s instead",
+		sidLabel(g.Sid),
+		strings.Join(g.Actions, " + "),
+		strings.Join(g.Principals, ", "),
+		strings.Join(g.Covers, ", "),
+		strings.Join(g.Suggested, ", "),
+	)
+}
+
+// BroadGrants returns the statements that allow s3:* (or *), or both
+// s3:Put* and s3:Delete*, to a wide principal: anyone, NotPrincipal, or
+// a whole account other than ownerAccount.
+func (p *Policy) BroadGrants(ownerAccount string) []BroadGrant {
+	grants := []BroadGrant{}
+
+	for _, stmt := range p.Statement {
+		if !stmt.IsAllow() || !stmt.hasWidePrincipal(ownerAccount) {
+			continue
+		}
+
+		var broad []string
+		var suggested []string
+		switch {
+		case stmt.allowsAction("*", "s3:*"):
+			broad = matching(stmt.Action, "s3:*")
+			suggested = []string{"s3:GetObject", "s3:ListBucket", "s3:PutObject", "s3:DeleteObject"}
+		case stmt.allowsAction("s3:Put*") && stmt.allowsAction("s3:Delete*"):
+			broad = append(matching(stmt.Action, "s3:Put*"), matching(stmt.Action, "s3:Delete*")...)
+			suggested = []string{"s3:PutObject", "s3:DeleteObject"}
+		default:
+			continue
+		}
+
+		covers := []string{}
+		for _, action := range adminActions {
+			if stmt.allowsAction(action) {
+				covers = append(covers, action)
+			}
+		}
+
+		principals := stmt.Principal.Principals()
+		if stmt.NotPrincipal != nil {
+			principals = []string{"everyone except " + strings.Join(stmt.NotPrincipal.Principals(), ", ")}
+		}
+
+		grants = append(grants, BroadGrant{
+			Sid:        stmt.Sid,
+			Principals: principals,
+			Actions:    broad,
+			Covers:     covers,
+			Suggested:  suggested,
+		})
+	}
+
+	return grants
+}
+
+func (s Statement) hasWidePrincipal(ownerAccount string) bool {
+	if s.NotPrincipal != nil || s.HasWildcardPrincipal() {
+		return true
+	}
+
+	if s.Principal == nil {
+		return false
+	}
+
+	for _, v := range s.Principal.AWS {
+		isWholeAccount := isAccountID(v) || strings.HasSuffix(v, ":root")
+		if isWholeAccount && AccountID(v) != ownerAccount {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowsAction reports whether one of the statement's Action patterns covers
+// any of the given actions (which may themselves be patterns, matched
+// literally).
+func (s Statement) allowsAction(actions ...string) bool {
+	for _, pattern := range s.Action {
+		for _, action := range actions {
+			if actionMatches(pattern, action) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matching returns the patterns in actions that cover action.
+func matching(actions StringList, action string) []string {
+	matched := []string{}
+	for _, pattern := range actions {
+		if actionMatches(pattern, action) {
+			matched = append(matched, pattern)
+		}
+	}
+
+	return matched
+}
+
+// actionMatches compares IAM actions case-insensitively, with * and ? in
+// pattern as wildcards.
+func actionMatches(pattern string, action string) bool {
+	matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(action))
+	return err == nil && matched
+}
+
+// sourceConditionKeys tie a service principal's access to requests made on
+// behalf of particular resources or accounts.
+var sourceConditionKeys = map[string]bool{
+	"aws:sourcearn":     true,
+	"aws:sourceaccount": true,
+	"aws:sourceorgid":   true,
+}
+
+// ConfusedDeputy is a statement granting to AWS services without saying on
+// whose behalf, letting any customer's resources in that service act on the
+// bucket.
+type ConfusedDeputy struct {
+	Sid      string   `json:"sid,omitempty"`
+	Services []string `json:"services"`
+}
+
+func (c ConfusedDeputy) String() string {
+	return fmt.Sprintf("statement %s grants to %s without an aws:SourceArn or aws:SourceAccount condition",
+		sidLabel(c.Sid), strings.Join(c.Services, ", "))
+}
+
+// ConfusedDeputies returns Allow statements for service principals (log
+// delivery, CloudTrail, SES and so on) that lack source conditions.
+func (p *Policy) ConfusedDeputies() []ConfusedDeputy {
+	deputies := []ConfusedDeputy{}
+
+	for _, stmt := range p.Statement {
+		if !stmt.IsAllow() || stmt.Principal == nil || len(stmt.Principal.Service) == 0 {
+			continue
+		}
+
+		if stmt.hasSourceCondition() {
+			continue
+		}
+
+		deputies = append(deputies, ConfusedDeputy{Sid: stmt.Sid, Services: stmt.Principal.Service})
+	}
+
+	return deputies
+}
+
+func (s Statement) hasSourceCondition() bool {
+	return s.hasConditionOn(sourceConditionKeys)
+}
+
+// hasConditionOn reports whether a restricting condition limits any of the
+// keys (lower case) to values other than *.
+func (s Statement) hasConditionOn(conditionKeys map[string]bool) bool {
+	for op, keys := range s.Condition {
+		if !isRestrictingOperator(op) {
+			continue
+		}
+
+		for key, values := range keys {
+			if conditionKeys[strings.ToLower(key)] && len(values) > 0 && !slices.Contains(values, "*") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func sidLabel(sid string) string {
+	if sid == "" {
+		return "(no Sid)"
+	}
+
+	return sid
+}
+
+// AntiPattern is a negated policy element in an Allow statement.
+type AntiPattern struct {
+	Sid         string `json:"sid,omitempty"`
+	Element     string `json:"element"`
+	Explanation string `json:"explanation"`
+}
+
+func (a AntiPattern) String() string {
+	return fmt.Sprintf("statement %s uses %s: %s", sidLabel(a.Sid), a.Element, a.Explanation)
+}
+
+// AntiPatterns returns uses of NotPrincipal, NotAction and NotResource in
+// Allow statements. In Deny statements they narrow a denial rather than
+// widen a grant, so aren't reported.
+func (p *Policy) AntiPatterns() []AntiPattern {
+	patterns := []AntiPattern{}
+
+	for _, stmt := range p.Statement {
+		if !stmt.IsAllow() {
+			continue
+		}
+
+		if stmt.NotPrincipal != nil {
+			patterns = append(patterns, AntiPattern{
+				Sid:         stmt.Sid,
+				Element:     "NotPrincipal",
+				Explanation: "Allow with NotPrincipal grants access to everyone, including anonymous users, except " + strings.Join(stmt.NotPrincipal.Principals(), ", "),
+			})
+		}
+
+		if len(stmt.NotAction) > 0 {
+			patterns = append(patterns, AntiPattern{
+				Sid:         stmt.Sid,
+				Element:     "NotAction",
+				Explanation: "Allow with NotAction grants every action except " + strings.Join(stmt.NotAction, ", ") + ", including any actions AWS adds in future",
+			})
+		}
+
+		if len(stmt.NotResource) > 0 {
+			patterns = append(patterns, AntiPattern{
+				Sid:         stmt.Sid,
+				Element:     "NotResource",
+				Explanation: "Allow with NotResource applies to every resource except " + strings.Join(stmt.NotResource, ", "),
+			})
+		}
+	}
+
+	return patterns
+}
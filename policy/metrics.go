@@ -0,0 +1,33 @@
+package policy
+
+// MaxSize is the largest bucket policy S3 accepts, in bytes.
+const MaxSize = 20 * 1024
+
+type Metrics struct {
+	Statements int `json:"statements"`
+	Size       int `json:"size"`
+	Principals int `json:"principals"` // distinct principals across all statements
+}
+
+func (p *Policy) Metrics() Metrics {
+	principals := map[string]bool{}
+	for _, stmt := range p.Statement {
+		for _, principal := range stmt.Principal.Principals() {
+			principals[principal] = true
+		}
+		for _, principal := range stmt.NotPrincipal.Principals() {
+			principals[principal] = true
+		}
+	}
+
+	return Metrics{
+		Statements: len(p.Statement),
+		Size:       p.Size,
+		Principals: len(principals),
+	}
+}
+
+// SizeRatio is how much of the policy size limit is used, from 0 to 1.
+func (m Metrics) SizeRatio() float64 {
+	return float64(m.Size) / MaxSize
+}
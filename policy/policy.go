@@ -0,0 +1,309 @@
+// Package policy parses S3 bucket policies and decides whether they are
+// public using the same rules as S3 Block Public Access.
+//
+// See: https://docs.aws.amazon.com/AmazonS3/latest/userguide/access-control-block-public-access.html#access-control-block-public-access-policy-status
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Policy struct {
+	Version   string      `json:"Version,omitempty"`
+	ID        string      `json:"Id,omitempty"`
+	Statement []Statement `json:"Statement"`
+
+	Size int `json:"-"` // length of the document in bytes, when parsed with Parse
+}
+
+type Statement struct {
+	Sid          string     `json:"Sid,omitempty"`
+	Effect       string     `json:"Effect"`
+	Principal    *Principal `json:"Principal,omitempty"`
+	NotPrincipal *Principal `json:"NotPrincipal,omitempty"`
+	Action       StringList `json:"Action,omitempty"`
+	NotAction    StringList `json:"NotAction,omitempty"`
+	Resource     StringList `json:"Resource,omitempty"`
+	NotResource  StringList `json:"NotResource,omitempty"`
+	Condition    Conditions `json:"Condition,omitempty"`
+}
+
+// Conditions maps operator (e.g. StringEquals) to condition key to values.
+type Conditions map[string]map[string]StringList
+
+// Principal is either "*" (All) or a map of principal type to values.
+type Principal struct {
+	All           bool
+	AWS           StringList
+	Service       StringList
+	Federated     StringList
+	CanonicalUser StringList
+}
+
+// StringList accepts either a single value or an array of values. Non-string
+// scalars (common in conditions, e.g. "aws:SecureTransport": false) are
+// converted to strings.
+type StringList []string
+
+// Parse parses a policy document.
+func Parse(doc string) (*Policy, error) {
+	var p Policy
+	if err := json.Unmarshal([]byte(doc), &p); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+
+	p.Size = len(doc)
+	return &p, nil
+}
+
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Version   string          `json:"Version"`
+		ID        string          `json:"Id"`
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	p.Version = raw.Version
+	p.ID = raw.ID
+	p.Statement = nil
+
+	if len(raw.Statement) == 0 {
+		return nil
+	}
+
+	// A single statement may be given as an object rather than an array.
+	if raw.Statement[0] == '{' {
+		var stmt Statement
+		if err := json.Unmarshal(raw.Statement, &stmt); err != nil {
+			return err
+		}
+		p.Statement = []Statement{stmt}
+		return nil
+	}
+
+	return json.Unmarshal(raw.Statement, &p.Statement)
+}
+
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if s != "*" {
+			return fmt.Errorf("invalid principal %q", s)
+		}
+		p.All = true
+		return nil
+	}
+
+	var m map[string]StringList
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	p.AWS = m["AWS"]
+	p.Service = m["Service"]
+	p.Federated = m["Federated"]
+	p.CanonicalUser = m["CanonicalUser"]
+	return nil
+}
+
+func (p Principal) MarshalJSON() ([]byte, error) {
+	if p.All {
+		return json.Marshal("*")
+	}
+
+	m := map[string]StringList{}
+	for k, v := range map[string]StringList{"AWS": p.AWS, "Service": p.Service, "Federated": p.Federated, "CanonicalUser": p.CanonicalUser} {
+		if len(v) > 0 {
+			m[k] = v
+		}
+	}
+
+	return json.Marshal(m)
+}
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	values, ok := raw.([]any)
+	if !ok {
+		values = []any{raw}
+	}
+
+	*l = make(StringList, 0, len(values))
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			*l = append(*l, v)
+		case bool:
+			*l = append(*l, strconv.FormatBool(v))
+		case float64:
+			*l = append(*l, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			return fmt.Errorf("unsupported value %v", v)
+		}
+	}
+
+	return nil
+}
+
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+
+	return json.Marshal([]string(l))
+}
+
+// IsAllow reports whether the statement grants (rather than denies) access.
+func (s Statement) IsAllow() bool {
+	return strings.EqualFold(s.Effect, "Allow")
+}
+
+// Principals returns every principal value in the statement, prefixed by
+// type for non-AWS principals (e.g. "Service:logging.s3.amazonaws.com").
+func (p *Principal) Principals() []string {
+	if p == nil {
+		return nil
+	}
+
+	if p.All {
+		return []string{"*"}
+	}
+
+	principals := append([]string{}, p.AWS...)
+	for _, v := range p.Service {
+		principals = append(principals, "Service:"+v)
+	}
+	for _, v := range p.Federated {
+		principals = append(principals, "Federated:"+v)
+	}
+	for _, v := range p.CanonicalUser {
+		principals = append(principals, "CanonicalUser:"+v)
+	}
+
+	return principals
+}
+
+// Grantees returns the distinct principals named in Allow statements, in
+// order of first appearance.
+func (p *Policy) Grantees() []string {
+	seen := map[string]bool{}
+	grantees := []string{}
+
+	for _, stmt := range p.Statement {
+		if !stmt.IsAllow() {
+			continue
+		}
+
+		for _, principal := range stmt.Principal.Principals() {
+			if !seen[principal] {
+				seen[principal] = true
+				grantees = append(grantees, principal)
+			}
+		}
+	}
+
+	return grantees
+}
+
+// ExternalAccounts returns the distinct accounts other than ownerAccount
+// granted access by AWS principals in Allow statements.
+func (p *Policy) ExternalAccounts(ownerAccount string) []string {
+	seen := map[string]bool{}
+	accounts := []string{}
+
+	for _, stmt := range p.Statement {
+		if !stmt.IsAllow() || stmt.Principal == nil {
+			continue
+		}
+
+		for _, principal := range stmt.Principal.AWS {
+			id := AccountID(principal)
+			if id != "" && id != ownerAccount && !seen[id] {
+				seen[id] = true
+				accounts = append(accounts, id)
+			}
+		}
+	}
+
+	return accounts
+}
+
+// DeletedPrincipals returns the distinct AWS principals in Allow statements
+// that are IAM unique IDs rather than ARNs. IAM rewrites a policy's reference
+// to a user or role to its unique ID when the principal is deleted, so these
+// grant nothing and are left over from deleted principals.
+func (p *Policy) DeletedPrincipals() []string {
+	seen := map[string]bool{}
+	deleted := []string{}
+
+	for _, stmt := range p.Statement {
+		if !stmt.IsAllow() || stmt.Principal == nil {
+			continue
+		}
+
+		for _, principal := range stmt.Principal.AWS {
+			if isUniqueID(principal) && !seen[principal] {
+				seen[principal] = true
+				deleted = append(deleted, principal)
+			}
+		}
+	}
+
+	return deleted
+}
+
+// isUniqueID reports whether s looks like the unique ID of an IAM user or
+// role, e.g. AROA1234567890EXAMPLE.
+func isUniqueID(s string) bool {
+	if len(s) < 16 || !(strings.HasPrefix(s, "AIDA") || strings.HasPrefix(s, "AROA")) {
+		return false
+	}
+
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AccountID extracts the account ID from an AWS principal, which may be a
+// bare ID or an ARN such as arn:aws:iam::123456789012:role/foo. It returns
+// "" if there is none.
+func AccountID(principal string) string {
+	if isAccountID(principal) {
+		return principal
+	}
+
+	parts := strings.SplitN(principal, ":", 6)
+	if len(parts) == 6 && parts[0] == "arn" && isAccountID(parts[4]) {
+		return parts[4]
+	}
+
+	return ""
+}
+
+func isAccountID(s string) bool {
+	if len(s) != 12 {
+		return false
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
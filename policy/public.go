@@ -0,0 +1,267 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// fixedConditionKeys are the condition keys that S3 accepts as limiting a
+// wildcard principal to a fixed set of callers. Keys are lower case as
+// condition keys are case-insensitive.
+var fixedConditionKeys = map[string]bool{
+	"aws:principalorgid":   true,
+	"aws:principalaccount": true,
+	"aws:principalarn":     true,
+	"aws:sourceip":         true,
+	"aws:sourcearn":        true,
+	"aws:sourcevpc":        true,
+	"aws:sourcevpce":       true,
+	"aws:sourceowner":      true,
+	"aws:sourceaccount":    true,
+	"aws:userid":           true,
+	"s3:x-amz-server-side-encryption-aws-kms-key-id": true,
+	"s3:dataaccesspointarn":                          true,
+	"s3:dataaccesspointaccount":                      true,
+	"kms:calleraccount":                              true, // for KMS key policies
+}
+
+// roleUserID matches the "AROLEID:*" pattern S3 permits for aws:userid.
+var roleUserID = regexp.MustCompile(`^[A-Z0-9]+:\*$`)
+
+// IsPublic reports whether any statement grants access to a non-fixed set of
+// principals.
+func (p *Policy) IsPublic() bool {
+	return len(p.PublicStatements()) > 0
+}
+
+// PublicStatements returns the statements that make the policy public.
+func (p *Policy) PublicStatements() []Statement {
+	stmts := []Statement{}
+	for _, stmt := range p.Statement {
+		if stmt.IsPublic() {
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	return stmts
+}
+
+// writeActions are the actions that let a public grant change or destroy
+// data.
+var writeActions = []string{
+	"s3:PutObject",
+	"s3:PutObjectAcl",
+	"s3:DeleteObject",
+	"s3:DeleteObjectVersion",
+	"s3:PutBucketPolicy",
+	"s3:PutBucketAcl",
+	"s3:DeleteBucket",
+}
+
+// IsPublicWrite reports whether a public statement allows writing or
+// deleting. NotAction is assumed to leave some write action allowed.
+func (p *Policy) IsPublicWrite() bool {
+	for _, stmt := range p.PublicStatements() {
+		if len(stmt.NotAction) > 0 || stmt.allowsAction(writeActions...) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsPublic reports whether the statement allows access to anyone. Deny
+// statements never make a policy public, and Allow with NotPrincipal grants
+// access to everybody not listed.
+func (s Statement) IsPublic() bool {
+	if !s.IsAllow() {
+		return false
+	}
+
+	if s.NotPrincipal != nil {
+		return true
+	}
+
+	return s.HasWildcardPrincipal() && !s.Condition.RestrictsToFixedValues()
+}
+
+// HasWildcardPrincipal reports whether the principal matches non-fixed
+// values, regardless of any conditions.
+func (s Statement) HasWildcardPrincipal() bool {
+	if s.Principal == nil {
+		return false
+	}
+
+	if s.Principal.All {
+		return true
+	}
+
+	for _, v := range s.Principal.Principals() {
+		if !isFixed(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RestrictsToFixedValues reports whether at least one condition limits
+// access to fixed values of a key S3 recognises.
+func (c Conditions) RestrictsToFixedValues() bool {
+	for op, keys := range c {
+		if !isRestrictingOperator(op) {
+			continue
+		}
+
+		for key, values := range keys {
+			if restrictsKey(key, values) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isRestrictingOperator reports whether op only matches the listed values.
+// Negated operators, ...IfExists (passes when the key is absent) and
+// ForAllValues (passes for an empty set) don't restrict anything.
+func isRestrictingOperator(op string) bool {
+	op = strings.ToLower(op)
+
+	if strings.HasSuffix(op, "ifexists") || strings.HasPrefix(op, "forallvalues:") {
+		return false
+	}
+
+	switch strings.TrimPrefix(op, "foranyvalue:") {
+	case "stringequals", "stringequalsignorecase", "stringlike", "arnequals", "arnlike", "ipaddress":
+		return true
+	default:
+		return false
+	}
+}
+
+func restrictsKey(key string, values StringList) bool {
+	key = strings.ToLower(key)
+	if !fixedConditionKeys[key] || len(values) == 0 {
+		return false
+	}
+
+	for _, v := range values {
+		switch {
+		case key == "aws:sourceip" && !isFixedCIDR(v):
+			return false
+		case key == "aws:userid" && roleUserID.MatchString(v):
+			continue
+		case !isFixed(v):
+			return false
+		}
+	}
+
+	return true
+}
+
+// isFixed reports whether v contains no wildcards or policy variables.
+func isFixed(v string) bool {
+	return !strings.ContainsAny(v, "*?") && !strings.Contains(v, "${")
+}
+
+// isFixedCIDR follows Block Public Access, which treats IPv4 ranges wider
+// than /8 and IPv6 ranges wider than /32 as public.
+func isFixedCIDR(v string) bool {
+	if !strings.Contains(v, "/") {
+		return net.ParseIP(v) != nil
+	}
+
+	_, network, err := net.ParseCIDR(v)
+	if err != nil {
+		return false
+	}
+
+	ones, bits := network.Mask.Size()
+	if bits == 32 {
+		return ones >= 8
+	}
+	return ones >= 32
+}
+
+// Mitigation is a condition that narrows who a wildcard-principal statement
+// applies to.
+type Mitigation struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+	Narrow bool     `json:"narrow"` // tight enough that the grant is not meaningfully public
+}
+
+func (m Mitigation) String() string {
+	return fmt.Sprintf("%s in [%s]", m.Key, strings.Join(m.Values, ", "))
+}
+
+// mitigatingKeys are the condition keys reported as mitigations, keyed by
+// lower case name.
+var mitigatingKeys = map[string]string{
+	"aws:sourcevpce":       "aws:SourceVpce",
+	"aws:sourcevpc":        "aws:SourceVpc",
+	"aws:sourceip":         "aws:SourceIp",
+	"aws:principalorgid":   "aws:PrincipalOrgID",
+	"aws:principalaccount": "aws:PrincipalAccount",
+	"aws:sourceaccount":    "aws:SourceAccount",
+	"aws:sourcearn":        "aws:SourceArn",
+}
+
+// Mitigations returns the conditions that narrow access for a statement with
+// a wildcard principal.
+func (s Statement) Mitigations() []Mitigation {
+	if !s.IsAllow() || !s.HasWildcardPrincipal() {
+		return nil
+	}
+
+	mitigations := []Mitigation{}
+	for op, keys := range s.Condition {
+		if !isRestrictingOperator(op) {
+			continue
+		}
+
+		for key, values := range keys {
+			name, ok := mitigatingKeys[strings.ToLower(key)]
+			if !ok || !restrictsKey(key, values) {
+				continue
+			}
+
+			narrow := true
+			if name == "aws:SourceIp" {
+				narrow = areNarrowCIDRs(values)
+			}
+
+			mitigations = append(mitigations, Mitigation{Key: name, Values: values, Narrow: narrow})
+		}
+	}
+
+	sort.Slice(mitigations, func(i, j int) bool { return mitigations[i].Key < mitigations[j].Key })
+	return mitigations
+}
+
+// areNarrowCIDRs reports whether every range is at most a /16 (IPv4) or /48
+// (IPv6). Anything wider is closer to "the internet" than "our office".
+func areNarrowCIDRs(values []string) bool {
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(v)
+		if err != nil {
+			return false
+		}
+
+		ones, bits := network.Mask.Size()
+		if (bits == 32 && ones < 16) || (bits == 128 && ones < 48) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,176 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Template is an approved bucket policy. Values may contain the placeholders
+// {{bucket}} and {{account}}, which are replaced with the bucket name and
+// account ID before comparison, and {{any}}, which matches anything.
+type Template struct {
+	Name   string
+	Policy *Policy
+}
+
+// Deviation is a difference between a bucket policy and a template.
+type Deviation struct {
+	Sid    string `json:"sid,omitempty"`
+	Kind   string `json:"kind"` // "added", "missing" or "modified"
+	Actual string `json:"actual,omitempty"`
+}
+
+func (d Deviation) String() string {
+	sid := sidLabel(d.Sid)
+
+	switch d.Kind {
+	case "added":
+		return fmt.Sprintf("statement %s is not in the template: %s", sid, d.Actual)
+	case "missing":
+		return fmt.Sprintf("statement %s from the template is missing", sid)
+	default:
+		return fmt.Sprintf("statement %s differs from the template: %s", sid, d.Actual)
+	}
+}
+
+func ParseTemplate(name string, doc string) (Template, error) {
+	p, err := Parse(doc)
+	if err != nil {
+		return Template{}, fmt.Errorf("template %s: %w", name, err)
+	}
+
+	return Template{Name: name, Policy: p}, nil
+}
+
+// Applies reports whether the policy looks like it was derived from the
+// template, i.e. at least one statement shares a Sid or matches outright.
+func (t Template) Applies(p *Policy, bucket string, account string) bool {
+	for _, stmt := range p.Statement {
+		for _, tmpl := range t.Policy.Statement {
+			if stmt.Sid != "" && stmt.Sid == tmpl.Sid {
+				return true
+			}
+
+			if matches(tmpl, stmt, bucket, account) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Diff lists how the policy deviates from the template. Statements are
+// paired by Sid where possible and otherwise by content.
+func (t Template) Diff(p *Policy, bucket string, account string) []Deviation {
+	deviations := []Deviation{}
+	matched := make([]bool, len(t.Policy.Statement))
+
+	for _, stmt := range p.Statement {
+		found := false
+		for i, tmpl := range t.Policy.Statement {
+			if !matched[i] && matches(tmpl, stmt, bucket, account) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		kind := "added"
+		for i, tmpl := range t.Policy.Statement {
+			if !matched[i] && stmt.Sid != "" && stmt.Sid == tmpl.Sid {
+				matched[i], kind = true, "modified"
+				break
+			}
+		}
+
+		deviations = append(deviations, Deviation{Sid: stmt.Sid, Kind: kind, Actual: canonical(stmt)})
+	}
+
+	for i, tmpl := range t.Policy.Statement {
+		if !matched[i] {
+			deviations = append(deviations, Deviation{Sid: tmpl.Sid, Kind: "missing"})
+		}
+	}
+
+	return deviations
+}
+
+// matches compares statements by their canonical JSON, with the template's
+// placeholders filled in.
+func matches(tmpl Statement, stmt Statement, bucket string, account string) bool {
+	pattern := regexp.QuoteMeta(canonical(tmpl))
+	pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta("{{bucket}}"), regexp.QuoteMeta(bucket))
+	pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta("{{account}}"), regexp.QuoteMeta(account))
+	pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta("{{any}}"), `[^"]*`)
+
+	re, err := regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return false
+	}
+
+	return re.MatchString(canonical(stmt))
+}
+
+// canonical renders a statement as JSON with list values sorted and
+// condition keys lower cased, so equivalent statements compare equal. The
+// Sid is dropped as it doesn't affect what is granted.
+func canonical(stmt Statement) string {
+	stmt.Sid = ""
+	if stmt.IsAllow() {
+		stmt.Effect = "Allow"
+	} else {
+		stmt.Effect = "Deny"
+	}
+	stmt.Action = sorted(stmt.Action)
+	stmt.NotAction = sorted(stmt.NotAction)
+	stmt.Resource = sorted(stmt.Resource)
+	stmt.NotResource = sorted(stmt.NotResource)
+
+	if stmt.Condition != nil {
+		conditions := Conditions{}
+		for op, keys := range stmt.Condition {
+			lowered := map[string]StringList{}
+			for key, values := range keys {
+				lowered[strings.ToLower(key)] = sorted(values)
+			}
+			conditions[strings.ToLower(op)] = lowered
+		}
+		stmt.Condition = conditions
+	}
+
+	stmt.Principal = sortedPrincipal(stmt.Principal)
+	stmt.NotPrincipal = sortedPrincipal(stmt.NotPrincipal)
+
+	data, _ := json.Marshal(stmt)
+	return string(data)
+}
+
+func sortedPrincipal(p *Principal) *Principal {
+	if p == nil {
+		return nil
+	}
+
+	return &Principal{
+		All:           p.All,
+		AWS:           sorted(p.AWS),
+		Service:       sorted(p.Service),
+		Federated:     sorted(p.Federated),
+		CanonicalUser: sorted(p.CanonicalUser),
+	}
+}
+
+func sorted(l StringList) StringList {
+	if l == nil {
+		return nil
+	}
+
+	l = append(StringList{}, l...)
+	sort.Strings(l)
+	return l
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// preflightMain checks that the credentials for each account allow every
+// action the selected checks need, before a long scan finds out the hard
+// way.
+func preflightMain(args []string) {
+	fs := flag.NewFlagSet("preflight", flag.ExitOnError)
+	profiles := fs.String("profiles", "deployTools", "comma-separated AWS profiles (one per account) to check")
+	onlyChecks := fs.String("checks", "", "comma-separated checks to check permissions for; all if empty")
+	skipChecks := fs.String("skip-checks", "", "comma-separated checks not to check permissions for")
+	endpointURL := fs.String("endpoint-url", "", "send AWS requests to this URL instead")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit preflight [-profiles profiles] [-checks checks]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	check(setEndpoints(*endpointURL, nil), "invalid endpoint")
+
+	sel, err := newCheckSelection(*onlyChecks, *skipChecks, nil)
+	check(err, "invalid checks")
+	actions := sel.permissions()
+
+	ctx := context.Background()
+	failed := false
+	for _, profile := range strings.Split(*profiles, ",") {
+		principal, denied, err := deniedActions(ctx, profile, actions)
+		switch {
+		case err != nil:
+			fmt.Printf("%s: FAILED (%v)\n", profile, err)
+			failed = true
+		case len(denied) > 0:
+			fmt.Printf("%s: %s is denied %d of %d actions:\n", profile, principal, len(denied), len(actions))
+			for _, action := range denied {
+				fmt.Printf("  %s\n", action)
+			}
+			failed = true
+		default:
+			fmt.Printf("%s: OK, %s is allowed all %d actions\n", profile, principal, len(actions))
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// deniedActions simulates the profile's principal's IAM policies, returning
+// the principal and those of the actions it isn't allowed. Bucket policies
+// and SCPs can still deny what this allows.
+func deniedActions(ctx context.Context, profile string, actions []string) (string, []string, error) {
+	config, err := loadConfig(ctx, profile)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to load AWS config: %w", err)
+	}
+
+	identity, err := sts.NewFromConfig(config).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to get caller identity: %w", err)
+	}
+
+	client := iam.NewFromConfig(config)
+	principal, err := principalARN(ctx, client, aws.ToString(identity.Arn))
+	if err != nil {
+		return "", nil, err
+	}
+
+	denied := []string{}
+	paginator := iam.NewSimulatePrincipalPolicyPaginator(client, &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(principal),
+		ActionNames:     actions,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return principal, nil, fmt.Errorf("unable to simulate policies: %w", err)
+		}
+
+		for _, result := range page.EvaluationResults {
+			if result.EvalDecision != iamtypes.PolicyEvaluationDecisionTypeAllowed {
+				denied = append(denied, aws.ToString(result.EvalActionName))
+			}
+		}
+	}
+
+	return principal, denied, nil
+}
+
+// principalARN returns the IAM user or role behind the caller's ARN. For an
+// assumed role, it is looked up for its path, which the session ARN lacks.
+func principalARN(ctx context.Context, client *iam.Client, caller string) (string, error) {
+	parts := strings.Split(caller, ":")
+	if len(parts) != 6 {
+		return "", fmt.Errorf("invalid caller ARN %s", caller)
+	}
+
+	resource := parts[5]
+	switch {
+	case strings.HasPrefix(resource, "user/"):
+		return caller, nil
+	case strings.HasPrefix(resource, "assumed-role/"):
+		name, _, _ := strings.Cut(strings.TrimPrefix(resource, "assumed-role/"), "/")
+		role, err := client.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(name)})
+		if err != nil {
+			// Without iam:GetRole, assume the role has no path.
+			return fmt.Sprintf("arn:%s:iam::%s:role/%s", parts[1], parts[4], name), nil
+		}
+		return aws.ToString(role.Role.Arn), nil
+	default:
+		return "", errors.New("can only check the permissions of IAM users and roles, not " + caller)
+	}
+}
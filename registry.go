@@ -0,0 +1,238 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// checkInfo describes a built-in check for list-checks and -checks.
+type checkInfo struct {
+	ID          string
+	Description string
+	Severity    string   // the severity findings are raised at
+	Permissions []string // IAM actions needed beyond scanPermissions
+}
+
+// registry lists every built-in check, in report order. The last six
+// aren't run per bucket: five are account-level, and replication compares
+// buckets across the accounts scanned.
+var registry = []checkInfo{
+	{"public-access", "Bucket readable by anyone, by probe, Access Analyzer or policy", "LOW to CRITICAL, scored",
+		slices.Concat(exposurePermissions, []string{"s3:GetBucketAcl", "s3:GetBucketPublicAccessBlock", "macie2:ListFindings", "macie2:GetFindings", "cloudwatch:ListMetrics", "cloudwatch:GetMetricData", "s3:ListStorageLensConfigurations", "s3:GetStorageLensConfiguration"})},
+	{"config-disagreement", "AWS Config public-access rules disagree with what was found", "LOW or MEDIUM",
+		slices.Concat(exposurePermissions, []string{"config:DescribeConfigRules", "config:GetComplianceDetailsByConfigRule", "s3:GetBucketAcl"})},
+	{"trusted-advisor-disagreement", "Trusted Advisor bucket permissions check disagrees with what was found", "LOW or MEDIUM",
+		slices.Concat(exposurePermissions, []string{"support:DescribeTrustedAdvisorChecks", "support:DescribeTrustedAdvisorCheckResult"})},
+	{"cloudfront-origin", "Public bucket serving a CloudFront distribution without origin access control", "MEDIUM",
+		slices.Concat(exposurePermissions, []string{"cloudfront:ListDistributions"})},
+	{"broad-actions", "Policy grants broad actions such as s3:* to wide principals", "MEDIUM",
+		[]string{"s3:GetBucketPolicy"}},
+	{"confused-deputy", "Policy grants a service principal without a source account or ARN condition", "MEDIUM",
+		[]string{"s3:GetBucketPolicy"}},
+	{"negated-elements", "Policy uses NotPrincipal, NotAction or NotResource with Allow", "MEDIUM",
+		[]string{"s3:GetBucketPolicy"}},
+	{"policy-complexity", "Policy is large or complicated enough to be hard to review", "LOW",
+		[]string{"s3:GetBucketPolicy"}},
+	{"policy-drift", "Policy doesn't match any approved template (with -templates)", "LOW",
+		[]string{"s3:GetBucketPolicy"}},
+	{"dangling-principals", "Policy grants deleted principals, or closed or unknown accounts (with -org-profile)", "LOW to HIGH",
+		[]string{"s3:GetBucketPolicy", "organizations:ListAccounts"}},
+	{"data-events", "Sensitive bucket has no CloudTrail data event logging", "MEDIUM",
+		[]string{"cloudtrail:DescribeTrails", "cloudtrail:GetEventSelectors", "cloudtrail:GetTrailStatus"}},
+	{"log-bucket", "Bucket receiving server access, CloudTrail or load balancer logs is public, grants log delivery without source conditions, or has ACLs enabled", "MEDIUM to CRITICAL",
+		slices.Concat(exposurePermissions, []string{"s3:GetBucketLogging", "cloudtrail:DescribeTrails", "elasticloadbalancing:DescribeLoadBalancers", "elasticloadbalancing:DescribeLoadBalancerAttributes", "s3:GetBucketAcl"})},
+	{"state-bucket", "Terraform, CloudFormation or CDK state bucket has no default encryption; its public exposure is raised to CRITICAL", "CRITICAL",
+		[]string{"s3:ListBucket", "s3:GetEncryptionConfiguration"}},
+	{"notification-targets", "Event notifications go to another account, or to a topic, queue or function that no longer exists", "MEDIUM",
+		[]string{"s3:GetBucketNotification", "sns:GetTopicAttributes", "sqs:GetQueueUrl", "lambda:GetFunction"}},
+	{"requester-pays", "Requester Pays is on for a bucket open to anonymous access, or off for one shared with unknown accounts", "LOW or MEDIUM",
+		[]string{"s3:GetBucketRequestPayment", "s3:GetBucketPolicy", "s3:GetBucketAcl"}},
+	{"object-lock", "Bucket tagged for compliance or archive data lacks Object Lock with the required retention mode and period", "MEDIUM or HIGH",
+		[]string{"s3:GetBucketObjectLockConfiguration", "s3:GetBucketTagging"}},
+	{"kms-key-policy", "Default encryption key isn't allowed, or its policy grants access to anyone or other accounts", "MEDIUM or HIGH",
+		[]string{"s3:GetEncryptionConfiguration", "kms:DescribeKey", "kms:GetKeyPolicy"}},
+	{"public-objects", "S3 Inventory shows objects with public ACLs (with -inventory)", "HIGH",
+		[]string{"s3:GetInventoryConfiguration", "s3:GetBucketLocation", "s3:ListBucket", "s3:GetObject", "s3:GetBucketPublicAccessBlock"}},
+	{"unused-buckets", "No requests or object writes in the period, a decommission candidate (advisory)", "LOW",
+		[]string{"s3:GetMetricsConfiguration", "s3:ListBucket", "cloudwatch:GetMetricData"}},
+	{"storage-cost", "Large bucket in S3 Standard with no lifecycle transitions or Intelligent-Tiering (advisory)", "LOW",
+		[]string{"cloudwatch:ListMetrics", "cloudwatch:GetMetricData", "s3:GetLifecycleConfiguration", "s3:GetIntelligentTieringConfiguration"}},
+	{"transfer-acceleration", "Transfer Acceleration is enabled, or its endpoint allows anonymous reads", "LOW, HIGH or CRITICAL",
+		slices.Concat(exposurePermissions, []string{"s3:GetAccelerateConfiguration"})},
+	{"typosquatting", "Lookalike names of public buckets, or those listed, are registered outside the account", "MEDIUM",
+		exposurePermissions},
+	{"guardduty-s3-protection", "GuardDuty or its S3 Protection is off in a region with buckets", "MEDIUM",
+		[]string{"guardduty:ListDetectors", "guardduty:GetDetector", "guardduty:ListFindings", "guardduty:GetFindings"}},
+	{"object-lambda-access-point", "Object Lambda access point is public or bypasses its bucket's controls", "MEDIUM or HIGH",
+		[]string{"s3:ListAccessPointsForObjectLambda", "s3:GetAccessPointConfigurationForObjectLambda", "s3:GetAccessPointPolicyForObjectLambda", "s3:GetAccessPointPolicyStatusForObjectLambda", "s3:GetAccessPoint", "s3:GetAccessPointPolicy", "s3:GetAccessPointPolicyStatus"}},
+	{"dangling-bucket-reference", "Route 53 record or CloudFront distribution points at a bucket that doesn't exist", "HIGH",
+		[]string{"route53:ListHostedZones", "route53:ListResourceRecordSets", "cloudfront:ListDistributions"}},
+	{"identity-policy", "IAM role, user or group policy allows reading objects in every bucket, or in buckets outside the account", "LOW to HIGH",
+		[]string{"iam:GetAccountAuthorizationDetails"}},
+	{"vpc-endpoint-policy", "S3 VPC endpoint policy lets requests through to any bucket, including other accounts'", "LOW or MEDIUM",
+		[]string{"ec2:DescribeVpcEndpoints"}},
+	{"replication", "Replica is public, unencrypted or otherwise less protected than its source bucket, across the accounts scanned", "MEDIUM to CRITICAL",
+		slices.Concat(exposurePermissions, []string{"s3:GetReplicationConfiguration", "s3:GetEncryptionConfiguration", "s3:GetBucketPublicAccessBlock", "s3:GetBucketObjectLockConfiguration"})},
+}
+
+// s3CompatibleChecks are the built-in checks that apply to S3-compatible
+// stores such as MinIO and Ceph; the others need AWS services or features
+// they don't have.
+var s3CompatibleChecks = []string{"public-access", "broad-actions", "negated-elements", "policy-complexity", "policy-drift", "state-bucket", "object-lock"}
+
+// offlineChecks are the built-in checks that run on buckets recorded in AWS
+// Config snapshots, needing only the configuration Config records. Without
+// the probe, public-access goes by the policy alone.
+var offlineChecks = []string{"public-access", "broad-actions", "confused-deputy", "negated-elements", "policy-complexity", "policy-drift", "dangling-principals", "requester-pays", "transfer-acceleration"}
+
+// exposureChecks are the built-in checks that go by whether a bucket is
+// public, which takes the probe, Access Analyzer and the policy to tell.
+var exposureChecks = []string{"public-access", "config-disagreement", "trusted-advisor-disagreement", "cloudfront-origin", "log-bucket", "transfer-acceleration", "typosquatting", "replication"}
+
+// exposurePermissions are the IAM actions each of exposureChecks needs to
+// tell whether a bucket is public.
+var exposurePermissions = []string{"s3:GetBucketPolicy", "s3:PutObject", "s3:DeleteObject", "access-analyzer:ListAnalyzers", "access-analyzer:ListFindings"}
+
+// policyChecks are the built-in checks that read the bucket policy besides
+// those in exposureChecks.
+var policyChecks = []string{"broad-actions", "confused-deputy", "negated-elements", "policy-complexity", "policy-drift", "dangling-principals", "requester-pays"}
+
+// scanPermissions are the IAM actions every scan needs, to list buckets,
+// read the tags that identify their owners and the Object Ownership setting
+// for the ACL report. sts:GetCallerIdentity is always allowed.
+var scanPermissions = []string{"s3:ListAllMyBuckets", "s3:GetBucketTagging", "s3:GetBucketOwnershipControls"}
+
+// sweepPermissions are the IAM actions s3-audit sweep needs, besides
+// iam:PassRole for the role it gives S3 Batch Operations.
+var sweepPermissions = []string{"s3:GetBucketLocation", "s3:GetInventoryConfiguration", "s3:GetObject", "s3:PutObject", "s3:CreateJob", "s3:DescribeJob"}
+
+// checkSelection is the checks chosen with -checks and -skip-checks.
+type checkSelection struct {
+	only []string // run just these, if any
+	skip []string
+
+	s3Compatible bool // only s3CompatibleChecks of the built-in checks
+	offline      bool // only offlineChecks of the built-in checks
+}
+
+// newCheckSelection parses the comma-separated flag values. Names must be
+// built-in checks or one of custom, the names of configured rules and
+// plugins.
+func newCheckSelection(only string, skip string, custom []string) (checkSelection, error) {
+	sel := checkSelection{only: splitList(only), skip: splitList(skip)}
+
+	for _, id := range slices.Concat(sel.only, sel.skip) {
+		known := slices.ContainsFunc(registry, func(c checkInfo) bool { return c.ID == id })
+		if !known && !slices.Contains(custom, id) {
+			return sel, fmt.Errorf("unknown check %q (see s3-audit list-checks)", id)
+		}
+	}
+
+	return sel, nil
+}
+
+func (s checkSelection) enabled(id string) bool {
+	builtin := slices.ContainsFunc(registry, func(c checkInfo) bool { return c.ID == id })
+	if builtin && s.s3Compatible && !slices.Contains(s3CompatibleChecks, id) {
+		return false
+	}
+	if builtin && s.offline && !slices.Contains(offlineChecks, id) {
+		return false
+	}
+	if len(s.only) > 0 && !slices.Contains(s.only, id) {
+		return false
+	}
+
+	return !slices.Contains(s.skip, id)
+}
+
+// permissions returns the IAM actions the selected checks need, sorted.
+func (s checkSelection) permissions() []string {
+	actions := slices.Clone(scanPermissions)
+	for _, c := range registry {
+		if s.enabled(c.ID) {
+			actions = append(actions, c.Permissions...)
+		}
+	}
+
+	slices.Sort(actions)
+	return slices.Compact(actions)
+}
+
+// newReportSelection parses -only-check. Rego rules and plugins name the
+// checks of their findings themselves, so with either configured a name
+// that isn't a known check may still be one of theirs, and is allowed.
+func newReportSelection(only string, custom []string, open bool) (checkSelection, error) {
+	if open {
+		return checkSelection{only: splitList(only)}, nil
+	}
+
+	return newCheckSelection(only, "", custom)
+}
+
+// reports reports whether the finding is from a selected check, by its own
+// check name or that of the custom rule or plugin reporting it.
+func (s checkSelection) reports(f Finding) bool {
+	if f.Source == "" || f.Source == f.Check {
+		return s.enabled(f.Check)
+	}
+	if slices.Contains(s.skip, f.Check) || slices.Contains(s.skip, f.Source) {
+		return false
+	}
+
+	return len(s.only) == 0 || slices.Contains(s.only, f.Check) || slices.Contains(s.only, f.Source)
+}
+
+// selected returns the findings from the selected checks.
+func (s checkSelection) selected(findings []Finding) []Finding {
+	filtered := []Finding{}
+	for _, f := range findings {
+		if s.reports(f) {
+			filtered = append(filtered, f)
+		}
+	}
+
+	return filtered
+}
+
+func splitList(s string) []string {
+	values := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// listChecksMain implements the list-checks subcommand.
+func listChecksMain(args []string) {
+	fs := flag.NewFlagSet("list-checks", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit list-checks")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	printChecks(os.Stdout)
+}
+
+func printChecks(w io.Writer) {
+	for _, c := range registry {
+		fmt.Fprintf(w, "%-30s %s\n", c.ID, c.Description)
+		fmt.Fprintf(w, "%-30s severity: %s\n", "", c.Severity)
+		permissions := "none"
+		if len(c.Permissions) > 0 {
+			permissions = strings.Join(c.Permissions, ", ")
+		}
+		fmt.Fprintf(w, "%-30s permissions: %s\n", "", permissions)
+		if params := describeCheckParams(c.ID); params != "" {
+			fmt.Fprintf(w, "%-30s params: %s\n", "", params)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestCheckSelectionReports(t *testing.T) {
+	builtin := Finding{Check: "public-access"}
+	rego := Finding{Check: "prod-kms", Source: "rego"}
+	expression := Finding{Check: "tagged", Source: "tagged"}
+	plugin := Finding{Check: "naming", Source: "naming-standard"}
+
+	tests := []struct {
+		only string
+		want []Finding
+	}{
+		{"", []Finding{builtin, rego, expression, plugin}},
+		{"public-access", []Finding{builtin}},
+		{"rego", []Finding{rego}},
+		{"prod-kms", []Finding{rego}},
+		{"tagged", []Finding{expression}},
+		{"naming-standard", []Finding{plugin}},
+		{"naming,public-access", []Finding{builtin, plugin}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.only, func(t *testing.T) {
+			sel, err := newReportSelection(tt.only, []string{"rego", "tagged", "naming-standard"}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := sel.selected([]Finding{builtin, rego, expression, plugin})
+			if len(got) != len(tt.want) {
+				t.Fatalf("selected() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Check != tt.want[i].Check {
+					t.Errorf("selected()[%d] = %s, want %s", i, got[i].Check, tt.want[i].Check)
+				}
+			}
+		})
+	}
+}
+
+func TestNewReportSelection(t *testing.T) {
+	if _, err := newReportSelection("prod-kms", []string{"tagged"}, false); err == nil {
+		t.Error("unknown check allowed without Rego rules or plugins")
+	}
+	if _, err := newReportSelection("prod-kms", []string{"rego"}, true); err != nil {
+		t.Errorf("check a Rego rule may report rejected: %v", err)
+	}
+	if _, err := newReportSelection("tagged,public-access", []string{"tagged"}, false); err != nil {
+		t.Errorf("known checks rejected: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/guardian/s3-audit/awsapi"
+)
+
+// replicationRule is an enabled rule replicating the bucket's objects to
+// another bucket.
+type replicationRule struct {
+	ID          string `json:"id"`
+	Destination string `json:"destination"` // bucket name
+	Account     string `json:"account"`     // destination account ID, if the rule gives one
+}
+
+// getReplication returns the bucket's enabled replication rules.
+func getReplication(ctx context.Context, client awsapi.S3, bucketName string, region string) ([]replicationRule, error) {
+	out, err := client.GetBucketReplication(ctx, &s3.GetBucketReplicationInput{Bucket: &bucketName}, inRegion(region))
+	if isErrorCode(err, "ReplicationConfigurationNotFoundError") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []replicationRule{}
+	for _, rule := range out.ReplicationConfiguration.Rules {
+		if rule.Status != s3types.ReplicationRuleStatusEnabled || rule.Destination == nil {
+			continue
+		}
+
+		rules = append(rules, replicationRule{
+			ID:          aws.ToString(rule.ID),
+			Destination: strings.TrimPrefix(aws.ToString(rule.Destination.Bucket), "arn:aws:s3:::"),
+			Account:     aws.ToString(rule.Destination.Account),
+		})
+	}
+
+	return rules, nil
+}
+
+// bucketProtections are the protections a replica should keep if its source
+// has them.
+type bucketProtections struct {
+	Account       string `json:"account"`
+	AccountID     string `json:"accountId"`
+	Region        string `json:"region"`
+	Public        bool   `json:"public"`
+	AccessBlocked bool   `json:"accessBlocked"` // every public access block setting is on
+	Encrypted     bool   `json:"encrypted"`
+	KMS           bool   `json:"kms"`
+	ObjectLock    bool   `json:"objectLock"`
+}
+
+func protectionsOf(facts *BucketFacts) bucketProtections {
+	pab := facts.PublicAccessBlock
+	return bucketProtections{
+		Account:       facts.Account,
+		AccountID:     facts.AccountID,
+		Region:        facts.Region,
+		Public:        facts.Exposure.any(),
+		AccessBlocked: pab != nil && pab.BlockPublicAcls && pab.IgnorePublicAcls && pab.BlockPublicPolicy && pab.RestrictPublicBuckets,
+		Encrypted:     facts.Encryption != nil,
+		KMS:           facts.Encryption != nil && facts.Encryption.KMS,
+		ObjectLock:    facts.ObjectLock != nil,
+	}
+}
+
+type replicationEdge struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Account     string `json:"account"` // destination account ID, if the rule gives one
+	Rule        string `json:"rule"`
+}
+
+// replicationMap is where objects are replicated across the accounts
+// scanned, with the protections of every bucket scanned, so that replicas
+// can be compared with their sources once all the accounts are in. It is
+// kept in the checkpoint, so a resumed scan has the accounts already done.
+type replicationMap struct {
+	Buckets map[string]bucketProtections `json:"buckets"`
+	Edges   []replicationEdge            `json:"edges"`
+}
+
+func newReplicationMap() *replicationMap {
+	return &replicationMap{Buckets: map[string]bucketProtections{}, Edges: []replicationEdge{}}
+}
+
+func (m *replicationMap) add(facts *BucketFacts) {
+	m.Buckets[facts.Name] = protectionsOf(facts)
+	for _, rule := range facts.Replication {
+		m.Edges = append(m.Edges, replicationEdge{Source: facts.Name, Destination: rule.Destination, Account: rule.Account, Rule: rule.ID})
+	}
+}
+
+// findings compares each replica scanned with its source, reporting
+// protections the source has that the replica lacks, against the replica.
+func (m *replicationMap) findings() []Finding {
+	findings := []Finding{}
+	for _, e := range m.Edges {
+		src, ok := m.Buckets[e.Source]
+		dst, scanned := m.Buckets[e.Destination]
+		if !ok || !scanned {
+			continue
+		}
+
+		weaker := func(severity Severity, message string) {
+			findings = append(findings, Finding{
+				Account:   dst.Account,
+				AccountID: dst.AccountID,
+				Bucket:    e.Destination,
+				Check:     "replication",
+				Subject:   e.Source,
+				Severity:  severity,
+				Message:   fmt.Sprintf("replica of %s (%s) %s", e.Source, src.Account, message),
+				Details:   []string{fmt.Sprintf("replication rule %s of %s in %s", cmp.Or(e.Rule, "(no ID)"), e.Source, src.Region)},
+			})
+		}
+		switch {
+		case dst.Public && !src.Public:
+			weaker(SeverityCritical, "is public, though the source isn't")
+		case src.AccessBlocked && !dst.AccessBlocked:
+			weaker(SeverityMedium, "doesn't block public access, though the source does")
+		}
+		switch {
+		case src.Encrypted && !dst.Encrypted:
+			weaker(SeverityHigh, "has no default encryption, though the source has")
+		case src.KMS && !dst.KMS:
+			weaker(SeverityMedium, "isn't encrypted with KMS, though the source is")
+		}
+		if src.ObjectLock && !dst.ObjectLock {
+			weaker(SeverityMedium, "has no Object Lock, though the source has")
+		}
+	}
+
+	return findings
+}
+
+// printReplicationMap lists each bucket's replicas, noting those outside the
+// accounts scanned, whose protections couldn't be compared.
+func printReplicationMap(w io.Writer, m *replicationMap) {
+	if m == nil || len(m.Edges) == 0 {
+		fmt.Fprintln(w, "No replication between the buckets scanned.")
+		return
+	}
+
+	edges := slices.SortedFunc(slices.Values(m.Edges), func(a, b replicationEdge) int {
+		return cmp.Or(cmp.Compare(a.Source, b.Source), cmp.Compare(a.Destination, b.Destination))
+	})
+
+	fmt.Fprintln(w, "Replication")
+	for _, e := range edges {
+		src := m.Buckets[e.Source]
+		line := fmt.Sprintf("  %s/%s (%s) -> ", src.Account, e.Source, src.Region)
+		if dst, ok := m.Buckets[e.Destination]; ok {
+			line += fmt.Sprintf("%s/%s (%s)", dst.Account, e.Destination, dst.Region)
+		} else {
+			account := "the same account"
+			if e.Account != "" {
+				account = "account " + e.Account
+			}
+			line += fmt.Sprintf("%s in %s, not scanned", e.Destination, account)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
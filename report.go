@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// printReport writes findings as a table, optionally grouped under a heading
+// per owner. Grouped, the findings are streamed once per owner.
+func printReport(w io.Writer, findings findingStream, groupBy string) error {
+	cols := columns{}
+	groups := map[string]int{}
+	err := findings(func(f Finding) error {
+		cols.fit(f)
+		groups[ownerOrUnowned(f)]++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	printRow := func(f Finding) error {
+		cols.print(w, f)
+		return nil
+	}
+	if groupBy != "owner" {
+		return findings(printRow)
+	}
+
+	for _, owner := range slices.Sorted(maps.Keys(groups)) {
+		fmt.Fprintf(w, "\n== %s (%d findings) ==\n", owner, groups[owner])
+		err := findings.filter(func(f Finding) bool { return ownerOrUnowned(f) == owner })(printRow)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ownerOrUnowned(f Finding) string {
+	if f.Owner == "" {
+		return unowned
+	}
+
+	return f.Owner
+}
+
+// columns are the widths of the account, bucket and check columns, wide
+// enough for the longest value so that long bucket names don't break the
+// alignment.
+type columns struct {
+	account, bucket, check int
+}
+
+func columnsFor(findings []Finding) columns {
+	cols := columns{}
+	for _, f := range findings {
+		cols.fit(f)
+	}
+
+	return cols
+}
+
+// fit widens the columns to fit the finding.
+func (c *columns) fit(f Finding) {
+	c.account = max(c.account, len(f.Account))
+	c.bucket = max(c.bucket, len(f.Bucket))
+	c.check = max(c.check, len(f.Check))
+}
+
+// printFinding writes a finding on its own, not aligned with any others.
+func printFinding(w io.Writer, f Finding) {
+	columnsFor([]Finding{f}).print(w, f)
+}
+
+func (c columns) print(w io.Writer, f Finding) {
+	severity := fmt.Sprintf("%-8s", f.Severity)
+	if colorize(w) {
+		severity = severityColors[f.Severity] + severity + colorReset
+	}
+
+	fmt.Fprintf(w, "%s  %-*s  %-*s  %-*s  (%s)\n", severity, c.account, f.Account, c.bucket, f.Bucket, c.check, f.Check, f.Message)
+	for _, detail := range f.Details {
+		fmt.Fprintf(w, "\t\t%s\n", detail)
+	}
+	if f.Score > 0 {
+		fmt.Fprintf(w, "\t\tscore %d from %s\n", f.Score, strings.Join(f.Signals, ", "))
+	}
+	if f.Metadata != nil {
+		fmt.Fprintf(w, "\t\t%s\n", f.Metadata)
+	}
+	if f.Usage != nil {
+		fmt.Fprintf(w, "\t\t%s\n", f.Usage)
+	}
+	if len(f.Grantees) > 0 {
+		fmt.Fprintf(w, "\t\taccess granted to %s\n", strings.Join(f.Grantees, ", "))
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// reportFuncs are available to report templates in addition to the
+// text/template builtins.
+var reportFuncs = template.FuncMap{
+	"join":        strings.Join,
+	"upper":       strings.ToUpper,
+	"lower":       strings.ToLower,
+	"formatBytes": formatBytes,
+	"formatTime":  formatTime,
+	// atLeast filters findings to those of the given severity or above,
+	// e.g. {{range atLeast "HIGH" .Findings}}.
+	"atLeast": func(severity string, findings []Finding) ([]Finding, error) {
+		var threshold Severity
+		if err := threshold.UnmarshalText([]byte(severity)); err != nil {
+			return nil, err
+		}
+
+		return atLeast(findings, threshold), nil
+	},
+	// byOwner groups findings by owning team, e.g.
+	// {{range $team, $findings := byOwner .Findings}}.
+	"byOwner": func(findings []Finding) map[string][]Finding {
+		groups := map[string][]Finding{}
+		for _, f := range findings {
+			owner := f.Owner
+			if owner == "" {
+				owner = unowned
+			}
+			groups[owner] = append(groups[owner], f)
+		}
+		return groups
+	},
+}
+
+// loadReportTemplate parses a text/template rendered with the saved report
+// (see savedReport) as its data.
+func loadReportTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Funcs(reportFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid report template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+func printTemplateReport(w io.Writer, tmpl *template.Template, r savedReport) error {
+	return tmpl.Execute(w, r)
+}
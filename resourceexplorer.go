@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// discoveryResourceExplorer is the -discovery value that finds buckets with
+// Resource Explorer as well as ListBuckets.
+const discoveryResourceExplorer = "resource-explorer"
+
+// resourceExplorer calls the Resource Explorer API, a small JSON API of
+// which discovery needs two operations, signing requests with the SDK's
+// SigV4 signer.
+type resourceExplorer struct {
+	config aws.Config
+	region string
+}
+
+// call POSTs the input to the operation and decodes its output.
+func (re resourceExplorer) call(ctx context.Context, op string, in any, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	endpoint, ok, _ := endpoints.GetServiceBaseEndpoint(ctx, "Resource Explorer 2")
+	if !ok || endpoint == "" {
+		endpoint = fmt.Sprintf("https://resource-explorer-2.%s.amazonaws.com", re.region)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/"+op, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	creds, err := re.config.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get credentials: %w", err)
+	}
+	hash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "resource-explorer-2", re.region, time.Now()); err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &apiErr)
+		return fmt.Errorf("%s: %s %s: %s", op, resp.Status, resp.Header.Get("X-Amzn-ErrorType"), apiErr.Message)
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// aggregatorRegion returns the region of the account's aggregator index,
+// which searches every region, or "" if it has none.
+func (re resourceExplorer) aggregatorRegion(ctx context.Context) (string, error) {
+	var out struct {
+		Indexes []struct {
+			Region string
+		}
+	}
+	if err := re.call(ctx, "ListIndexes", map[string]string{"Type": "AGGREGATOR"}, &out); err != nil {
+		return "", err
+	}
+
+	if len(out.Indexes) == 0 {
+		return "", nil
+	}
+	return out.Indexes[0].Region, nil
+}
+
+// searchBuckets returns the buckets owned by the account in Resource
+// Explorer's default view, searching from the aggregator index if there is
+// one and otherwise only the config's region. A search returns at most 1000
+// resources, so more buckets than that is an error rather than a partial
+// list.
+func searchBuckets(ctx context.Context, config aws.Config, accountID string) ([]s3types.Bucket, error) {
+	re := resourceExplorer{config: config, region: config.Region}
+	region, err := re.aggregatorRegion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if region != "" {
+		re.region = region
+	}
+
+	in := map[string]any{"QueryString": "resourcetype:s3:bucket", "MaxResults": 1000}
+	buckets := []s3types.Bucket{}
+	for {
+		var out struct {
+			Resources []struct {
+				Arn             string
+				OwningAccountId string
+				Region          string
+			}
+			Count struct {
+				Complete bool
+			}
+			NextToken *string
+		}
+		if err := re.call(ctx, "Search", in, &out); err != nil {
+			return nil, err
+		}
+		if !out.Count.Complete {
+			return nil, fmt.Errorf("more than 1000 buckets in the %s view, so Resource Explorer can't list them all", re.region)
+		}
+
+		for _, r := range out.Resources {
+			name, ok := strings.CutPrefix(r.Arn, "arn:aws:s3:::")
+			if !ok || (accountID != "" && r.OwningAccountId != accountID) {
+				continue
+			}
+			buckets = append(buckets, s3types.Bucket{Name: aws.String(name), BucketRegion: aws.String(r.Region)})
+		}
+
+		if out.NextToken == nil {
+			return buckets, nil
+		}
+		in["NextToken"] = *out.NextToken
+	}
+}
+
+// discoverBuckets cross-checks the buckets ListBuckets listed, or failed to
+// list with listErr, against those Resource Explorer finds. Each bucket only
+// one of them found is recorded as an error: listed but not indexed suggests
+// a region without an index, and indexed but not listed a bucket deleted
+// since it was indexed. If either fails, the other's buckets are scanned.
+func discoverBuckets(ctx context.Context, as *accountScan, listed []s3types.Bucket, listErr error) ([]s3types.Bucket, error) {
+	indexed, err := searchBuckets(ctx, as.config, as.ID)
+	switch {
+	case err != nil && listErr != nil:
+		return nil, listErr
+	case err != nil:
+		as.fail("", fmt.Errorf("unable to search Resource Explorer: %w", err))
+		return listed, nil
+	case listErr != nil:
+		as.fail("", fmt.Errorf("unable to list buckets, scanning those Resource Explorer found: %w", listErr))
+		return indexed, nil
+	}
+
+	name := func(b s3types.Bucket) string { return aws.ToString(b.Name) }
+	found := func(buckets []s3types.Bucket, bucket string) bool {
+		return slices.ContainsFunc(buckets, func(b s3types.Bucket) bool { return name(b) == bucket })
+	}
+
+	for _, b := range listed {
+		if !found(indexed, name(b)) {
+			as.fail(name(b), fmt.Errorf("listed by ListBuckets but not found by Resource Explorer; is there an index in %s?", aws.ToString(b.BucketRegion)))
+		}
+	}
+	for _, b := range indexed {
+		if !found(listed, name(b)) {
+			as.fail(name(b), fmt.Errorf("found by Resource Explorer in %s but not listed by ListBuckets; deleted since it was indexed?", aws.ToString(b.BucketRegion)))
+		}
+	}
+
+	return listed, nil
+}
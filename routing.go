@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// Routing sends each team the findings on its own buckets, on top of the
+// outputs that receive every finding, e.g.
+//
+//	"routing": {
+//	  "tags": ["NotifySlack", "TeamEmail"],
+//	  "contacts": {
+//	    "dotcom@example.com": {"type": "sns", "topicARN": "arn:aws:sns:eu-west-1:123456789012:dotcom-alerts"},
+//	    "data-team": {"type": "slack", "webhookURL": "https://hooks.slack.com/services/..."}
+//	  },
+//	  "minSeverity": "HIGH"
+//	}
+//
+// A finding's contact is the value of the first of Tags its bucket has, or
+// otherwise its owning team (see owners). Contacts maps contacts to where
+// their findings go; a contact that is itself a Slack webhook URL or SNS
+// topic ARN needs no entry. Findings with no route only go to the outputs.
+type Routing struct {
+	Tags        []string          `json:"tags"`
+	Contacts    map[string]Output `json:"contacts"`
+	MinSeverity Severity          `json:"minSeverity"`
+}
+
+func (rt Routing) validate() error {
+	for contact, o := range rt.Contacts {
+		if err := o.validate(); err != nil {
+			return fmt.Errorf("routing contact %s: %w", contact, err)
+		}
+	}
+
+	return nil
+}
+
+// route returns the finding's contact and where to send it, if it has one.
+func (rt Routing) route(f Finding) (string, Output, bool) {
+	candidates := []string{}
+	if f.Metadata != nil {
+		for _, tag := range rt.Tags {
+			if v := f.Metadata.Tags[tag]; v != "" {
+				candidates = append(candidates, v)
+				break
+			}
+		}
+	}
+	if f.Owner != "" {
+		candidates = append(candidates, f.Owner)
+	}
+
+	for _, contact := range candidates {
+		if o, ok := rt.Contacts[contact]; ok {
+			return contact, o, true
+		}
+		switch {
+		case strings.HasPrefix(contact, "https://hooks.slack.com/"):
+			return contact, Output{Type: "slack", WebhookURL: contact}, true
+		case strings.HasPrefix(contact, "arn:aws:sns:"):
+			return contact, Output{Type: "sns", TopicARN: contact}, true
+		}
+	}
+
+	return "", Output{}, false
+}
+
+// routeFindings sends each contact a copy of the report with only their
+// findings, streamed once per contact. A failure doesn't stop the others
+// being sent.
+func routeFindings(ctx context.Context, rt Routing, r savedReport, findings findingStream) error {
+	if len(rt.Tags) == 0 && len(rt.Contacts) == 0 {
+		return nil
+	}
+
+	findings = findings.filter(func(f Finding) bool { return f.Severity >= rt.MinSeverity })
+	outputs := map[string]Output{}
+	counts := map[string]int{}
+	unrouted := 0
+	err := findings(func(f Finding) error {
+		contact, o, ok := rt.route(f)
+		if !ok {
+			unrouted++
+			return nil
+		}
+		outputs[contact] = o
+		counts[contact]++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log := component("routing")
+	errs := []error{}
+	for _, contact := range slices.Sorted(maps.Keys(counts)) {
+		routed := findings.filter(func(f Finding) bool {
+			to, _, _ := rt.route(f)
+			return to == contact
+		})
+		if err := sendOutputs(ctx, []Output{outputs[contact]}, r, routed); err != nil {
+			errs = append(errs, fmt.Errorf("routing to %s: %w", contact, err))
+			continue
+		}
+		log.Info("sent findings to their owner", "contact", contact, "findings", counts[contact])
+	}
+	if unrouted > 0 {
+		log.Info("findings with no owner contact were only sent to the outputs", "findings", unrouted)
+	}
+
+	return errors.Join(errs...)
+}
+
+// publishSNS publishes the summary of the report's findings to the topic,
+// for teams subscribed by email or chat.
+func publishSNS(ctx context.Context, profile string, topicARN string, r savedReport, findings findingStream) error {
+	config, err := loadConfig(ctx, profile)
+	if err != nil {
+		return fmt.Errorf("unable to load AWS config: %w", err)
+	}
+
+	parts := strings.SplitN(topicARN, ":", 6)
+	if len(parts) < 6 {
+		return fmt.Errorf("invalid topic ARN %s", topicARN)
+	}
+	client := sns.NewFromConfig(config, func(o *sns.Options) { o.Region = parts[3] })
+
+	counts, err := findings.severityCounts()
+	if err != nil {
+		return err
+	}
+	summary, err := snsSummary(r, counts, findings)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn: &topicARN,
+		Subject:  aws.String(fmt.Sprintf("S3 audit: %d findings", totalCount(counts))),
+		Message:  aws.String(summary),
+	})
+	return err
+}
+
+// maxSNSMessage is the most bytes SNS takes in a message.
+const maxSNSMessage = 256 * 1024
+
+// snsSummary is the number of findings per severity and as many of the
+// findings as fit in a message, most severe first, counting those left out.
+// The findings are streamed once per severity.
+func snsSummary(r savedReport, counts map[Severity]int, findings findingStream) (string, error) {
+	text := &strings.Builder{}
+	text.WriteString(slackSummary(r, counts, nil, 0, 0))
+
+	listed := 0
+	full := errors.New("message full")
+	for sev := SeverityCritical; sev >= SeverityLow; sev-- {
+		err := findings(func(f Finding) error {
+			if f.Severity != sev {
+				return nil
+			}
+			line := fmt.Sprintf("• %s/%s %s: %s\n", f.Account, f.Bucket, f.Check, f.Message)
+			// Leave room to say how many more there are.
+			if text.Len()+len(line) > maxSNSMessage-100 {
+				return full
+			}
+			text.WriteString(line)
+			listed++
+			return nil
+		})
+		if errors.Is(err, full) {
+			fmt.Fprintf(text, "and %d more, see the full report\n", totalCount(counts)-listed)
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return text.String(), nil
+}
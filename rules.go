@@ -0,0 +1,139 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+// ruleEngine evaluates teams' own Rego rules against bucket facts. Rules are
+// written in package s3audit and add violations to the deny set, either as
+// a message or as an object with msg and optional check, subject and
+// severity. The subject tells apart several violations of a check on a
+// bucket across scans; without it, the message does.
+//
+//	package s3audit
+//
+//	deny contains {"check": "prod-kms", "severity": "HIGH", "msg": msg} if {
+//		input.tags.Stage == "PROD"
+//		not input.encryption.kms
+//		msg := "PROD buckets must use KMS encryption"
+//	}
+type ruleEngine struct {
+	query rego.PreparedEvalQuery
+}
+
+type violation struct {
+	Check    string
+	Subject  string // the message if not given
+	Severity Severity
+	Message  string
+}
+
+// loadRules compiles every .rego file under dir.
+func loadRules(ctx context.Context, dir string) (*ruleEngine, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	onlyRego := func(_ string, info fs.FileInfo, _ int) bool {
+		return !info.IsDir() && filepath.Ext(info.Name()) != ".rego"
+	}
+
+	query, err := rego.New(
+		rego.Query("data.s3audit.deny"),
+		rego.Load([]string{dir}, onlyRego),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ruleEngine{query: query}, nil
+}
+
+func (e *ruleEngine) evaluate(ctx context.Context, facts *BucketFacts) ([]violation, error) {
+	results, err := e.query.Eval(ctx, rego.EvalInput(facts))
+	if err != nil {
+		return nil, err
+	}
+
+	violations := []violation{}
+	for _, result := range results {
+		for _, expr := range result.Expressions {
+			values, ok := expr.Value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("deny must be a set, got %T", expr.Value)
+			}
+
+			for _, value := range values {
+				v, err := toViolation(value)
+				if err != nil {
+					return nil, err
+				}
+				violations = append(violations, v)
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+func toViolation(value any) (violation, error) {
+	v := violation{Check: "rego", Severity: SeverityMedium}
+
+	switch value := value.(type) {
+	case string:
+		v.Message = value
+	case map[string]any:
+		msg, _ := value["msg"].(string)
+		if msg == "" {
+			return v, fmt.Errorf("deny object has no msg: %v", value)
+		}
+		v.Message = msg
+
+		if check, ok := value["check"].(string); ok {
+			v.Check = check
+		}
+		if subject, ok := value["subject"].(string); ok {
+			v.Subject = subject
+		}
+
+		if severity, ok := value["severity"].(string); ok {
+			if err := v.Severity.UnmarshalText([]byte(severity)); err != nil {
+				return v, err
+			}
+		}
+	default:
+		return v, fmt.Errorf("unsupported deny value %v", value)
+	}
+
+	return v, nil
+}
+
+func (r *ruleEngine) Name() string { return "rego" }
+
+// Run reports each violation as a finding under the check it names.
+func (r *ruleEngine) Run(ctx context.Context, facts *BucketFacts) []Finding {
+	findings, _ := r.runFallible(ctx, facts)
+	return findings
+}
+
+// runFallible is Run, also returning any error evaluating the rules. The
+// violations found before it are still reported.
+func (r *ruleEngine) runFallible(ctx context.Context, facts *BucketFacts) ([]Finding, error) {
+	violations, err := r.evaluate(ctx, facts)
+	if err != nil {
+		err = fmt.Errorf("unable to evaluate rules: %w", err)
+	}
+
+	findings := []Finding{}
+	for _, v := range violations {
+		findings = append(findings, Finding{Check: v.Check, Subject: cmp.Or(v.Subject, v.Message), Severity: v.Severity, Message: v.Message})
+	}
+
+	return findings, err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRuleEngine(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		tags    map[string]string
+		want    []Finding
+		wantErr bool
+	}{
+		{"no violations", `deny contains "untagged" if { not input.tags.Owner }`,
+			map[string]string{"Owner": "ops"}, nil, false},
+		{"message", `deny contains "untagged" if { not input.tags.Owner }`,
+			nil, []Finding{{Check: "rego", Subject: "untagged", Severity: SeverityMedium, Message: "untagged"}}, false},
+		{"object", `deny contains {"check": "prod-kms", "severity": "high", "msg": "PROD buckets must use KMS"} if { input.tags.Stage == "PROD" }`,
+			map[string]string{"Stage": "PROD"}, []Finding{{Check: "prod-kms", Subject: "PROD buckets must use KMS", Severity: SeverityHigh, Message: "PROD buckets must use KMS"}}, false},
+		{"subject", `deny contains {"check": "tag-values", "subject": k, "msg": sprintf("tag %s is empty", [k])} if { some k; input.tags[k] == "" }`,
+			map[string]string{"Owner": ""}, []Finding{{Check: "tag-values", Subject: "Owner", Severity: SeverityMedium, Message: "tag Owner is empty"}}, false},
+		{"no msg", `deny contains {"check": "prod-kms"} if { true }`, nil, nil, true},
+		{"unknown severity", `deny contains {"severity": "URGENT", "msg": "m"} if { true }`, nil, nil, true},
+		{"not a message", `deny contains 42 if { true }`, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "rule.rego"), []byte("package s3audit\n\n"+tt.rule+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			// Other files alongside the rules are ignored.
+			if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# rules\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			engine, err := loadRules(t.Context(), dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := engine.runFallible(t.Context(), &BucketFacts{Tags: tt.tags})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runFallible() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("runFallible() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.Check != w.Check || g.Subject != w.Subject || g.Severity != w.Severity || g.Message != w.Message {
+					t.Errorf("runFallible()[%d] = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRulesInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "rule.rego"), []byte("package s3audit\n\ndeny contains if {\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadRules(t.Context(), dir); err == nil {
+		t.Error("loadRules() compiled an invalid rule")
+	}
+}
@@ -0,0 +1,579 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/guardian/s3-audit/awsapi"
+	"github.com/guardian/s3-audit/policy"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scanner holds state shared across the accounts in a scan.
+type scanner struct {
+	store        *findingStore
+	accountNames accountNames
+	organization organization // nil without -org-profile
+	owners       *owners
+	templates    []policy.Template
+	rules        *ruleEngine
+	expressions  []ExpressionRule
+	plugins      []*plugin
+	conf         *Config
+	inventory    bool // read S3 Inventory reports for public object ACLs
+	checks       []Check
+	selection    checkSelection
+	clients      func(aws.Config) clients // creates the AWS clients for an account
+	buckets      []string                 // only scan these buckets, if set
+	found        map[string]bool          // which of buckets have been found
+	acls         map[string]aclCount      // per profile scanned
+	replication  *replicationMap          // nil unless the replication check is enabled
+	progress     *checkpoint              // records progress through each account, if set
+	s3Compatible bool                     // the S3 endpoint isn't AWS, see Config.S3Compatible
+	discovery    string                   // how buckets are found besides ListBuckets, if at all
+	snapshots    configSnapshots          // scanned in place of the accounts, if set
+}
+
+// clients are the AWS clients behind the awsapi interfaces, which tests can
+// replace with mocks.
+type clients struct {
+	s3             awsapi.S3
+	s3Control      awsapi.S3Control
+	accessAnalyzer awsapi.AccessAnalyzer
+}
+
+func newAWSClients(config aws.Config) clients {
+	return clients{
+		s3: s3.NewFromConfig(config, func(o *s3.Options) {
+			// Emulators such as LocalStack don't serve virtual-hosted buckets.
+			o.UsePathStyle = endpoints.s3Endpoint() != ""
+		}),
+		s3Control:      s3control.NewFromConfig(config),
+		accessAnalyzer: accessanalyzer.NewFromConfig(config),
+	}
+}
+
+// account identifies the account being scanned.
+type account struct {
+	Profile string
+	ID      string
+}
+
+// accountScan is the per-account state shared by every bucket in it.
+type accountScan struct {
+	account
+	config                 aws.Config
+	log                    *slog.Logger // tagged with the account
+	client                 awsapi.S3
+	s3Control              awsapi.S3Control
+	publicByAccessAnalyzer map[string]bool
+	cloudFrontOrigins      map[string][]cloudFrontOrigin
+	logTargets             logTargets
+	dataEventSelectors     []dataEventSelector
+	macieFindings          map[string][]macieFinding
+	configCompliance       map[string]map[string]configEvaluation
+	trustedAdvisor         map[string]trustedAdvisorResult
+	guardDutyFindings      map[string][]guardDutyFinding
+	storageLens            map[string]storageLensMetrics
+	kmsKeys                *kmsKeys
+	notificationTargets    *notificationTargets
+	bucketSizes            map[string]bucketSize
+	bucketNames            map[string]bool
+	errors                 []scanError             // parts of the account that couldn't be read
+	acls                   aclCount                // buckets with ACLs enabled and disabled
+	s3Compatible           bool                    // only S3 can be called
+	snapshot               map[string]*BucketFacts // recorded facts by bucket, used in place of collecting them
+
+	// Options for collecting facts. Facts only some checks use are collected
+	// if one of them is selected.
+	selection        checkSelection
+	custom           bool // custom rules or plugins are run, which may use any fact
+	inventory        bool
+	replication      bool // collect replication rules
+	egressPricePerGB float64
+	activity         *unusedBucketsParams // nil unless the unused-buckets check is enabled
+	storageCost      *storageCostParams   // nil unless the storage-cost check is enabled
+	typosquatting    *typosquattingParams // nil unless the typosquatting check is enabled
+	stateBuckets     *stateBucketParams   // nil unless the state-bucket check is enabled
+}
+
+// scanAccount scans every bucket in the account, returning how many there
+// were and the parts of the account that couldn't be read. The error is only
+// for failing to record findings, or the scan being cancelled.
+func (s *scanner) scanAccount(ctx context.Context, profile string) (int, []scanError, error) {
+	if s.snapshots != nil {
+		return s.scanSnapshotAccount(ctx, profile)
+	}
+
+	ctx, span := tracer.Start(ctx, "scanAccount", trace.WithAttributes(attribute.String("account", profile)))
+	defer span.End()
+
+	accountFailed := func(err error) (int, []scanError, error) {
+		component("scan").Warn("unable to scan account", "scan", scanID(ctx), "account", profile, "error", err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, []scanError{{Account: profile, Error: err.Error()}}, ctx.Err()
+	}
+
+	as, clients, err := s.newAccountScan(ctx, profile)
+	if err != nil {
+		return accountFailed(err)
+	}
+	span.SetAttributes(attribute.String("account.id", as.ID))
+
+	buckets, err := listBuckets(ctx, as.client)
+	if s.discovery == discoveryResourceExplorer && !as.s3Compatible {
+		buckets, err = discoverBuckets(ctx, as, buckets, err)
+	}
+	if err != nil {
+		return accountFailed(fmt.Errorf("unable to list buckets: %w", err))
+	}
+
+	as.bucketNames = map[string]bool{}
+	for _, bucket := range buckets {
+		as.bucketNames[aws.ToString(bucket.Name)] = true
+	}
+	targeted := len(s.buckets) > 0
+	if targeted {
+		buckets = s.targetBuckets(buckets)
+		if len(buckets) == 0 {
+			return 0, as.errors, nil
+		}
+	}
+
+	// An S3-compatible store has none of the other AWS services.
+	if !as.s3Compatible {
+		if err := s.scanAccountServices(ctx, as, clients, buckets, targeted); err != nil {
+			return 0, as.errors, err
+		}
+	}
+
+	if err := s.scanBuckets(ctx, as, buckets); err != nil {
+		return 0, as.errors, err
+	}
+	span.SetAttributes(attribute.Int("buckets", len(buckets)), attribute.Int("errors", len(as.errors)))
+
+	return len(buckets), as.errors, nil
+}
+
+func (s *scanner) saveProgress(as *accountScan, scanned int, buckets int) {
+	if s.progress != nil {
+		s.progress.saveProgress(accountProgress{Account: as.Profile, Buckets: buckets, Scanned: scanned, Errors: len(as.errors)})
+	}
+}
+
+// scanBuckets scans each of the account's buckets in turn, recording their
+// findings. The error is only for failing to record findings, or the scan
+// being cancelled.
+func (s *scanner) scanBuckets(ctx context.Context, as *accountScan, buckets []s3types.Bucket) error {
+	for i, bucket := range buckets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.saveProgress(as, i, len(buckets))
+
+		findings := s.scanBucket(ctx, as, bucket)
+
+		for _, finding := range findings {
+			if err := s.store.Add(finding); err != nil {
+				return fmt.Errorf("unable to record finding: %w", err)
+			}
+		}
+	}
+
+	s.saveProgress(as, len(buckets), len(buckets))
+	if s.acls == nil {
+		s.acls = map[string]aclCount{}
+	}
+	s.acls[as.Profile] = as.acls
+
+	return nil
+}
+
+// scanAccountServices gathers what the other AWS services know about the
+// account's buckets, and runs the account-level checks. The error is only
+// for failing to record findings.
+func (s *scanner) scanAccountServices(ctx context.Context, as *accountScan, clients clients, buckets []s3types.Bucket, targeted bool) error {
+	config := as.config
+	var err error
+
+	if as.needs(exposureChecks...) {
+		as.publicByAccessAnalyzer, err = getAccessAnalyzerPublicBuckets(ctx, clients.accessAnalyzer)
+		if err != nil {
+			as.fail("", err)
+		}
+		as.log.Info("found public buckets with Access Analyzer", "buckets", len(as.publicByAccessAnalyzer))
+	}
+
+	if as.needs("cloudfront-origin", "dangling-bucket-reference") {
+		as.cloudFrontOrigins, err = getCloudFrontOrigins(ctx, cloudfront.NewFromConfig(config))
+		if err != nil {
+			as.fail("", fmt.Errorf("unable to list CloudFront distributions: %w", err))
+		}
+	}
+
+	if s.selection.enabled("log-bucket") {
+		if as.logTargets, err = getLogTargets(ctx, config, as.client, buckets); err != nil {
+			as.fail("", err)
+		}
+	}
+
+	if as.needs("data-events") {
+		as.dataEventSelectors, err = getDataEventSelectors(ctx, cloudtrail.NewFromConfig(config))
+		if err != nil {
+			as.fail("", fmt.Errorf("unable to get CloudTrail event selectors: %w", err))
+		}
+	}
+
+	if as.needs("public-access") {
+		if as.macieFindings, err = getMacieFindings(ctx, config, bucketRegions(buckets)); err != nil {
+			as.fail("", err)
+		}
+	}
+
+	// Config and Trusted Advisor are only needed to cross-check the results,
+	// and Trusted Advisor fails without a Business or Enterprise support plan.
+	if s.selection.enabled("config-disagreement") {
+		if as.configCompliance, err = getConfigCompliance(ctx, config, bucketRegions(buckets)); err != nil {
+			as.fail("", err)
+		}
+	}
+
+	if s.selection.enabled("trusted-advisor-disagreement") {
+		as.trustedAdvisor, err = getTrustedAdvisorResults(ctx, config)
+		if err != nil {
+			as.fail("", fmt.Errorf("unable to get Trusted Advisor results (a Business or Enterprise support plan is needed): %w", err))
+		}
+	}
+
+	// Sizes and usage are context for findings, chiefly for prioritising
+	// public buckets.
+	if as.needs("public-access", "storage-cost") {
+		if as.bucketSizes, err = getBucketSizes(ctx, config, bucketRegions(buckets)); err != nil {
+			as.fail("", err)
+		}
+	}
+
+	if as.needs("public-access") {
+		as.storageLens, err = getStorageLensMetrics(ctx, config, as.s3Control, as.ID)
+		if err != nil {
+			as.fail("", fmt.Errorf("unable to get Storage Lens metrics: %w", err))
+		}
+	}
+
+	var guardDutyRegions []guardDutyRegion
+	if as.needs("guardduty-s3-protection") {
+		if guardDutyRegions, as.guardDutyFindings, err = getGuardDuty(ctx, config, bucketRegions(buckets)); err != nil {
+			as.fail("", err)
+		}
+	}
+	for _, region := range guardDutyRegions {
+		if region.S3Protection || targeted || !s.selection.enabled("guardduty-s3-protection") {
+			continue
+		}
+
+		finding := Finding{Account: as.Profile, AccountID: as.ID, Check: "guardduty-s3-protection", Subject: region.Region, Severity: SeverityMedium}
+		if region.Enabled {
+			finding.Message = fmt.Sprintf("GuardDuty S3 Protection is not enabled in %s", region.Region)
+		} else {
+			finding.Message = fmt.Sprintf("GuardDuty is not enabled in %s", region.Region)
+		}
+
+		if err := s.store.Add(finding); err != nil {
+			return fmt.Errorf("unable to record finding: %w", err)
+		}
+	}
+
+	var objectLambdaAccessPoints []objectLambdaAccessPoint
+	if s.selection.enabled("object-lambda-access-point") && !targeted {
+		if objectLambdaAccessPoints, err = getObjectLambdaAccessPoints(ctx, as.s3Control, as.ID, bucketRegions(buckets)); err != nil {
+			as.fail("", err)
+		}
+	}
+	for _, ap := range objectLambdaAccessPoints {
+		for _, finding := range s.objectLambdaFindings(as, ap) {
+			if err := s.store.Add(finding); err != nil {
+				return fmt.Errorf("unable to record finding: %w", err)
+			}
+		}
+	}
+
+	if s.selection.enabled("dangling-bucket-reference") && !targeted {
+		refs := cloudFrontBucketReferences(as.cloudFrontOrigins)
+		route53Refs, err := getRoute53BucketReferences(ctx, route53.NewFromConfig(config))
+		if err != nil {
+			as.fail("", err)
+		}
+		for _, finding := range s.danglingReferenceFindings(ctx, as, append(refs, route53Refs...)) {
+			if err := s.store.Add(finding); err != nil {
+				return fmt.Errorf("unable to record finding: %w", err)
+			}
+		}
+	}
+
+	if s.selection.enabled("identity-policy") && !targeted {
+		policies, err := getIdentityPolicies(ctx, iam.NewFromConfig(config))
+		if err != nil {
+			as.fail("", err)
+		}
+		for _, finding := range identityPolicyFindings(as, policies) {
+			if err := s.store.Add(finding); err != nil {
+				return fmt.Errorf("unable to record finding: %w", err)
+			}
+		}
+	}
+
+	if s.selection.enabled("vpc-endpoint-policy") && !targeted {
+		endpoints, err := getS3VPCEndpoints(ctx, config, bucketRegions(buckets))
+		if err != nil {
+			as.fail("", err)
+		}
+		for _, finding := range vpcEndpointFindings(as, endpoints) {
+			if err := s.store.Add(finding); err != nil {
+				return fmt.Errorf("unable to record finding: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// newAccountScan loads the profile's AWS config and identity, ready to
+// collect facts about its buckets with the options the checks need.
+func (s *scanner) newAccountScan(ctx context.Context, profile string) (*accountScan, clients, error) {
+	config, err := loadConfig(ctx, profile)
+	if err != nil {
+		return nil, clients{}, fmt.Errorf("unable to load AWS config: %w", err)
+	}
+
+	// An S3-compatible store has no STS, nor account IDs.
+	id := ""
+	if !s.s3Compatible {
+		identity, err := sts.NewFromConfig(config).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err != nil {
+			return nil, clients{}, fmt.Errorf("unable to get caller identity: %w", err)
+		}
+		id = aws.ToString(identity.Account)
+	}
+	clients := s.clients(config)
+	as := &accountScan{
+		account:             account{Profile: profile, ID: id},
+		log:                 component("scan").With("scan", scanID(ctx), "account", profile),
+		config:              config,
+		client:              clients.s3,
+		s3Control:           clients.s3Control,
+		kmsKeys:             newKMSKeys(config),
+		notificationTargets: newNotificationTargets(config),
+
+		selection:        s.selection,
+		inventory:        s.inventory,
+		egressPricePerGB: s.conf.egressPricePerGB(),
+		s3Compatible:     s.s3Compatible,
+		replication:      s.replication != nil,
+	}
+	for _, c := range s.checks {
+		switch c := c.(type) {
+		case unusedBucketsCheck:
+			as.activity = &c.params
+		case storageCostCheck:
+			as.storageCost = &c.params
+		case typosquattingCheck:
+			as.typosquatting = &c.params
+		case stateBucketCheck:
+			as.stateBuckets = &c.params
+		case *ruleEngine, ExpressionRule, *plugin:
+			as.custom = true
+		}
+	}
+
+	return as, clients, nil
+}
+
+// targetBuckets returns those of buckets given with -bucket, noting them as
+// found.
+func (s *scanner) targetBuckets(buckets []s3types.Bucket) []s3types.Bucket {
+	if s.found == nil {
+		s.found = map[string]bool{}
+	}
+
+	return slices.DeleteFunc(buckets, func(b s3types.Bucket) bool {
+		name := aws.ToString(b.Name)
+		if !slices.Contains(s.buckets, name) {
+			return true
+		}
+
+		s.found[name] = true
+		return false
+	})
+}
+
+// scanBucket runs the checks on a bucket. A panic in a check is recorded as
+// an error for the bucket rather than ending the scan.
+func (s *scanner) scanBucket(ctx context.Context, as *accountScan, bucket s3types.Bucket) (findings []Finding) {
+	ctx, span := tracer.Start(ctx, "scanBucket", trace.WithAttributes(attribute.String("bucket", aws.ToString(bucket.Name))))
+	defer func() {
+		var err error
+		if r := recover(); r != nil {
+			err = fmt.Errorf("scan failed: %v", r)
+			as.fail(aws.ToString(bucket.Name), err)
+			findings = nil
+		}
+		span.SetAttributes(attribute.Int("findings", len(findings)))
+		endSpan(span, err)
+	}()
+
+	facts, recorded := as.snapshot[aws.ToString(bucket.Name)]
+	if !recorded {
+		facts = collectFacts(ctx, as, bucket)
+	}
+	as.acls.add(facts.ObjectOwnership)
+	if s.replication != nil {
+		s.replication.add(facts)
+	}
+	facts.Owner = s.owners.resolve(facts.Name, facts.Tags)
+
+	// Ownership, metadata, GuardDuty findings and usage are context for every
+	// finding on the bucket.
+	metadata := &bucketMetadata{Created: facts.Created, Region: facts.Region, Tags: facts.Tags}
+	if facts.Size != nil {
+		metadata.Objects, metadata.SizeBytes = facts.Size.Objects, facts.Size.Bytes
+	}
+
+	findings = []Finding{}
+	for _, check := range s.checks {
+		for _, finding := range runCheck(ctx, as, check, facts) {
+			finding.Account, finding.AccountID, finding.Bucket = as.Profile, as.ID, facts.Name
+			finding.Owner = facts.Owner
+			escalateStateBucket(facts, &finding)
+			s.conf.escalateSensitiveName(facts, &finding)
+			finding.Metadata = metadata
+			finding.Usage = facts.StorageLens
+			finding.GuardDuty = facts.GuardDutyFindings
+			for _, gd := range facts.GuardDutyFindings {
+				finding.Details = append(finding.Details, fmt.Sprintf("GuardDuty reported %s (finding %s)", gd.Type, gd.ID))
+			}
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings
+}
+
+// runCheck runs the check on the bucket, recording a fallible check's failure
+// to evaluate as a scan error for it. Custom checks name the checks of their
+// findings themselves, so their findings also record which one reported
+// them.
+func runCheck(ctx context.Context, as *accountScan, check Check, facts *BucketFacts) []Finding {
+	c, ok := check.(fallibleCheck)
+	if !ok {
+		return check.Run(ctx, facts)
+	}
+
+	findings, err := c.runFallible(ctx, facts)
+	if err != nil {
+		as.fail(facts.Name, err)
+	}
+	for i := range findings {
+		findings[i].Source = check.Name()
+	}
+
+	return findings
+}
+
+// Finding records a problem with a bucket found by a check. Account-level
+// findings have no Bucket. The public-access check also fills in the fields
+// below GuardDuty.
+type Finding struct {
+	Account   string              `json:"account"`
+	AccountID string              `json:"accountId,omitempty"`
+	Bucket    string              `json:"bucket,omitempty"`
+	Owner     string              `json:"owner,omitempty"` // team owning the bucket, see owners
+	Check     string              `json:"check"`
+	Source    string              `json:"source,omitempty"`  // the custom rule or plugin reporting it; empty for built-in checks
+	Subject   string              `json:"subject,omitempty"` // what the finding is about, where a check can raise several on a bucket or account
+	Severity  Severity            `json:"severity"`
+	Message   string              `json:"message"`
+	Details   []string            `json:"details,omitempty"`
+	Metadata  *bucketMetadata     `json:"metadata,omitempty"`
+	GuardDuty []guardDutyFinding  `json:"guardDuty,omitempty"` // recent GuardDuty findings for the bucket
+	Usage     *storageLensMetrics `json:"usage,omitempty"`     // Storage Lens metrics for the bucket
+
+	Public        bool                `json:"public,omitempty"`       // probe object readable without credentials
+	AWSPublic     bool                `json:"awsPublic,omitempty"`    // Access Analyzer reports the bucket as public
+	PolicyPublic  bool                `json:"policyPublic,omitempty"` // bucket policy is public by S3's definition
+	PublicWrite   bool                `json:"publicWrite,omitempty"`  // public grants include writing or deleting
+	Mitigations   []policy.Mitigation `json:"mitigations,omitempty"`
+	Grantees      []string            `json:"grantees,omitempty"` // principals the policy allows, with account names resolved
+	SensitiveData []macieFinding      `json:"sensitiveData,omitempty"`
+	Egress        *egressEstimate     `json:"egress,omitempty"`  // for buckets confirmed public
+	Score         int                 `json:"score,omitempty"`   // see Scoring
+	Signals       []string            `json:"signals,omitempty"` // what the score is made of
+}
+
+// scanIDKey is the context key for the ID of the running scan, which tags
+// its logs.
+type scanIDKey struct{}
+
+func withScanID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, scanIDKey{}, id)
+}
+
+func scanID(ctx context.Context) string {
+	id, _ := ctx.Value(scanIDKey{}).(string)
+	return id
+}
+
+// bucketRegions returns the distinct regions the buckets are in.
+func bucketRegions(buckets []s3types.Bucket) []string {
+	regions := []string{}
+	for _, bucket := range buckets {
+		region := aws.ToString(bucket.BucketRegion)
+		if region != "" && !slices.Contains(regions, region) {
+			regions = append(regions, region)
+		}
+	}
+
+	return regions
+}
+
+func loadConfig(ctx context.Context, profile string) (aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(
+		ctx,
+		config.WithRegion("eu-west-1"),
+		config.WithSharedConfigProfile(profile),
+	)
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg.APIOptions = append(cfg.APIOptions, addRequestCounting, addWriteGuard(profile))
+	if debugRequests {
+		cfg.APIOptions = append(cfg.APIOptions, addRequestLogging)
+	}
+	if traceRequests {
+		cfg.APIOptions = append(cfg.APIOptions, addRequestTracing)
+	}
+
+	if len(endpoints) == 0 {
+		return cfg, nil
+	}
+
+	// Clients take the first endpoint found in the config sources, so the
+	// overrides go ahead of the environment and shared config file.
+	cfg.ConfigSources = append([]any{endpoints}, cfg.ConfigSources...)
+	return cfg, nil
+}
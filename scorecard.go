@@ -0,0 +1,119 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// failureWeights are how much of a bucket's score a failed check costs, by
+// the finding's severity. A single CRITICAL finding zeroes the bucket.
+var failureWeights = map[Severity]float64{
+	SeverityLow:      0.05,
+	SeverityMedium:   0.15,
+	SeverityHigh:     0.5,
+	SeverityCritical: 1,
+}
+
+// scoreAccounts gives each profile a 0-100 posture score: the average of
+// its buckets' scores, where a bucket starts at 1 and loses the failure
+// weight of each finding against it. Account-level findings aren't about
+// any bucket so don't count. An account with no buckets scores 100.
+func scoreAccounts(findings []Finding, buckets map[string]int) map[string]int {
+	lost := scoreLost{}
+	for _, f := range findings {
+		lost.add(f)
+	}
+
+	return lost.scores(buckets)
+}
+
+// scoreLost is the score each bucket has lost, by profile and bucket, so
+// that findings can be scored as they're streamed from the store.
+type scoreLost map[string]map[string]float64
+
+func (lost scoreLost) add(f Finding) {
+	if f.Bucket == "" || isCostFinding(f) {
+		return
+	}
+
+	if lost[f.Account] == nil {
+		lost[f.Account] = map[string]float64{}
+	}
+	lost[f.Account][f.Bucket] += failureWeights[f.Severity]
+}
+
+// scores gives each profile in buckets its score, as scoreAccounts.
+func (lost scoreLost) scores(buckets map[string]int) map[string]int {
+	scores := map[string]int{}
+	for profile, count := range buckets {
+		if count == 0 {
+			scores[profile] = 100
+			continue
+		}
+
+		total := float64(count)
+		for _, l := range lost[profile] {
+			total -= min(l, 1)
+		}
+
+		scores[profile] = int(100*total/float64(count) + 0.5)
+	}
+
+	return scores
+}
+
+// printLeagueTable ranks accounts by score, best first, with the change
+// since previous (which may be nil).
+func printLeagueTable(w io.Writer, current savedReport, previous *savedReport) {
+	profiles := slices.Collect(maps.Keys(current.Scores))
+	slices.SortFunc(profiles, func(a, b string) int {
+		return cmp.Or(cmp.Compare(current.Scores[b], current.Scores[a]), cmp.Compare(a, b))
+	})
+
+	fmt.Fprintln(w, "Account league table")
+	for i, profile := range profiles {
+		line := fmt.Sprintf("  %2d. %-40s %3d", i+1, profile, current.Scores[profile])
+		if previous != nil {
+			if before, ok := previous.Scores[profile]; ok {
+				line += fmt.Sprintf("  (%+d)", current.Scores[profile]-before)
+			}
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// printScorecardHistory tabulates each account's score across saved
+// reports, oldest first.
+func printScorecardHistory(w io.Writer, reports []*savedReport) {
+	profiles := []string{}
+	for _, r := range reports {
+		for profile := range r.Scores {
+			if !slices.Contains(profiles, profile) {
+				profiles = append(profiles, profile)
+			}
+		}
+	}
+	slices.Sort(profiles)
+
+	header := []string{fmt.Sprintf("%-40s", "account")}
+	for _, r := range reports {
+		header = append(header, formatTime(r.Time))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, profile := range profiles {
+		row := []string{fmt.Sprintf("%-40s", profile)}
+		for _, r := range reports {
+			if score, ok := r.Scores[profile]; ok {
+				row = append(row, fmt.Sprintf("%*d", len(formatTime(r.Time)), score))
+			} else {
+				row = append(row, fmt.Sprintf("%*s", len(formatTime(r.Time)), "-"))
+			}
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule is a group of accounts s3-audit serve scans on a cron schedule,
+// configured in the config file, e.g.
+//
+//	{"name": "prod", "profiles": ["ophan-prod", "frontend-prod"], "cron": "0 2 * * *", "jitter": "15m"}
+//
+// Each scan is run as s3-audit with the same config file, the profiles and
+// any extra args, with its own checkpoint and history directories named
+// after the schedule.
+type Schedule struct {
+	Name     string   `json:"name"`
+	Profiles []string `json:"profiles"`
+	Cron     string   `json:"cron"`   // standard five fields, in the report timezone
+	Jitter   string   `json:"jitter"` // the most to delay each scan by at random, e.g. 15m
+	Args     []string `json:"args"`   // other scan flags, e.g. ["-quiet", "-output", "slack=..."]
+}
+
+func (s Schedule) validate() error {
+	if s.Name == "" || strings.ContainsAny(s.Name, `/\`) {
+		return fmt.Errorf("invalid schedule name %q", s.Name)
+	}
+	if len(s.Profiles) == 0 {
+		return fmt.Errorf("schedule %s has no profiles", s.Name)
+	}
+	if _, err := cron.ParseStandard(s.Cron); err != nil {
+		return fmt.Errorf("schedule %s: invalid cron expression: %w", s.Name, err)
+	}
+	if s.Jitter != "" {
+		if _, err := time.ParseDuration(s.Jitter); err != nil {
+			return fmt.Errorf("schedule %s: invalid jitter: %w", s.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// serveMain runs the scans in the config file's schedules until stopped,
+// reporting their progress through the status API.
+func serveMain(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	configFile := fs.String("config", "", "JSON config file with schedules")
+	checkpointDir := fs.String("checkpoint-dir", defaultCheckpointDir(), "directory for each schedule's scan progress")
+	historyDir := fs.String("history-dir", defaultHistoryDir(), "directory for each schedule's saved scans")
+	logFormat := fs.String("log-format", "text", "log as text or json")
+	listen := fs.String("listen", "localhost:8080", "address to serve the scan status API (GET /scans and /scans/{id}) and Grafana API (GET /grafana/...) on, or empty for none")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit serve -config config.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	conf, err := loadConfigFile(*configFile)
+	check(err, "unable to load config")
+	if len(conf.Schedules) == 0 {
+		check(errors.New("no schedules in the config file"), "unable to serve")
+	}
+	check(setReportTime(conf.Timezone, conf.TimeFormat), "invalid config")
+	check(setupLogging(os.Stderr, *logFormat, "info"), "invalid logging flags")
+
+	exe, err := os.Executable()
+	check(err, "unable to find s3-audit executable")
+	configPath, err := filepath.Abs(*configFile)
+	check(err, "unable to find config file")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	statuses := &scanStatuses{}
+	if *listen != "" {
+		ln, err := net.Listen("tcp", *listen)
+		check(err, "unable to serve status API")
+		mux := http.NewServeMux()
+		mux.Handle("/grafana", grafanaHandler(*historyDir, conf.Schedules))
+		mux.Handle("/grafana/", grafanaHandler(*historyDir, conf.Schedules))
+		mux.Handle("/", statuses.handler())
+		server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go server.Serve(ln)
+		defer server.Shutdown(context.Background())
+		component("serve").Info("serving status API", "address", ln.Addr().String())
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range conf.Schedules {
+		sched, _ := cron.ParseStandard(s.Cron)
+		jitter, _ := time.ParseDuration(s.Jitter)
+		scan := scheduledScan{
+			schedule:      s.Name,
+			accounts:      len(s.Profiles),
+			checkpointDir: filepath.Join(*checkpointDir, s.Name),
+			historyDir:    filepath.Join(*historyDir, s.Name),
+			exe:           exe,
+		}
+		scan.args = append([]string{
+			"scan",
+			"-config", configPath,
+			"-profiles", strings.Join(s.Profiles, ","),
+			"-checkpoint-dir", scan.checkpointDir,
+			"-history-dir", scan.historyDir,
+		}, s.Args...)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runSchedule(ctx, scan, sched, jitter, statuses)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// scheduledScan is the scan a schedule runs.
+type scheduledScan struct {
+	schedule      string
+	accounts      int
+	checkpointDir string
+	historyDir    string
+	exe           string
+	args          []string
+}
+
+// runSchedule runs the scan each time the schedule fires, after a random
+// delay of up to jitter so that schedules firing together don't all start at
+// once. If the previous scan is still running, the next is skipped.
+func runSchedule(ctx context.Context, scan scheduledScan, sched cron.Schedule, jitter time.Duration, statuses *scanStatuses) {
+	log := component("serve").With("schedule", scan.schedule)
+	var running atomic.Bool
+	var scans sync.WaitGroup
+	defer scans.Wait()
+
+	for {
+		next := sched.Next(time.Now().In(reportLocation))
+		if jitter > 0 {
+			next = next.Add(rand.N(jitter))
+		}
+		log.Info("next scan scheduled", "at", formatTime(next))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		if !running.CompareAndSwap(false, true) {
+			log.Warn("skipping scan, the previous one is still running")
+			continue
+		}
+
+		scans.Add(1)
+		go func() {
+			defer scans.Done()
+			defer running.Store(false)
+			runScheduledScan(ctx, log, scan, statuses)
+		}()
+	}
+}
+
+// runScheduledScan runs a scan as a child process. Stopping serve interrupts
+// it, leaving its checkpoint to resume from.
+func runScheduledScan(ctx context.Context, log *slog.Logger, scan scheduledScan, statuses *scanStatuses) {
+	started := time.Now()
+	status := statuses.start(scan)
+	log = log.With("id", status.ID)
+	log.Info("starting scan")
+
+	cmd := exec.CommandContext(ctx, scan.exe, scan.args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = time.Minute
+
+	err := cmd.Run()
+	statuses.finish(status, err)
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		log.Info("scan finished with no findings", "took", time.Since(started).Round(time.Second))
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 3:
+		log.Info("scan finished with findings", "took", time.Since(started).Round(time.Second))
+	default:
+		log.Error("scan failed", "error", err, "took", time.Since(started).Round(time.Second))
+	}
+}
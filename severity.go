@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityLow Severity = iota
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = []string{"LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+
+	return severityNames[s]
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	for i, name := range severityNames {
+		if strings.EqualFold(string(text), name) {
+			*s = Severity(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+// atLeast returns the findings of the given severity or above.
+func atLeast(findings []Finding, threshold Severity) []Finding {
+	filtered := []Finding{}
+	for _, f := range findings {
+		if f.Severity >= threshold {
+			filtered = append(filtered, f)
+		}
+	}
+
+	return filtered
+}
+
+// Signals that raise or lower the score of a public-access finding.
+const (
+	signalPublic           = "public"           // readable anonymously, public policy, or Access Analyzer public with no conditions
+	signalPublicWrite      = "publicWrite"      // public grants include writing or deleting
+	signalAccessAnalyzer   = "accessAnalyzer"   // Access Analyzer public, though conditions limit it
+	signalBroadConditions  = "broadConditions"  // conditions limit access, but not all narrowly
+	signalNarrowConditions = "narrowConditions" // every condition narrowly limits access; subtracted
+	signalSensitiveData    = "sensitiveData"    // Macie found personal data or credentials
+	signalSensitiveTags    = "sensitiveTags"    // tagged as sensitive (see Config.SensitiveTags)
+	signalProduction       = "production"       // in a production account or tagged as production
+)
+
+var defaultWeights = map[string]int{
+	signalPublic:           50,
+	signalPublicWrite:      30,
+	signalAccessAnalyzer:   20,
+	signalBroadConditions:  20,
+	signalNarrowConditions: 15,
+	signalSensitiveData:    30,
+	signalSensitiveTags:    15,
+	signalProduction:       15,
+}
+
+// Scoring configures how public-access findings are graded. A finding's
+// score is the sum of the weights of its signals, less narrowConditions'
+// and no lower than zero, and its severity the highest threshold the score
+// reaches. Weights not given take their
+// defaults.
+type Scoring struct {
+	Weights    map[string]int `json:"weights"`
+	Thresholds Thresholds     `json:"thresholds"`
+
+	// ProductionAccounts are account IDs or profiles treated as production.
+	ProductionAccounts []string `json:"productionAccounts"`
+
+	// ProductionTags marks buckets as production in any account, e.g.
+	// {"Stage": ["PROD"]}.
+	ProductionTags map[string][]string `json:"productionTags"`
+}
+
+// Thresholds are the minimum scores for each severity; lower scores are LOW.
+type Thresholds struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+}
+
+var defaultThresholds = Thresholds{Critical: 80, High: 50, Medium: 20}
+
+// validate fills in defaults and rejects unknown signals, which are
+// probably typos.
+func (s *Scoring) validate() error {
+	weights := maps.Clone(defaultWeights)
+	for signal, weight := range s.Weights {
+		if _, ok := defaultWeights[signal]; !ok {
+			return fmt.Errorf("unknown scoring signal %q", signal)
+		}
+		weights[signal] = weight
+	}
+	s.Weights = weights
+
+	if s.Thresholds == (Thresholds{}) {
+		s.Thresholds = defaultThresholds
+	}
+
+	return nil
+}
+
+// severityFor scores a public-access finding from the bucket's signals,
+// recording the score and the signals behind it on the finding.
+func (c *Config) severityFor(f *Finding, facts *BucketFacts) Severity {
+	signals := []string{}
+
+	if f.Public || f.PolicyPublic || (f.AWSPublic && len(f.Mitigations) == 0) {
+		signals = append(signals, signalPublic)
+	} else if f.AWSPublic {
+		signals = append(signals, signalAccessAnalyzer)
+	}
+
+	if f.PublicWrite {
+		signals = append(signals, signalPublicWrite)
+	}
+
+	if len(f.Mitigations) > 0 {
+		narrow := true
+		for _, m := range f.Mitigations {
+			narrow = narrow && m.Narrow
+		}
+
+		if narrow {
+			signals = append(signals, signalNarrowConditions)
+		} else {
+			signals = append(signals, signalBroadConditions)
+		}
+	}
+
+	if slices.ContainsFunc(f.SensitiveData, macieFinding.isPersonalOrSecret) {
+		signals = append(signals, signalSensitiveData)
+	}
+
+	if c.isSensitive(facts.Tags) {
+		signals = append(signals, signalSensitiveTags)
+	}
+
+	if c.isProduction(facts) {
+		signals = append(signals, signalProduction)
+	}
+
+	f.Score = 0
+	for _, signal := range signals {
+		if signal == signalNarrowConditions {
+			f.Score -= c.Scoring.Weights[signal]
+			continue
+		}
+		f.Score += c.Scoring.Weights[signal]
+	}
+	f.Score = max(f.Score, 0)
+	f.Signals = signals
+
+	t := c.Scoring.Thresholds
+	switch {
+	case f.Score >= t.Critical:
+		return SeverityCritical
+	case f.Score >= t.High:
+		return SeverityHigh
+	case f.Score >= t.Medium:
+		return SeverityMedium
+	default:
+		return SeverityLow
+	}
+}
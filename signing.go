@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// reportSignature is written next to a saved report as <report>.sig. The
+// KMS fields are only set when a signing key was given.
+type reportSignature struct {
+	SHA256    string `json:"sha256"`
+	KeyID     string `json:"keyId,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+}
+
+// signReport writes <path>.sha256, in the format sha256sum -c reads, and
+// <path>.sig with the digest signed by the asymmetric KMS key, if keyID is
+// set, so that archived reports can be shown to be unmodified.
+func signReport(ctx context.Context, path string, config aws.Config, keyID string) error {
+	digest, err := fileDigest(path)
+	if err != nil {
+		return err
+	}
+
+	sum := fmt.Sprintf("%x  %s\n", digest, filepath.Base(path))
+	if err := os.WriteFile(path+".sha256", []byte(sum), 0o600); err != nil {
+		return err
+	}
+
+	sig := reportSignature{SHA256: hex.EncodeToString(digest)}
+	if keyID != "" {
+		client := kmsClient(config, keyID)
+
+		key, err := client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: &keyID})
+		if err != nil {
+			return fmt.Errorf("unable to get signing key: %w", err)
+		}
+		algorithm, err := sha256Algorithm(key.SigningAlgorithms)
+		if err != nil {
+			return fmt.Errorf("KMS key %s %w", keyID, err)
+		}
+
+		out, err := client.Sign(ctx, &kms.SignInput{
+			KeyId:            key.KeyId,
+			Message:          digest,
+			MessageType:      kmstypes.MessageTypeDigest,
+			SigningAlgorithm: algorithm,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to sign report: %w", err)
+		}
+
+		sig.KeyID = aws.ToString(out.KeyId)
+		sig.Algorithm = string(out.SigningAlgorithm)
+		sig.Signature = out.Signature
+	}
+
+	data, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path+".sig", data, 0o600)
+}
+
+// sha256Algorithm picks the algorithm that signs a SHA-256 digest, which is
+// what signReport signs, from those the key supports.
+func sha256Algorithm(algorithms []kmstypes.SigningAlgorithmSpec) (kmstypes.SigningAlgorithmSpec, error) {
+	for _, algorithm := range algorithms {
+		if strings.HasSuffix(string(algorithm), "_SHA_256") {
+			return algorithm, nil
+		}
+	}
+	return "", errors.New("can't sign a SHA-256 digest")
+}
+
+// verifyReport checks the report against its .sig, including the KMS
+// signature if there is one. If keyID is set, the report must be signed, and
+// the signature must be valid for that key rather than the one the .sig
+// names, so that a report re-signed with another key is rejected.
+func verifyReport(ctx context.Context, path string, config aws.Config, keyID string) error {
+	data, err := os.ReadFile(path + ".sig")
+	if err != nil {
+		return err
+	}
+
+	var sig reportSignature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return fmt.Errorf("invalid signature file: %w", err)
+	}
+
+	digest, err := fileDigest(path)
+	if err != nil {
+		return err
+	}
+
+	expected, err := hex.DecodeString(sig.SHA256)
+	if err != nil || !bytes.Equal(digest, expected) {
+		return errors.New("report does not match its digest")
+	}
+
+	if keyID == "" {
+		if sig.KeyID == "" {
+			return nil
+		}
+		keyID = sig.KeyID
+	} else if sig.KeyID == "" {
+		return errors.New("report is not signed")
+	}
+
+	out, err := kmsClient(config, keyID).Verify(ctx, &kms.VerifyInput{
+		KeyId:            &keyID,
+		Message:          digest,
+		MessageType:      kmstypes.MessageTypeDigest,
+		Signature:        sig.Signature,
+		SigningAlgorithm: kmstypes.SigningAlgorithmSpec(sig.Algorithm),
+	})
+	var invalid *kmstypes.KMSInvalidSignatureException
+	if errors.As(err, &invalid) {
+		return fmt.Errorf("signature is not valid for key %s (signed by %s)", keyID, sig.KeyID)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to verify signature: %w", err)
+	}
+	if !out.SignatureValid {
+		return fmt.Errorf("signature is not valid for key %s (signed by %s)", keyID, sig.KeyID)
+	}
+
+	return nil
+}
+
+// verifyMain implements the verify subcommand.
+func verifyMain(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	profile := fs.String("profile", "deployTools", "AWS profile allowed to use the signing key")
+	endpointURL := fs.String("endpoint-url", "", "send AWS requests to this URL instead")
+	keyID := fs.String("key", "", "require a valid signature by this KMS key, rather than trusting the key the signature names")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit verify [-profile profile] [-key key] report.json ...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	check(setEndpoints(*endpointURL, nil), "invalid endpoint")
+
+	ctx := context.Background()
+	config, err := loadConfig(ctx, *profile)
+	check(err, "unable to load AWS config")
+
+	failed := false
+	for _, path := range fs.Args() {
+		if err := verifyReport(ctx, path, config, *keyID); err != nil {
+			fmt.Printf("%s: FAILED (%v)\n", path, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: OK\n", path)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func fileDigest(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+// kmsClient returns a KMS client for the key's region, when the key is
+// given as an ARN.
+func kmsClient(config aws.Config, keyID string) *kms.Client {
+	return kms.NewFromConfig(config, func(o *kms.Options) {
+		if parts := strings.SplitN(keyID, ":", 6); len(parts) == 6 && parts[0] == "arn" {
+			o.Region = parts[3]
+		}
+	})
+}
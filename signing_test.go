@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+func TestSHA256Algorithm(t *testing.T) {
+	tests := []struct {
+		name       string
+		algorithms []kmstypes.SigningAlgorithmSpec
+		want       kmstypes.SigningAlgorithmSpec
+	}{
+		{"rsa", []kmstypes.SigningAlgorithmSpec{
+			kmstypes.SigningAlgorithmSpecRsassaPssSha512,
+			kmstypes.SigningAlgorithmSpecRsassaPkcs1V15Sha384,
+			kmstypes.SigningAlgorithmSpecRsassaPssSha256,
+			kmstypes.SigningAlgorithmSpecRsassaPkcs1V15Sha256,
+		}, kmstypes.SigningAlgorithmSpecRsassaPssSha256},
+		{"ecc p256", []kmstypes.SigningAlgorithmSpec{kmstypes.SigningAlgorithmSpecEcdsaSha256}, kmstypes.SigningAlgorithmSpecEcdsaSha256},
+		{"ecc p384", []kmstypes.SigningAlgorithmSpec{kmstypes.SigningAlgorithmSpecEcdsaSha384}, ""},
+		{"none", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sha256Algorithm(tt.algorithms)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("sha256Algorithm() = %s, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("sha256Algorithm() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyUnsignedReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(path, []byte(`{"findings":[]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := signReport(t.Context(), path, aws.Config{}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyReport(t.Context(), path, aws.Config{}, ""); err != nil {
+		t.Errorf("verifyReport() without a key = %v, want nil", err)
+	}
+	if err := verifyReport(t.Context(), path, aws.Config{}, "alias/s3-audit"); err == nil {
+		t.Error("verifyReport() with a key passed an unsigned report")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"findings":null}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyReport(t.Context(), path, aws.Config{}, ""); err == nil {
+		t.Error("verifyReport() passed a modified report")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/guardian/s3-audit/awsapi"
+)
+
+// compile parses the name patterns, which are checked when the check is
+// built so that a bad pattern fails the scan before it starts.
+func (p *stateBucketParams) compile() error {
+	p.names = nil
+	for _, pattern := range p.NamePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid parameters for state-bucket: %w", err)
+		}
+		p.names = append(p.names, re)
+	}
+
+	return nil
+}
+
+// findStateFiles returns why the bucket looks like it holds infrastructure
+// state, or "" if it doesn't: its name matches one of the patterns, or one
+// of the first page of keys listed ends with one of the suffixes. Listing is
+// skipped when the name is enough.
+func findStateFiles(ctx context.Context, client awsapi.S3, bucket string, region string, p *stateBucketParams) (string, error) {
+	for _, re := range p.names {
+		if re.MatchString(bucket) {
+			return fmt.Sprintf("name matches %s", re), nil
+		}
+	}
+
+	if len(p.KeySuffixes) == 0 {
+		return "", nil
+	}
+
+	out, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: &bucket, MaxKeys: aws.Int32(1000)}, inRegion(region))
+	if err != nil {
+		return "", fmt.Errorf("unable to list objects: %w", err)
+	}
+
+	for _, object := range out.Contents {
+		key := aws.ToString(object.Key)
+		for _, suffix := range p.KeySuffixes {
+			if strings.HasSuffix(key, suffix) {
+				return fmt.Sprintf("holds %s", key), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// escalateStateBucket raises a finding of public exposure on a state bucket
+// to CRITICAL. Policies that are only public but for their conditions, and
+// object ACLs that Block Public Access ignores, are left as they are.
+func escalateStateBucket(facts *BucketFacts, finding *Finding) {
+	if facts.StateBucket == "" || finding.Severity == SeverityCritical {
+		return
+	}
+
+	switch finding.Check {
+	case "public-access":
+		if !finding.Public && !finding.AWSPublic && !finding.PolicyPublic {
+			return
+		}
+	case "public-objects":
+		if finding.Severity == SeverityLow {
+			return
+		}
+	default:
+		return
+	}
+
+	finding.Severity = SeverityCritical
+	finding.Details = append(finding.Details, fmt.Sprintf("raised to CRITICAL as an infrastructure state bucket (%s), whose state files hold secrets and resource IDs", facts.StateBucket))
+}
+
+// stateBucketCheck reports Terraform, CloudFormation and CDK state buckets
+// without default encryption. Their state files hold secrets and resource
+// IDs, so exposure reported by other checks is raised to CRITICAL too; see
+// escalateStateBucket.
+type stateBucketCheck struct {
+	params stateBucketParams
+}
+
+func (stateBucketCheck) Name() string { return "state-bucket" }
+
+func (c stateBucketCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	if facts.StateBucket == "" || facts.Encryption != nil {
+		return nil
+	}
+
+	return []Finding{{
+		Check:    c.Name(),
+		Severity: SeverityCritical,
+		Message:  "infrastructure state bucket has no default encryption",
+		Details:  []string{"state bucket: " + facts.StateBucket},
+	}}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// scanStatus is a scheduled scan as reported by the status API of s3-audit
+// serve. Progress comes from the checkpoint the scan saves as it goes.
+type scanStatus struct {
+	ID                int        `json:"id"`
+	Schedule          string     `json:"schedule"`
+	State             string     `json:"state"` // running, finished or failed
+	Started           time.Time  `json:"started"`
+	Finished          *time.Time `json:"finished,omitempty"`
+	Error             string     `json:"error,omitempty"`
+	Accounts          int        `json:"accounts"`
+	AccountsCompleted int        `json:"accountsCompleted"`
+	CurrentAccount    string     `json:"currentAccount,omitempty"`
+	BucketsScanned    int        `json:"bucketsScanned"`
+	BucketsRemaining  int        `json:"bucketsRemaining"` // in the current account; later accounts' aren't listed yet
+	Errors            int        `json:"errors"`
+	Findings          *int       `json:"findings,omitempty"` // once finished
+
+	checkpointDir string
+	historyDir    string
+}
+
+// refresh updates a running scan's progress from its checkpoint.
+func (s *scanStatus) refresh() {
+	cp, progress := readProgress(s.checkpointDir, s.Started)
+	if cp == nil && progress == nil {
+		return
+	}
+
+	s.AccountsCompleted, s.BucketsScanned, s.Errors = 0, 0, 0
+	s.CurrentAccount, s.BucketsRemaining = "", 0
+	if cp != nil {
+		s.AccountsCompleted = len(cp.Completed)
+		for _, n := range cp.Buckets {
+			s.BucketsScanned += n
+		}
+		s.Errors = len(cp.Errors)
+	}
+	if progress != nil && (cp == nil || !cp.isCompleted(progress.Account)) {
+		s.CurrentAccount = progress.Account
+		s.BucketsScanned += progress.Scanned
+		s.BucketsRemaining = progress.Buckets - progress.Scanned
+		s.Errors += progress.Errors
+	}
+}
+
+// recentScans is how many finished scans the status API remembers.
+const recentScans = 50
+
+// scanStatuses are the current and recent scans of s3-audit serve.
+type scanStatuses struct {
+	mu    sync.Mutex
+	next  int
+	scans []*scanStatus
+}
+
+func (s *scanStatuses) start(scan scheduledScan) *scanStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.next++
+	status := &scanStatus{
+		ID:            s.next,
+		Schedule:      scan.schedule,
+		State:         "running",
+		Started:       time.Now(),
+		Accounts:      scan.accounts,
+		checkpointDir: scan.checkpointDir,
+		historyDir:    scan.historyDir,
+	}
+	s.scans = append(s.scans, status)
+
+	finished := 0
+	for i := len(s.scans) - 1; i >= 0; i-- {
+		if s.scans[i].State == "running" {
+			continue
+		}
+		if finished++; finished > recentScans {
+			s.scans = slices.Delete(s.scans, i, i+1)
+		}
+	}
+
+	return status
+}
+
+// finish records how the scan ended. A scan that completed is reported as
+// saved in the history; one that failed, as far as it got.
+func (s *scanStatuses) finish(status *scanStatus, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status.refresh()
+	now := time.Now()
+	status.Finished = &now
+
+	// Exit status 3 means there were findings.
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 3) {
+		status.State, status.Error = "failed", err.Error()
+		return
+	}
+
+	status.State = "finished"
+	r, err := latestReport(status.historyDir)
+	if err != nil || r == nil || r.Time.Before(status.Started) {
+		return
+	}
+	status.AccountsCompleted = len(r.Buckets)
+	status.CurrentAccount, status.BucketsRemaining = "", 0
+	status.BucketsScanned = r.bucketCount()
+	status.Errors = len(r.Errors)
+	findings := len(r.Findings)
+	status.Findings = &findings
+}
+
+// list returns the scans, most recent first.
+func (s *scanStatuses) list() []scanStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []scanStatus{}
+	for _, status := range slices.Backward(s.scans) {
+		if status.State == "running" {
+			status.refresh()
+		}
+		list = append(list, *status)
+	}
+
+	return list
+}
+
+func (s *scanStatuses) get(id int) (scanStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.scans, func(status *scanStatus) bool { return status.ID == id })
+	if i < 0 {
+		return scanStatus{}, false
+	}
+	if s.scans[i].State == "running" {
+		s.scans[i].refresh()
+	}
+
+	return *s.scans[i], true
+}
+
+// handler serves GET /scans, the current and recent scans, and
+// GET /scans/{id}, one of them.
+func (s *scanStatuses) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /scans", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, s.list())
+	})
+	mux.HandleFunc("GET /scans/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		status, ok := s.get(id)
+		if err != nil || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSONResponse(w, status)
+	})
+
+	return mux
+}
+
+func writeJSONResponse(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	"github.com/guardian/s3-audit/awsapi"
+)
+
+// storageLensNamespace is where Storage Lens publishes metrics when a
+// dashboard has CloudWatch publishing enabled.
+const storageLensNamespace = "AWS/S3/Storage-Lens"
+
+// storageLensLookback covers Storage Lens's daily publishing, which can lag
+// by a couple of days, with room to find the last day with requests.
+const storageLensLookback = 14 * 24 * time.Hour
+
+// storageLensMetrics are a bucket's latest Storage Lens figures.
+// LastActivity needs advanced metrics and is nil without them or if there
+// were no requests in the lookback period.
+type storageLensMetrics struct {
+	ObjectCount  int64      `json:"objectCount"`
+	StorageBytes int64      `json:"storageBytes"`
+	LastActivity *time.Time `json:"lastActivity,omitempty"`
+}
+
+func (m storageLensMetrics) String() string {
+	activity := "no recent requests"
+	if m.LastActivity != nil {
+		activity = "last requests " + formatTime(*m.LastActivity)
+	}
+
+	return fmt.Sprintf("%d objects, %s, %s", m.ObjectCount, formatBytes(m.StorageBytes), activity)
+}
+
+// getStorageLensMetrics returns per-bucket metrics from the first enabled
+// Storage Lens dashboard that publishes to CloudWatch. It returns an empty
+// map if there is none.
+func getStorageLensMetrics(ctx context.Context, config aws.Config, control awsapi.S3Control, accountID string) (map[string]storageLensMetrics, error) {
+	metrics := map[string]storageLensMetrics{}
+
+	configID, homeRegion, err := findStorageLensDashboard(ctx, control, accountID)
+	if err != nil || configID == "" {
+		return metrics, err
+	}
+
+	client := cloudwatch.NewFromConfig(config, func(o *cloudwatch.Options) { o.Region = homeRegion })
+
+	// Bucket-level metrics are found by listing, as their dimensions include
+	// the bucket's region, which isn't known from the bucket name alone.
+	queries := []cwtypes.MetricDataQuery{}
+	buckets := map[string]string{} // query ID to bucket
+	paginator := cloudwatch.NewListMetricsPaginator(client, &cloudwatch.ListMetricsInput{
+		Namespace: aws.String(storageLensNamespace),
+		Dimensions: []cwtypes.DimensionFilter{
+			{Name: aws.String("configuration_id"), Value: aws.String(configID)},
+			{Name: aws.String("record_type"), Value: aws.String("BUCKET")},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return metrics, fmt.Errorf("unable to list Storage Lens metrics: %w", err)
+		}
+
+		for _, metric := range page.Metrics {
+			name := aws.ToString(metric.MetricName)
+			if name != "ObjectCount" && name != "StorageBytes" && name != "AllRequests" {
+				continue
+			}
+
+			bucket, ok := dimension(metric.Dimensions, "bucket_name")
+			if _, perClass := dimension(metric.Dimensions, "storage_class"); !ok || perClass {
+				continue
+			}
+
+			id := fmt.Sprintf("m%d", len(queries))
+			buckets[id] = bucket
+			queries = append(queries, cwtypes.MetricDataQuery{
+				Id:         aws.String(id),
+				Label:      aws.String(name),
+				MetricStat: &cwtypes.MetricStat{Metric: &metric, Period: aws.Int32(86400), Stat: aws.String("Sum")},
+			})
+		}
+	}
+
+	end := time.Now()
+	start := end.Add(-storageLensLookback)
+
+	// GetMetricData accepts at most 500 queries per call.
+	for i := 0; i < len(queries); i += 500 {
+		batch := queries[i:min(i+500, len(queries))]
+
+		paginator := cloudwatch.NewGetMetricDataPaginator(client, &cloudwatch.GetMetricDataInput{
+			MetricDataQueries: batch,
+			StartTime:         &start,
+			EndTime:           &end,
+			ScanBy:            cwtypes.ScanByTimestampDescending,
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return metrics, fmt.Errorf("unable to get Storage Lens metrics: %w", err)
+			}
+
+			for _, result := range page.MetricDataResults {
+				bucket := buckets[aws.ToString(result.Id)]
+				m := metrics[bucket]
+				updateStorageLensMetrics(&m, aws.ToString(result.Label), result)
+				metrics[bucket] = m
+			}
+		}
+	}
+
+	return metrics, nil
+}
+
+// updateStorageLensMetrics takes the most recent value of a metric, whose
+// results are newest first. Pages may split a result, so values already
+// set aren't overwritten by older ones.
+func updateStorageLensMetrics(m *storageLensMetrics, name string, result cwtypes.MetricDataResult) {
+	if len(result.Values) == 0 {
+		return
+	}
+
+	switch name {
+	case "ObjectCount":
+		if m.ObjectCount == 0 {
+			m.ObjectCount = int64(result.Values[0])
+		}
+	case "StorageBytes":
+		if m.StorageBytes == 0 {
+			m.StorageBytes = int64(result.Values[0])
+		}
+	case "AllRequests":
+		for i, v := range result.Values {
+			if v > 0 && (m.LastActivity == nil || result.Timestamps[i].After(*m.LastActivity)) {
+				m.LastActivity = &result.Timestamps[i]
+				break
+			}
+		}
+	}
+}
+
+func findStorageLensDashboard(ctx context.Context, client awsapi.S3Control, accountID string) (string, string, error) {
+
+	paginator := s3control.NewListStorageLensConfigurationsPaginator(client, &s3control.ListStorageLensConfigurationsInput{AccountId: &accountID})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to list Storage Lens configurations: %w", err)
+		}
+
+		for _, entry := range page.StorageLensConfigurationList {
+			if !entry.IsEnabled {
+				continue
+			}
+
+			out, err := client.GetStorageLensConfiguration(ctx, &s3control.GetStorageLensConfigurationInput{
+				AccountId: &accountID,
+				ConfigId:  entry.Id,
+			}, func(o *s3control.Options) { o.Region = aws.ToString(entry.HomeRegion) })
+			if err != nil {
+				return "", "", fmt.Errorf("unable to get Storage Lens configuration %s: %w", aws.ToString(entry.Id), err)
+			}
+
+			export := out.StorageLensConfiguration.DataExport
+			if export != nil && export.CloudWatchMetrics != nil && export.CloudWatchMetrics.IsEnabled {
+				return aws.ToString(entry.Id), aws.ToString(entry.HomeRegion), nil
+			}
+		}
+	}
+
+	return "", "", nil
+}
+
+func dimension(dimensions []cwtypes.Dimension, name string) (string, bool) {
+	for _, d := range dimensions {
+		if aws.ToString(d.Name) == name {
+			return aws.ToString(d.Value), true
+		}
+	}
+
+	return "", false
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+)
+
+// findingStore spills findings to a file on disk as they are produced so
+// that memory use stays flat no matter how many findings a scan yields.
+type findingStore struct {
+	file *os.File
+	w    *bufio.Writer
+}
+
+// openFindingStore opens the findings file at path, discarding anything
+// written after offset (i.e. findings from an account that didn't finish).
+func openFindingStore(path string, offset int64) (*findingStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &findingStore{file: file, w: bufio.NewWriter(file)}, nil
+}
+
+func (s *findingStore) Add(finding Finding) error {
+	return json.NewEncoder(s.w).Encode(finding)
+}
+
+// Offset flushes pending findings and returns the size of the file.
+func (s *findingStore) Offset() (int64, error) {
+	if err := s.w.Flush(); err != nil {
+		return 0, err
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+// Each streams findings back from disk in the order they were added.
+func (s *findingStore) Each(fn func(Finding) error) error {
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(io.NewSectionReader(s.file, 0, 1<<62)))
+	for {
+		var finding Finding
+		err := dec.Decode(&finding)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(finding); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *findingStore) Close() error {
+	if err := s.w.Flush(); err != nil {
+		s.file.Close()
+		return err
+	}
+
+	return s.file.Close()
+}
+
+// findingStream passes findings to fn in turn, stopping at the first error,
+// as findingStore.Each does. Reports read a scan's findings through one,
+// passing over them as often as they need to rather than holding them all.
+type findingStream func(fn func(Finding) error) error
+
+// sliceStream streams findings already in memory, e.g. a loaded report's.
+func sliceStream(findings []Finding) findingStream {
+	return func(fn func(Finding) error) error {
+		for _, f := range findings {
+			if err := fn(f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// filter streams the findings keep accepts.
+func (s findingStream) filter(keep func(Finding) bool) findingStream {
+	return func(fn func(Finding) error) error {
+		return s(func(f Finding) error {
+			if !keep(f) {
+				return nil
+			}
+			return fn(f)
+		})
+	}
+}
+
+// severityCounts counts the findings of each severity.
+func (s findingStream) severityCounts() (map[Severity]int, error) {
+	counts := map[Severity]int{}
+	err := s(func(f Finding) error {
+		counts[f.Severity]++
+		return nil
+	})
+
+	return counts, err
+}
+
+// collect reads every finding into memory, for reports that need them all
+// at once.
+func (s findingStream) collect() ([]Finding, error) {
+	findings := []Finding{}
+	err := s(func(f Finding) error {
+		findings = append(findings, f)
+		return nil
+	})
+
+	return findings, err
+}
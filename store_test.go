@@ -0,0 +1,76 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFindingStoreStream(t *testing.T) {
+	store, err := openFindingStore(filepath.Join(t.TempDir(), "findings.jsonl"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	for _, f := range []Finding{
+		{Account: "a", Bucket: "one", Check: "public-access", Severity: SeverityCritical},
+		{Account: "a", Bucket: "two", Check: "unused-buckets", Severity: SeverityLow},
+		{Account: "b", Bucket: "three", Check: "public-access", Severity: SeverityHigh},
+	} {
+		if err := store.Add(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	high := findingStream(store.Each).filter(func(f Finding) bool { return f.Severity >= SeverityHigh })
+	counts, err := high.severityCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totalCount(counts) != 2 || counts[SeverityCritical] != 1 || counts[SeverityHigh] != 1 {
+		t.Errorf("severityCounts() = %v, want one CRITICAL and one HIGH", counts)
+	}
+
+	findings, err := high.collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 2 || findings[0].Bucket != "one" || findings[1].Bucket != "three" {
+		t.Errorf("collect() = %v, want buckets one and three in order", findings)
+	}
+}
+
+func TestLoadReportSummary(t *testing.T) {
+	r := savedReport{
+		Time:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Profiles: []string{"a"},
+		Buckets:  map[string]int{"a": 2},
+		Findings: []Finding{{Account: "a", Bucket: "one", Check: "public-access", Message: `"findings":[`}},
+		Scores:   map[string]int{"a": 90},
+		Errors:   []scanError{{Account: "a", Error: "denied"}},
+	}
+	path, err := saveReport(t.TempDir(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full, err := loadReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(full.Findings) != 1 || full.Findings[0].Message != r.Findings[0].Message {
+		t.Errorf("loadReport() findings = %v, want %v", full.Findings, r.Findings)
+	}
+
+	summary, err := loadReportSummary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Findings) != 0 {
+		t.Errorf("loadReportSummary() kept %d findings", len(summary.Findings))
+	}
+	if !summary.Time.Equal(r.Time) || summary.Scores["a"] != 90 || summary.bucketCount() != 2 || len(summary.Errors) != 1 {
+		t.Errorf("loadReportSummary() = %+v, want the report less its findings", summary)
+	}
+}
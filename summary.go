@@ -0,0 +1,184 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// severityPoints weight findings when ranking buckets and accounts by risk.
+var severityPoints = map[Severity]int{
+	SeverityLow:      1,
+	SeverityMedium:   5,
+	SeverityHigh:     20,
+	SeverityCritical: 50,
+}
+
+const summaryTopBuckets = 10
+
+type riskEntry struct {
+	Name     string
+	Points   int
+	Findings int
+}
+
+// printExecutiveSummary writes a one-page overview of the scan for readers
+// who don't need each finding: totals per severity, the riskiest buckets and
+// accounts, and what changed since previous (which may be nil).
+func printExecutiveSummary(w io.Writer, current savedReport, previous *savedReport) {
+	fmt.Fprintf(w, "S3 audit summary, %s\n", formatTime(current.Time))
+	fmt.Fprintf(w, "%d accounts, %d buckets, %d findings\n", len(current.Profiles), current.bucketCount(), len(current.Findings))
+	if len(current.Errors) > 0 {
+		fmt.Fprintf(w, "%d errors: parts of the scan are incomplete, see the findings report\n", len(current.Errors))
+	}
+	fmt.Fprintln(w)
+
+	counts := severityCounts(current.Findings)
+	var previousCounts map[Severity]int
+	if previous != nil {
+		previousCounts = severityCounts(previous.Findings)
+	}
+
+	fmt.Fprintln(w, "Findings by severity")
+	for sev := SeverityCritical; sev >= SeverityLow; sev-- {
+		line := fmt.Sprintf("  %-8s %5d", sev, counts[sev])
+		if previous != nil {
+			line += fmt.Sprintf("  (%+d)", counts[sev]-previousCounts[sev])
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	fmt.Fprintf(w, "\nRiskiest buckets\n")
+	for i, e := range topRisks(current.Findings, summaryTopBuckets, func(f Finding) string {
+		if f.Bucket == "" {
+			return ""
+		}
+		return f.Account + "/" + f.Bucket
+	}) {
+		fmt.Fprintf(w, "  %2d. %-70s %4d points, %d findings\n", i+1, e.Name, e.Points, e.Findings)
+	}
+
+	fmt.Fprintln(w)
+	printLeagueTable(w, current, previous)
+
+	fmt.Fprintf(w, "\nSince the previous scan\n")
+	if previous == nil {
+		fmt.Fprintln(w, "  no previous scan to compare with")
+		return
+	}
+
+	added, resolved := compareFindings(previous.Findings, current.Findings)
+	fmt.Fprintf(w, "  compared with %s\n", formatTime(previous.Time))
+	fmt.Fprintf(w, "  %d new findings (%d HIGH or CRITICAL)\n", len(added), countAtLeast(added, SeverityHigh))
+	fmt.Fprintf(w, "  %d resolved findings (%d HIGH or CRITICAL)\n", len(resolved), countAtLeast(resolved, SeverityHigh))
+}
+
+func severityCounts(findings []Finding) map[Severity]int {
+	counts := map[Severity]int{}
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+
+	return counts
+}
+
+// totalCount is the number of findings counted by severityCounts.
+func totalCount(counts map[Severity]int) int {
+	n := 0
+	for _, count := range counts {
+		n += count
+	}
+
+	return n
+}
+
+func countAtLeast(findings []Finding, sev Severity) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity >= sev {
+			n++
+		}
+	}
+
+	return n
+}
+
+// topRisks ranks the groups given by key (skipping "") by severity points.
+func topRisks(findings []Finding, n int, key func(Finding) string) []riskEntry {
+	entries := map[string]*riskEntry{}
+	for _, f := range findings {
+		k := key(f)
+		if k == "" {
+			continue
+		}
+
+		if entries[k] == nil {
+			entries[k] = &riskEntry{Name: k}
+		}
+		entries[k].Points += severityPoints[f.Severity]
+		entries[k].Findings++
+	}
+
+	ranked := []riskEntry{}
+	for _, e := range entries {
+		ranked = append(ranked, *e)
+	}
+	slices.SortFunc(ranked, func(a, b riskEntry) int {
+		return cmp.Or(cmp.Compare(b.Points, a.Points), cmp.Compare(a.Name, b.Name))
+	})
+
+	return ranked[:min(n, len(ranked))]
+}
+
+// compareFindings returns the findings only in after, and only in before.
+func compareFindings(before []Finding, after []Finding) ([]Finding, []Finding) {
+	beforeKeys := map[string]Finding{}
+	for _, f := range before {
+		beforeKeys[findingKey(f)] = f
+	}
+
+	afterKeys := map[string]Finding{}
+	added := []Finding{}
+	for _, f := range after {
+		k := findingKey(f)
+		afterKeys[k] = f
+		if _, ok := beforeKeys[k]; !ok {
+			added = append(added, f)
+		}
+	}
+
+	resolved := []Finding{}
+	for _, k := range slices.Sorted(maps.Keys(beforeKeys)) {
+		if _, ok := afterKeys[k]; !ok {
+			resolved = append(resolved, beforeKeys[k])
+		}
+	}
+
+	return added, resolved
+}
+
+// printCheckCounts writes the number of findings from each check in each
+// account, for cron emails and scripts that only need the totals.
+func printCheckCounts(w io.Writer, findings findingStream) error {
+	counts := map[string]map[string]int{}
+	err := findings(func(f Finding) error {
+		if counts[f.Account] == nil {
+			counts[f.Account] = map[string]int{}
+		}
+		counts[f.Account][f.Check]++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, account := range slices.Sorted(maps.Keys(counts)) {
+		for _, check := range slices.Sorted(maps.Keys(counts[account])) {
+			fmt.Fprintf(w, "%-20s\t%-30s\t%5d\n", account, check, counts[account][check])
+		}
+	}
+
+	return nil
+}
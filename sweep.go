@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	s3ctltypes "github.com/aws/aws-sdk-go-v2/service/s3control/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/guardian/s3-audit/awsapi"
+)
+
+// sweepPollInterval is how often a Batch Operations job's progress is
+// checked.
+const sweepPollInterval = 30 * time.Second
+
+// sweepMain finds a bucket's public objects from its inventory report and,
+// with -fix, submits a Batch Operations job making them private, waits for it
+// and reports the outcome as findings.
+func sweepMain(args []string) {
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	profile := fs.String("profile", "deployTools", "AWS profile for the bucket's account")
+	bucket := fs.String("bucket", "", "bucket to sweep, which needs an S3 Inventory report with ObjectAccessControlList")
+	fix := fs.Bool("fix", false, "submit a Batch Operations job setting each public object's ACL to private, rather than only listing them")
+	roleARN := fs.String("role-arn", "", "IAM role Batch Operations assumes, allowed s3:PutObjectAcl on the bucket and to read and write the report bucket")
+	reportBucket := fs.String("report-bucket", "", "bucket in the same region for the job's manifest and completion report")
+	priority := fs.Int("priority", 10, "job priority")
+	noWriteFlag := fs.Bool("no-write", false, "make no changes, so -fix fails before submitting the job (also set by S3_AUDIT_NO_WRITE)")
+	actionLog := fs.String("action-log", "", "append every change made, e.g. the job submitted, to this file as JSON lines with request IDs")
+	endpointURL := fs.String("endpoint-url", "", "send AWS requests to this URL instead")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit sweep -bucket bucket [-fix -role-arn arn -report-bucket bucket] [-profile profile]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	check(setEndpoints(*endpointURL, nil), "invalid endpoint")
+	check(setWriteGuard(*noWriteFlag, *actionLog), "invalid -action-log")
+
+	if *bucket == "" || (*fix && (*roleARN == "" || *reportBucket == "")) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config, err := loadConfig(ctx, *profile)
+	check(err, "unable to load AWS config")
+
+	identity, err := sts.NewFromConfig(config).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	check(err, "unable to get caller identity")
+	accountID := aws.ToString(identity.Account)
+
+	client := newAWSClients(config).s3
+	location, err := client.GetBucketLocation(ctx, &s3.GetBucketLocationInput{Bucket: bucket})
+	check(err, "unable to get bucket location")
+	region := string(location.LocationConstraint)
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	keys := []string{}
+	report, err := readPublicInventory(ctx, client, *bucket, region, func(key string) { keys = append(keys, key) })
+	check(err, "unable to read inventory")
+	if report == "" {
+		check(errors.New("no CSV inventory report with ObjectAccessControlList"), "unable to read inventory")
+	}
+
+	fmt.Printf("Inventory report %s lists %d public objects in %s\n", report, len(keys), *bucket)
+	if !*fix {
+		for _, key := range keys[:min(len(keys), maxPublicObjectKeys)] {
+			fmt.Printf("  %s\n", key)
+		}
+		if len(keys) > 0 {
+			fmt.Println("Run again with -fix to make them private with a Batch Operations job.")
+		}
+		return
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	s := &sweep{
+		client:    client,
+		control:   s3control.NewFromConfig(config, func(o *s3control.Options) { o.Region = region }),
+		log:       component("sweep").With("bucket", *bucket),
+		accountID: accountID,
+		bucket:    *bucket,
+		region:    region,
+		reports:   *reportBucket,
+		prefix:    fmt.Sprintf("s3-audit/sweeps/%s/%s", *bucket, time.Now().UTC().Format("20060102T150405Z")),
+	}
+
+	jobID, err := s.submit(ctx, keys, *roleARN, int32(*priority))
+	check(err, "unable to submit Batch Operations job")
+	s.log.Info("submitted Batch Operations job", "job", jobID, "objects", len(keys))
+
+	job, err := s.wait(ctx, jobID)
+	check(err, "unable to track Batch Operations job")
+
+	failed, err := s.failedKeys(ctx, jobID)
+	if err != nil {
+		s.log.Warn("unable to read completion report", "job", jobID, "error", err)
+	}
+
+	printReport(os.Stdout, sliceStream(s.findings(*profile, job, failed)), "")
+}
+
+// sweep is a Batch Operations job making one bucket's public objects
+// private.
+type sweep struct {
+	client    awsapi.S3
+	control   awsapi.S3Control
+	log       *slog.Logger
+	accountID string
+	bucket    string
+	region    string
+	reports   string // bucket for the manifest and completion report
+	prefix    string // where in it
+}
+
+// submit uploads a CSV manifest of keys and creates a job setting their ACLs
+// to private, returning its ID. The job runs without confirmation.
+func (s *sweep) submit(ctx context.Context, keys []string, roleARN string, priority int32) (string, error) {
+	var manifest strings.Builder
+	w := csv.NewWriter(&manifest)
+	for _, key := range keys {
+		// Manifest keys must be URL-encoded.
+		w.Write([]string{s.bucket, url.QueryEscape(key)})
+	}
+	w.Flush()
+
+	manifestKey := s.prefix + "/manifest.csv"
+	put, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &s.reports,
+		Key:    &manifestKey,
+		Body:   strings.NewReader(manifest.String()),
+	}, inRegion(s.region))
+	if err != nil {
+		return "", fmt.Errorf("unable to write manifest: %w", err)
+	}
+
+	out, err := s.control.CreateJob(ctx, &s3control.CreateJobInput{
+		AccountId:            &s.accountID,
+		ClientRequestToken:   aws.String(fmt.Sprintf("s3-audit-%d", time.Now().UnixNano())),
+		ConfirmationRequired: aws.Bool(false),
+		Description:          aws.String("s3-audit: make public objects private in " + s.bucket),
+		Priority:             &priority,
+		RoleArn:              &roleARN,
+		Operation: &s3ctltypes.JobOperation{
+			S3PutObjectAcl: &s3ctltypes.S3SetObjectAclOperation{
+				AccessControlPolicy: &s3ctltypes.S3AccessControlPolicy{CannedAccessControlList: s3ctltypes.S3CannedAccessControlListPrivate},
+			},
+		},
+		Manifest: &s3ctltypes.JobManifest{
+			Spec: &s3ctltypes.JobManifestSpec{
+				Format: s3ctltypes.JobManifestFormatS3BatchOperationsCsv20180820,
+				Fields: []s3ctltypes.JobManifestFieldName{s3ctltypes.JobManifestFieldNameBucket, s3ctltypes.JobManifestFieldNameKey},
+			},
+			Location: &s3ctltypes.JobManifestLocation{
+				ObjectArn: aws.String("arn:aws:s3:::" + s.reports + "/" + manifestKey),
+				ETag:      put.ETag,
+			},
+		},
+		Report: &s3ctltypes.JobReport{
+			Enabled:     true,
+			Bucket:      aws.String("arn:aws:s3:::" + s.reports),
+			Prefix:      &s.prefix,
+			Format:      s3ctltypes.JobReportFormatReportCsv20180820,
+			ReportScope: s3ctltypes.JobReportScopeFailedTasksOnly,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(out.JobId), nil
+}
+
+// wait polls the job until it finishes. Cancelling ctx stops waiting, not
+// the job.
+func (s *sweep) wait(ctx context.Context, jobID string) (*s3ctltypes.JobDescriptor, error) {
+	for {
+		out, err := s.control.DescribeJob(ctx, &s3control.DescribeJobInput{AccountId: &s.accountID, JobId: &jobID})
+		if err != nil {
+			return nil, err
+		}
+
+		job := out.Job
+		switch job.Status {
+		case s3ctltypes.JobStatusComplete, s3ctltypes.JobStatusFailed, s3ctltypes.JobStatusCancelled:
+			return job, nil
+		}
+
+		logger := s.log.With("job", jobID, "status", job.Status)
+		if p := job.ProgressSummary; p != nil {
+			logger = logger.With("succeeded", aws.ToInt64(p.NumberOfTasksSucceeded), "failed", aws.ToInt64(p.NumberOfTasksFailed), "total", aws.ToInt64(p.TotalNumberOfTasks))
+		}
+		logger.Info("waiting for Batch Operations job")
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("stopped waiting for job %s, which carries on: %w", jobID, ctx.Err())
+		case <-time.After(sweepPollInterval):
+		}
+	}
+}
+
+// completionReport is the manifest Batch Operations writes alongside the
+// result files of a completion report.
+type completionReport struct {
+	Results []struct {
+		TaskExecutionStatus string `json:"TaskExecutionStatus"`
+		Key                 string `json:"Key"`
+	} `json:"Results"`
+}
+
+// failedKeys reads the keys of the objects the job failed on from its
+// completion report.
+func (s *sweep) failedKeys(ctx context.Context, jobID string) ([]string, error) {
+	manifestKey := fmt.Sprintf("%s/job-%s/manifest.json", s.prefix, jobID)
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &s.reports, Key: &manifestKey}, inRegion(s.region))
+	if isErrorCode(err, "NoSuchKey") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	var report completionReport
+	if err := json.NewDecoder(out.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("invalid completion report s3://%s/%s: %w", s.reports, manifestKey, err)
+	}
+
+	keys := []string{}
+	for _, result := range report.Results {
+		if result.TaskExecutionStatus != "failed" {
+			continue
+		}
+
+		out, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &s.reports, Key: &result.Key}, inRegion(s.region))
+		if err != nil {
+			return nil, err
+		}
+
+		r := csv.NewReader(out.Body)
+		r.FieldsPerRecord = -1
+		for {
+			record, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				out.Body.Close()
+				return nil, fmt.Errorf("unable to read completion report s3://%s/%s: %w", s.reports, result.Key, err)
+			}
+
+			if len(record) > 1 {
+				key, err := url.QueryUnescape(record[1])
+				if err != nil {
+					key = record[1]
+				}
+				keys = append(keys, key)
+			}
+		}
+		out.Body.Close()
+	}
+
+	return keys, nil
+}
+
+// findings reports the job's outcome as public-objects findings: objects
+// it failed to make private are still public.
+func (s *sweep) findings(profile string, job *s3ctltypes.JobDescriptor, failed []string) []Finding {
+	succeeded, total := int64(0), int64(0)
+	if p := job.ProgressSummary; p != nil {
+		succeeded, total = aws.ToInt64(p.NumberOfTasksSucceeded), aws.ToInt64(p.TotalNumberOfTasks)
+	}
+	jobID := aws.ToString(job.JobId)
+
+	base := Finding{Account: profile, AccountID: s.accountID, Bucket: s.bucket, Check: "public-objects"}
+	findings := []Finding{}
+
+	if succeeded > 0 {
+		f := base
+		f.Severity = SeverityLow
+		f.Message = fmt.Sprintf("Batch Operations job %s made %d public objects private", jobID, succeeded)
+		findings = append(findings, f)
+	}
+
+	if remaining := total - succeeded; remaining > 0 || job.Status != s3ctltypes.JobStatusComplete {
+		f := base
+		f.Severity = SeverityHigh
+		f.Message = fmt.Sprintf("Batch Operations job %s finished %s, leaving %d objects public", jobID, strings.ToLower(string(job.Status)), remaining)
+		for _, reason := range job.FailureReasons {
+			f.Details = append(f.Details, fmt.Sprintf("%s: %s", aws.ToString(reason.FailureCode), aws.ToString(reason.FailureReason)))
+		}
+		for _, key := range failed[:min(len(failed), maxPublicObjectKeys)] {
+			f.Details = append(f.Details, "still public: "+key)
+		}
+		findings = append(findings, f)
+	}
+
+	return findings
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// reportLocation and reportLayout are how times are shown in reports, set
+// from the config file. The default of UTC and ISO 8601 lines up with
+// CloudTrail.
+var (
+	reportLocation = time.UTC
+	reportLayout   = time.RFC3339
+)
+
+// setReportTime applies the config's timezone (an IANA name such as
+// Europe/London) and Go time layout, where given.
+func setReportTime(timezone string, layout string) error {
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone: %w", err)
+		}
+		reportLocation = loc
+	}
+
+	if layout != "" {
+		reportLayout = layout
+	}
+
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.In(reportLocation).Format(reportLayout)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer makes the scan's spans: one per account, bucket and AWS operation.
+// Until setupTracing installs an exporter they go nowhere.
+var tracer = otel.Tracer("github.com/guardian/s3-audit")
+
+// traceRequests adds a span for every AWS request, set when tracing.
+var traceRequests bool
+
+// setupTracing exports spans over OTLP/HTTP to the endpoint, e.g.
+// http://localhost:4318, or the one in the standard OTEL_EXPORTER_OTLP_*
+// environment variables. Without either, nothing is traced. The returned
+// function flushes the spans still buffered.
+func setupTracing(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	opts := []otlptracehttp.Option{}
+	switch {
+	case endpoint != "":
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	case os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "":
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", "s3-audit")))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		component("tracing").Warn("unable to export spans", "error", err)
+	}))
+	traceRequests = true
+
+	return provider.Shutdown, nil
+}
+
+// endSpan ends the span, recording err if there was one.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// addRequestTracing adds a span to a client's middleware stack for each
+// operation, covering its retries, so that throttling can be told apart from
+// slow responses.
+func addRequestTracing(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("s3-audit/traceRequests", traceRequest), middleware.Before)
+}
+
+func traceRequest(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	service, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+	ctx, span := tracer.Start(ctx, service+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "aws-api"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", operation),
+			attribute.String("cloud.region", awsmiddleware.GetRegion(ctx)),
+		),
+	)
+
+	out, metadata, err := next.HandleInitialize(ctx, in)
+
+	if id, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+		span.SetAttributes(attribute.String("aws.request_id", id))
+	}
+	if attempts, ok := retry.GetAttemptResults(metadata); ok {
+		throttled := 0
+		throttles := retry.IsErrorThrottles(retry.DefaultThrottles)
+		for _, attempt := range attempts.Results {
+			if attempt.Err != nil && throttles.IsErrorThrottle(attempt.Err).Bool() {
+				throttled++
+			}
+		}
+		span.SetAttributes(attribute.Int("aws.retries", max(len(attempts.Results)-1, 0)), attribute.Int("aws.throttled", throttled))
+	}
+
+	// A cancelled scan isn't an error in the request.
+	if errors.Is(err, context.Canceled) {
+		span.End()
+	} else {
+		endSpan(span, err)
+	}
+
+	return out, metadata, err
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// triageEntry is a decision made about a finding during triage.
+type triageEntry struct {
+	AccountID string    `json:"accountId"`
+	Bucket    string    `json:"bucket"`
+	Check     string    `json:"check"`
+	Subject   string    `json:"subject,omitempty"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+func newTriageEntry(f Finding, at time.Time) triageEntry {
+	account := f.AccountID
+	if account == "" {
+		account = f.Account
+	}
+
+	return triageEntry{AccountID: account, Bucket: f.Bucket, Check: f.Check, Subject: f.Subject, Message: f.Message, Time: at}
+}
+
+func (e triageEntry) key() string {
+	return findingKey(Finding{AccountID: e.AccountID, Bucket: e.Bucket, Check: e.Check, Subject: e.Subject})
+}
+
+// matches reports whether the entry is for the finding. Entries saved before
+// findings had subjects have none, and are matched on the message instead.
+func (e triageEntry) matches(f Finding) bool {
+	if e.Subject == "" && f.Subject != "" {
+		legacy := f
+		legacy.Subject = ""
+		return e.Message == f.Message && e.key() == findingKey(legacy)
+	}
+
+	return e.key() == findingKey(f)
+}
+
+// triage is the findings suppressed, which are left out of later reports,
+// and those marked for remediation, e.g. for s3-audit sweep.
+type triage struct {
+	Suppressed []triageEntry `json:"suppressed"`
+	Remediate  []triageEntry `json:"remediate"`
+
+	version string // of the file as read, see triageLocation.read
+}
+
+func defaultTriageFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "s3-audit", "triage.json")
+}
+
+// loadTriage reads the triage file, which may not exist yet.
+func loadTriage(ctx context.Context, loc triageLocation) (*triage, error) {
+	t := &triage{Suppressed: []triageEntry{}, Remediate: []triageEntry{}}
+	data, version, err := loc.read(ctx)
+	if err != nil || data == nil {
+		return t, err
+	}
+	t.version = version
+
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("invalid triage file %s: %w", loc.path, err)
+	}
+
+	return t, nil
+}
+
+// save writes the triage file, failing with errTriageChanged if it has
+// changed since it was read.
+func (t *triage) save(ctx context.Context, loc triageLocation) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	version, err := loc.write(ctx, data, t.version)
+	if err != nil {
+		return err
+	}
+
+	t.version = version
+	return nil
+}
+
+// update makes a change to the triage file and saves it. If someone else has
+// saved it since it was read, it is read again and the change made to their
+// copy instead, so that people triaging together don't undo each other's
+// decisions.
+func (t *triage) update(ctx context.Context, loc triageLocation, change func(*triage)) error {
+	for attempt := 1; ; attempt++ {
+		change(t)
+		err := t.save(ctx, loc)
+		if !errors.Is(err, errTriageChanged) || attempt == 3 {
+			return err
+		}
+
+		latest, err := loadTriage(ctx, loc)
+		if err != nil {
+			return err
+		}
+		*t = *latest
+	}
+}
+
+func (t *triage) isSuppressed(f Finding) bool {
+	return containsFinding(t.Suppressed, f)
+}
+
+func (t *triage) isMarked(f Finding) bool {
+	return containsFinding(t.Remediate, f)
+}
+
+// setSuppressed suppresses the finding, or unsuppresses it.
+func (t *triage) setSuppressed(f Finding, on bool, at time.Time) {
+	t.Suppressed = setFinding(t.Suppressed, f, on, at)
+}
+
+// setMarked marks the finding for remediation, or unmarks it.
+func (t *triage) setMarked(f Finding, on bool, at time.Time) {
+	t.Remediate = setFinding(t.Remediate, f, on, at)
+}
+
+// unsuppressed returns the findings that haven't been suppressed.
+func (t *triage) unsuppressed(findings []Finding) []Finding {
+	return slices.DeleteFunc(slices.Clone(findings), t.isSuppressed)
+}
+
+func containsFinding(entries []triageEntry, f Finding) bool {
+	return slices.ContainsFunc(entries, func(e triageEntry) bool { return e.matches(f) })
+}
+
+// setFinding adds an entry for the finding, or removes it. Adding one that
+// is already there leaves it as it was, as does removing one that isn't.
+func setFinding(entries []triageEntry, f Finding, on bool, at time.Time) []triageEntry {
+	i := slices.IndexFunc(entries, func(e triageEntry) bool { return e.matches(f) })
+	switch {
+	case on && i < 0:
+		return append(entries, newTriageEntry(f, at))
+	case !on && i >= 0:
+		return slices.Delete(entries, i, i+1)
+	}
+
+	return entries
+}
+
+// triageMain browses the findings of a saved report, by default the latest,
+// in a terminal UI where they can be suppressed, marked for remediation or
+// opened in the AWS console.
+func triageMain(args []string) {
+	flags := flag.NewFlagSet("triage", flag.ExitOnError)
+	historyDir := flags.String("history-dir", defaultHistoryDir(), "directory where completed scans are saved")
+	triageFile := flags.String("triage", defaultTriageFile(), "file recording suppressed findings and those marked for remediation: a path, s3://bucket/key or ssm:/parameter-name")
+	profile := flags.String("profile", "deployTools", "AWS profile to read and write an S3 or SSM triage file with")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: s3-audit triage [report.json]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var report *savedReport
+	var err error
+	if flags.NArg() == 1 {
+		report, err = loadReport(flags.Arg(0))
+	} else {
+		report, err = latestReport(*historyDir)
+	}
+	check(err, "unable to load report")
+	if report == nil {
+		check(fmt.Errorf("no reports in %s", *historyDir), "unable to load report")
+	}
+
+	loc := triageLocation{path: *triageFile, profile: *profile}
+	t, err := loadTriage(context.Background(), loc)
+	check(err, "unable to load triage file")
+
+	_, err = tea.NewProgram(newTriageModel(report, t, loc), tea.WithAltScreen()).Run()
+	check(err, "unable to run terminal UI")
+}
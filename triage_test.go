@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTriageEntryMatches(t *testing.T) {
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	f := Finding{Account: "prod", AccountID: "111111111111", Bucket: "logs", Check: "lifecycle", Subject: "expiry", Message: "no expiry rule"}
+
+	tests := []struct {
+		name  string
+		entry triageEntry
+		want  bool
+	}{
+		{"same finding", newTriageEntry(f, at), true},
+		{"other subject", triageEntry{AccountID: "111111111111", Bucket: "logs", Check: "lifecycle", Subject: "transition", Message: "no expiry rule"}, false},
+		{"other bucket", triageEntry{AccountID: "111111111111", Bucket: "data", Check: "lifecycle", Subject: "expiry"}, false},
+		{"other check", triageEntry{AccountID: "111111111111", Bucket: "logs", Check: "versioning", Subject: "expiry"}, false},
+		{"other account", triageEntry{AccountID: "222222222222", Bucket: "logs", Check: "lifecycle", Subject: "expiry"}, false},
+		{"message changed", triageEntry{AccountID: "111111111111", Bucket: "logs", Check: "lifecycle", Subject: "expiry", Message: "old message"}, true},
+		{"legacy entry", triageEntry{AccountID: "111111111111", Bucket: "logs", Check: "lifecycle", Message: "no expiry rule"}, true},
+		{"legacy entry, other message", triageEntry{AccountID: "111111111111", Bucket: "logs", Check: "lifecycle", Message: "no transition rule"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.matches(f); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Findings from reports without account IDs are keyed on the profile.
+	byProfile := Finding{Account: "prod", Bucket: "logs", Check: "lifecycle"}
+	if !newTriageEntry(byProfile, at).matches(byProfile) {
+		t.Error("entry doesn't match the finding it was made from without an account ID")
+	}
+}
+
+func TestSetFinding(t *testing.T) {
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	a := Finding{AccountID: "111111111111", Bucket: "a", Check: "versioning"}
+	b := Finding{AccountID: "111111111111", Bucket: "b", Check: "versioning"}
+
+	tr := &triage{}
+	tr.setSuppressed(a, true, at)
+	tr.setSuppressed(a, true, at.Add(time.Hour))
+	tr.setSuppressed(b, false, at)
+	if len(tr.Suppressed) != 1 || !tr.Suppressed[0].Time.Equal(at) {
+		t.Fatalf("Suppressed = %+v, want a once, as first suppressed", tr.Suppressed)
+	}
+	if !tr.isSuppressed(a) || tr.isSuppressed(b) || tr.isMarked(a) {
+		t.Errorf("isSuppressed(a), isSuppressed(b), isMarked(a) = %v, %v, %v; want true, false, false", tr.isSuppressed(a), tr.isSuppressed(b), tr.isMarked(a))
+	}
+	if got := tr.unsuppressed([]Finding{a, b}); len(got) != 1 || got[0].Bucket != "b" {
+		t.Errorf("unsuppressed() = %+v, want b", got)
+	}
+
+	tr.setSuppressed(a, false, at)
+	if tr.isSuppressed(a) {
+		t.Error("a is still suppressed")
+	}
+}
+
+func TestTriageUpdate(t *testing.T) {
+	loc := triageLocation{path: filepath.Join(t.TempDir(), "triage", "triage.json")}
+	a := Finding{AccountID: "111111111111", Bucket: "a", Check: "versioning"}
+	b := Finding{AccountID: "111111111111", Bucket: "b", Check: "versioning"}
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	first, err := loadTriage(t.Context(), loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.update(t.Context(), loc, func(tr *triage) { tr.setSuppressed(a, true, at) }); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := loadTriage(t.Context(), loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := second.update(t.Context(), loc, func(tr *triage) { tr.setMarked(b, true, at) }); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := loadTriage(t.Context(), loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !saved.isSuppressed(a) || !saved.isMarked(b) {
+		t.Errorf("saved triage = %+v, want a suppressed and b marked", saved)
+	}
+}
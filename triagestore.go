@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// triageLocation is where the triage file is kept: a local path, an S3
+// object (s3://bucket/key) or an SSM parameter (ssm:/name), so that one list
+// of exemptions can serve everywhere the tool runs.
+type triageLocation struct {
+	path    string
+	profile string // AWS profile to read S3 and SSM with
+}
+
+// errTriageChanged is returned by triageLocation.write when the file has
+// been written by someone else since it was read.
+var errTriageChanged = errors.New("triage file changed since it was read")
+
+// read returns the contents of the triage file, or nil if it doesn't exist
+// yet, and its version: the S3 object's ETag or the SSM parameter's version.
+// Local files, which aren't shared, have none.
+func (l triageLocation) read(ctx context.Context) ([]byte, string, error) {
+	switch {
+	case strings.HasPrefix(l.path, "s3://"):
+		return l.readS3(ctx)
+	case strings.HasPrefix(l.path, "ssm:"):
+		return l.readSSM(ctx)
+	}
+
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", nil
+	}
+	return data, "", err
+}
+
+// write replaces the triage file, if it is still at the version read (or
+// still doesn't exist, if that is empty), returning the new version.
+// Otherwise it fails with errTriageChanged.
+func (l triageLocation) write(ctx context.Context, data []byte, version string) (string, error) {
+	switch {
+	case strings.HasPrefix(l.path, "s3://"):
+		return l.writeS3(ctx, data, version)
+	case strings.HasPrefix(l.path, "ssm:"):
+		return l.writeSSM(ctx, data, version)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return "", err
+	}
+	return "", os.WriteFile(l.path, data, 0o600)
+}
+
+// cachedObject is the last copy of an S3 triage file read, kept so it is
+// only downloaded again when its ETag changes.
+type cachedObject struct {
+	ETag string `json:"etag"`
+	Data []byte `json:"data"`
+}
+
+func (l triageLocation) cachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	sum := sha256.Sum256([]byte(l.path))
+	return filepath.Join(dir, "s3-audit-triage", hex.EncodeToString(sum[:8])+".json")
+}
+
+func (l triageLocation) readCache() cachedObject {
+	var cached cachedObject
+	if data, err := os.ReadFile(l.cachePath()); err == nil {
+		json.Unmarshal(data, &cached)
+	}
+
+	return cached
+}
+
+// writeCache saves the object read or written. Failing to is only logged,
+// as the next read will download it again.
+func (l triageLocation) writeCache(etag string, data []byte) {
+	path := l.cachePath()
+	cached, err := json.Marshal(cachedObject{ETag: etag, Data: data})
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(path), 0o700); err == nil {
+			err = os.WriteFile(path, cached, 0o600)
+		}
+	}
+	if err != nil {
+		component("triage").Warn("unable to cache triage file", "path", path, "error", err)
+	}
+}
+
+// s3Client returns a client for the triage file's bucket, in its region.
+func (l triageLocation) s3Client(ctx context.Context) (*s3.Client, string, string, error) {
+	bucket, key, err := parseS3URL(l.path)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	config, err := loadConfig(ctx, l.profile)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("unable to load AWS config: %w", err)
+	}
+
+	client, err := regionalS3Client(ctx, config, bucket)
+	return client, bucket, key, err
+}
+
+// parseS3URL splits s3://bucket/key.
+func parseS3URL(u string) (string, string, error) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(u, "s3://"), "/")
+	if !strings.HasPrefix(u, "s3://") || bucket == "" || key == "" {
+		return "", "", fmt.Errorf("invalid S3 location %s: must be s3://bucket/key", u)
+	}
+
+	return bucket, key, nil
+}
+
+// regionalS3Client returns a client for the bucket's region.
+func regionalS3Client(ctx context.Context, config aws.Config, bucket string) (*s3.Client, error) {
+	region, err := bucketRegion(ctx, newAWSClients(config).s3, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return s3.NewFromConfig(config, func(o *s3.Options) {
+		o.Region = region
+		o.UsePathStyle = endpoints.s3Endpoint() != ""
+	}), nil
+}
+
+func (l triageLocation) readS3(ctx context.Context) ([]byte, string, error) {
+	client, bucket, key, err := l.s3Client(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cached := l.readCache()
+	input := &s3.GetObjectInput{Bucket: &bucket, Key: &key}
+	if cached.ETag != "" {
+		input.IfNoneMatch = aws.String(cached.ETag)
+	}
+
+	out, err := client.GetObject(ctx, input)
+	if isNotModified(err) {
+		return cached.Data, cached.ETag, nil
+	}
+	if isErrorCode(err, "NoSuchKey") {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read %s: %w", l.path, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read %s: %w", l.path, err)
+	}
+
+	l.writeCache(aws.ToString(out.ETag), data)
+	return data, aws.ToString(out.ETag), nil
+}
+
+// writeS3 writes the object if its ETag is still the version read, or if it
+// still doesn't exist.
+func (l triageLocation) writeS3(ctx context.Context, data []byte, version string) (string, error) {
+	client, bucket, key, err := l.s3Client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:      &bucket,
+		Key:         &key,
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}
+	if version != "" {
+		input.IfMatch = aws.String(version)
+	} else {
+		input.IfNoneMatch = aws.String("*")
+	}
+
+	out, err := client.PutObject(ctx, input)
+	if isErrorCode(err, "PreconditionFailed") || isErrorCode(err, "ConditionalRequestConflict") {
+		return "", errTriageChanged
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to write %s: %w", l.path, err)
+	}
+
+	l.writeCache(aws.ToString(out.ETag), data)
+	return aws.ToString(out.ETag), nil
+}
+
+// isNotModified reports whether a conditional request failed because the
+// object hasn't changed.
+func isNotModified(err error) bool {
+	var re interface{ HTTPStatusCode() int }
+	return isErrorCode(err, "NotModified") || (errors.As(err, &re) && re.HTTPStatusCode() == 304)
+}
+
+func (l triageLocation) ssmClient(ctx context.Context) (*ssm.Client, error) {
+	config, err := loadConfig(ctx, l.profile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS config: %w", err)
+	}
+
+	return ssm.NewFromConfig(config), nil
+}
+
+func (l triageLocation) readSSM(ctx context.Context) ([]byte, string, error) {
+	client, err := l.ssmClient(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	out, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(strings.TrimPrefix(l.path, "ssm:")),
+		WithDecryption: aws.Bool(true),
+	})
+	var notFound *ssmtypes.ParameterNotFound
+	if errors.As(err, &notFound) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read %s: %w", l.path, err)
+	}
+
+	return []byte(aws.ToString(out.Parameter.Value)), strconv.FormatInt(out.Parameter.Version, 10), nil
+}
+
+// writeSSM writes the parameter if it is still at the version read, or if it
+// still doesn't exist. SSM has no conditional writes, so the version is
+// compared first; a write in between the two isn't noticed.
+func (l triageLocation) writeSSM(ctx context.Context, data []byte, version string) (string, error) {
+	client, err := l.ssmClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	name := aws.String(strings.TrimPrefix(l.path, "ssm:"))
+	if version != "" {
+		current, err := client.GetParameter(ctx, &ssm.GetParameterInput{Name: name})
+		if err != nil {
+			return "", fmt.Errorf("unable to read %s: %w", l.path, err)
+		}
+		if strconv.FormatInt(current.Parameter.Version, 10) != version {
+			return "", errTriageChanged
+		}
+	}
+
+	// Intelligent-Tiering moves the parameter to the advanced tier once it
+	// outgrows the standard tier's 4 KB. Without a version, the parameter
+	// is only created, not overwritten.
+	out, err := client.PutParameter(ctx, &ssm.PutParameterInput{
+		Name:      name,
+		Value:     aws.String(string(data)),
+		Type:      ssmtypes.ParameterTypeString,
+		Tier:      ssmtypes.ParameterTierIntelligentTiering,
+		Overwrite: aws.Bool(version != ""),
+	})
+	var exists *ssmtypes.ParameterAlreadyExists
+	if errors.As(err, &exists) {
+		return "", errTriageChanged
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to write %s: %w", l.path, err)
+	}
+
+	return strconv.FormatInt(out.Version, 10), nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/support"
+)
+
+const trustedAdvisorBucketCheck = "Amazon S3 Bucket Permissions"
+
+// trustedAdvisorResult is Trusted Advisor's view of a bucket.
+type trustedAdvisorResult struct {
+	Status  string   `json:"status"`  // "ok", "warning" or "error"
+	Reasons []string `json:"reasons"` // e.g. "Policy Allows Access"
+}
+
+func (r trustedAdvisorResult) flagged() bool {
+	return r.Status == "warning" || r.Status == "error"
+}
+
+// getTrustedAdvisorResults maps bucket names to the results of the S3 Bucket
+// Permissions check. The Support API needs a Business or Enterprise support
+// plan; callers should treat an error as "no signal".
+func getTrustedAdvisorResults(ctx context.Context, config aws.Config) (map[string]trustedAdvisorResult, error) {
+	// The Support API is only available in us-east-1.
+	client := support.NewFromConfig(config, func(o *support.Options) { o.Region = "us-east-1" })
+
+	checks, err := client.DescribeTrustedAdvisorChecks(ctx, &support.DescribeTrustedAdvisorChecksInput{Language: aws.String("en")})
+	if err != nil {
+		return nil, err
+	}
+
+	var checkID string
+	columns := map[string]int{}
+	for _, check := range checks.Checks {
+		if aws.ToString(check.Name) != trustedAdvisorBucketCheck {
+			continue
+		}
+
+		checkID = aws.ToString(check.Id)
+		for i, column := range check.Metadata {
+			columns[aws.ToString(column)] = i
+		}
+	}
+
+	bucketColumn, ok := columns["Bucket Name"]
+	if checkID == "" || !ok {
+		return nil, fmt.Errorf("no %q check found", trustedAdvisorBucketCheck)
+	}
+
+	out, err := client.DescribeTrustedAdvisorCheckResult(ctx, &support.DescribeTrustedAdvisorCheckResultInput{
+		CheckId:  &checkID,
+		Language: aws.String("en"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[string]trustedAdvisorResult{}
+	if out.Result == nil {
+		return results, nil
+	}
+
+	for _, resource := range out.Result.FlaggedResources {
+		if resource.IsSuppressed || bucketColumn >= len(resource.Metadata) {
+			continue
+		}
+
+		result := trustedAdvisorResult{Status: strings.ToLower(aws.ToString(resource.Status)), Reasons: []string{}}
+		for _, column := range []string{"ACL Allows List", "ACL Allows Upload/Delete", "Policy Allows Access"} {
+			i, ok := columns[column]
+			if ok && i < len(resource.Metadata) && aws.ToString(resource.Metadata[i]) == "Yes" {
+				result.Reasons = append(result.Reasons, column)
+			}
+		}
+
+		results[aws.ToString(resource.Metadata[bucketColumn])] = result
+	}
+
+	return results, nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// triageModel is the terminal UI for s3-audit triage: a list of findings
+// above a pane with the details of the selected one.
+type triageModel struct {
+	report *savedReport
+	triage *triage
+	loc    triageLocation // of the triage file, saved after every change
+
+	findings []Finding // those shown
+	showAll  bool      // include suppressed findings
+	cursor   int
+	top      int // first finding in view
+	width    int
+	height   int
+	status   string
+}
+
+func newTriageModel(report *savedReport, t *triage, loc triageLocation) *triageModel {
+	m := &triageModel{report: report, triage: t, loc: loc, width: 80, height: 24}
+	m.filter()
+	return m
+}
+
+// detailLines is the height of the details pane.
+const detailLines = 10
+
+func (m *triageModel) Init() tea.Cmd { return nil }
+
+func (m *triageModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tea.KeyMsg:
+		m.status = ""
+		switch msg.String() {
+		case "q", "ctrl+c", "esc":
+			return m, tea.Quit
+		case "up", "k":
+			m.move(-1)
+		case "down", "j":
+			m.move(1)
+		case "pgup":
+			m.move(-m.listLines())
+		case "pgdown", " ":
+			m.move(m.listLines())
+		case "home", "g":
+			m.move(-len(m.findings))
+		case "end", "G":
+			m.move(len(m.findings))
+		case "s":
+			m.act((*triage).setSuppressed, "suppressed", (*triage).isSuppressed)
+			m.filter()
+		case "r":
+			m.act((*triage).setMarked, "marked for remediation", (*triage).isMarked)
+		case "a":
+			m.showAll = !m.showAll
+			m.filter()
+		case "o":
+			if f, ok := m.selected(); ok {
+				if err := openBrowser(consoleURL(f)); err != nil {
+					m.status = "unable to open browser: " + err.Error()
+				} else {
+					m.status = "opened " + consoleURL(f)
+				}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// act toggles a triage decision on the selected finding and saves it,
+// along with any others' decisions saved meanwhile.
+func (m *triageModel) act(set func(*triage, Finding, bool, time.Time), done string, is func(*triage, Finding) bool) {
+	f, ok := m.selected()
+	if !ok {
+		return
+	}
+
+	on := !is(m.triage, f)
+	err := m.triage.update(context.Background(), m.loc, func(t *triage) { set(t, f, on, time.Now()) })
+	if err != nil {
+		set(m.triage, f, !on, time.Now())
+		m.status = "unable to save triage file: " + err.Error()
+		return
+	}
+
+	if is(m.triage, f) {
+		m.status = f.Bucket + " " + done
+	} else {
+		m.status = f.Bucket + " no longer " + done
+	}
+}
+
+// filter updates the findings shown, keeping the cursor in range.
+func (m *triageModel) filter() {
+	if m.showAll {
+		m.findings = m.report.Findings
+	} else {
+		m.findings = m.triage.unsuppressed(m.report.Findings)
+	}
+	m.move(0)
+}
+
+func (m *triageModel) move(by int) {
+	m.cursor = max(0, min(m.cursor+by, len(m.findings)-1))
+	if m.cursor < m.top {
+		m.top = m.cursor
+	}
+	if m.cursor >= m.top+m.listLines() {
+		m.top = m.cursor - m.listLines() + 1
+	}
+}
+
+func (m *triageModel) selected() (Finding, bool) {
+	if len(m.findings) == 0 {
+		return Finding{}, false
+	}
+
+	return m.findings[m.cursor], true
+}
+
+// listLines is the number of findings in view, leaving room for the
+// header, details pane and help.
+func (m *triageModel) listLines() int {
+	return max(1, m.height-detailLines-4)
+}
+
+func (m *triageModel) View() string {
+	b := &strings.Builder{}
+	suppressed := len(m.report.Findings) - len(m.triage.unsuppressed(m.report.Findings))
+	fmt.Fprintf(b, "s3-audit triage: scan of %s, %d findings (%d suppressed)\n", formatTime(m.report.Time), len(m.report.Findings), suppressed)
+
+	for i := m.top; i < min(m.top+m.listLines(), len(m.findings)); i++ {
+		f := m.findings[i]
+		cursor := " "
+		if i == m.cursor {
+			cursor = ">"
+		}
+		flags := ""
+		if m.triage.isSuppressed(f) {
+			flags += "S"
+		}
+		if m.triage.isMarked(f) {
+			flags += "R"
+		}
+		line := fmt.Sprintf("%s %-2s %-8s %-20s %-40s %s", cursor, flags, f.Severity, f.Account, f.Bucket, f.Check)
+		fmt.Fprintln(b, truncate(line, m.width))
+	}
+	for i := len(m.findings) - m.top; i < m.listLines(); i++ {
+		fmt.Fprintln(b)
+	}
+
+	fmt.Fprintln(b, strings.Repeat("-", m.width))
+	details := []string{}
+	if f, ok := m.selected(); ok {
+		d := &strings.Builder{}
+		printFinding(d, f)
+		details = strings.Split(strings.TrimSuffix(d.String(), "\n"), "\n")
+		details[0] = f.Message
+		if f.Owner != "" {
+			details = append(details, "\towner "+f.Owner)
+		}
+	}
+	for i := range detailLines {
+		if i < len(details) {
+			fmt.Fprint(b, truncate(strings.ReplaceAll(details[i], "\t", "  "), m.width))
+		}
+		fmt.Fprintln(b)
+	}
+
+	fmt.Fprintln(b, m.status)
+	fmt.Fprint(b, "j/k move  s suppress  r mark for remediation  o open in console  a show suppressed  q quit")
+
+	return b.String()
+}
+
+// truncate cuts s to at most width runes, never splitting one.
+func truncate(s string, width int) string {
+	n := 0
+	for i := range s {
+		if n == width {
+			return s[:i]
+		}
+		n++
+	}
+
+	return s
+}
+
+// consoleURL links to the permissions of the finding's bucket in the S3
+// console.
+func consoleURL(f Finding) string {
+	u := "https://s3.console.aws.amazon.com/s3/buckets/" + url.PathEscape(f.Bucket) + "?tab=permissions"
+	if f.Metadata != nil && f.Metadata.Region != "" {
+		u += "&region=" + url.QueryEscape(f.Metadata.Region)
+	}
+
+	return u
+}
+
+func openBrowser(u string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", u).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", u).Start()
+	default:
+		return exec.Command("xdg-open", u).Start()
+	}
+}
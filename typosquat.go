@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// bucketNamePattern matches valid S3 bucket names.
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// lookalikes are characters commonly mistaken for one another.
+var lookalikes = map[byte]byte{'0': 'o', 'o': '0', '1': 'l', 'l': '1', '-': '.', '.': '-'}
+
+// typoVariants returns up to max likely mistypings of a bucket name: a
+// character dropped, doubled, swapped with its neighbour or replaced by a
+// lookalike, or a separator dropped. Only valid bucket names are returned.
+func typoVariants(name string, max int) []string {
+	candidates := []string{}
+	for i := range len(name) {
+		candidates = append(candidates,
+			name[:i]+name[i+1:],
+			name[:i+1]+name[i:],
+		)
+		if i+1 < len(name) && name[i] != name[i+1] {
+			candidates = append(candidates, name[:i]+string(name[i+1])+string(name[i])+name[i+2:])
+		}
+		if c, ok := lookalikes[name[i]]; ok {
+			candidates = append(candidates, name[:i]+string(c)+name[i+1:])
+		}
+	}
+	candidates = append(candidates, strings.ReplaceAll(name, "-", ""))
+
+	variants := []string{}
+	for _, v := range candidates {
+		if len(variants) == max {
+			break
+		}
+		if v != name && bucketNamePattern.MatchString(v) && !strings.Contains(v, "..") && !slices.Contains(variants, v) {
+			variants = append(variants, v)
+		}
+	}
+
+	return variants
+}
+
+// findTyposquats returns the variants of the bucket name that exist as
+// buckets other than ours, probing anonymously. Bucket names are global, so
+// anyone can register a name one of our clients might mistype and receive
+// its uploads or serve it content.
+func findTyposquats(ctx context.Context, name string, ours map[string]bool, max int) ([]string, error) {
+	squats := []string{}
+	for _, variant := range typoVariants(name, max) {
+		if ours[variant] {
+			continue
+		}
+
+		exists, err := bucketExists(ctx, variant)
+		if err != nil {
+			return nil, fmt.Errorf("unable to probe %s: %w", variant, err)
+		}
+		if exists {
+			squats = append(squats, variant)
+		}
+	}
+
+	return squats, nil
+}
+
+// bucketExists reports whether a bucket with the name exists in any
+// account. S3 answers an anonymous request for a missing bucket with 404,
+// and for anyone else's with 403 or a redirect to its region.
+func bucketExists(ctx context.Context, name string) (bool, error) {
+	url := "https://s3.amazonaws.com/" + name
+	if endpoint := endpoints.s3Endpoint(); endpoint != "" {
+		url = strings.TrimSuffix(endpoint, "/") + "/" + name
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	// Redirects to the bucket's region are enough to know it exists.
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode != http.StatusNotFound, nil
+}
+
+// typosquattingCheck reports buckets with lookalike names registered
+// outside the account. Only public buckets and those listed in the
+// parameters are probed, as they are the ones clients are pointed at.
+type typosquattingCheck struct {
+	params typosquattingParams
+}
+
+func (typosquattingCheck) Name() string { return "typosquatting" }
+
+func (c typosquattingCheck) Run(_ context.Context, facts *BucketFacts) []Finding {
+	if len(facts.Typosquats) == 0 {
+		return nil
+	}
+
+	finding := Finding{Check: c.Name(), Severity: SeverityMedium}
+	finding.Message = fmt.Sprintf("%d lookalike bucket names are registered outside this account, and could receive data from mistyped clients", len(facts.Typosquats))
+	for _, name := range facts.Typosquats {
+		finding.Details = append(finding.Details, "exists: "+name)
+	}
+
+	return []Finding{finding}
+}
+
+// probes reports whether the bucket's lookalikes should be probed.
+func (p typosquattingParams) probes(facts *BucketFacts) bool {
+	return facts.Exposure.any() || slices.Contains(p.Buckets, facts.Name)
+}
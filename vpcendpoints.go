@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/guardian/s3-audit/policy"
+)
+
+// vpcEndpoint is a gateway or interface endpoint for S3 in one of the
+// account's VPCs.
+type vpcEndpoint struct {
+	ID     string
+	Type   string // Gateway or Interface
+	VPC    string
+	Region string
+	Policy *policy.Policy
+}
+
+// getS3VPCEndpoints lists the S3 endpoints in the regions. Endpoints only
+// reach buckets in their own region, so regions without buckets are left
+// out.
+func getS3VPCEndpoints(ctx context.Context, config aws.Config, regions []string) ([]vpcEndpoint, error) {
+	errs := []error{}
+	endpoints := []vpcEndpoint{}
+
+	for _, region := range regions {
+		client := ec2.NewFromConfig(config, func(o *ec2.Options) { o.Region = region })
+		paginator := ec2.NewDescribeVpcEndpointsPaginator(client, &ec2.DescribeVpcEndpointsInput{
+			Filters: []ec2types.Filter{{Name: aws.String("service-name"), Values: []string{"com.amazonaws." + region + ".s3"}}},
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to describe VPC endpoints in %s: %w", region, err))
+				break
+			}
+
+			for _, e := range page.VpcEndpoints {
+				endpoint := vpcEndpoint{
+					ID:     aws.ToString(e.VpcEndpointId),
+					Type:   string(e.VpcEndpointType),
+					VPC:    aws.ToString(e.VpcId),
+					Region: region,
+				}
+				if e.PolicyDocument != nil {
+					if endpoint.Policy, err = policy.Parse(*e.PolicyDocument); err != nil {
+						errs = append(errs, fmt.Errorf("unable to parse policy of VPC endpoint %s: %w", endpoint.ID, err))
+						continue
+					}
+				}
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+
+	return endpoints, errors.Join(errs...)
+}
+
+// vpcEndpointFindings reports S3 endpoints whose policies let requests
+// through to any bucket. Bucket policies can't stop data being copied from
+// a VPC to a bucket in someone else's account; the endpoint policy is where
+// that's stopped, by limiting it to the organization's buckets. Letting
+// anyone through, e.g. with credentials from outside the organization, is
+// MEDIUM and otherwise LOW.
+func vpcEndpointFindings(as *accountScan, endpoints []vpcEndpoint) []Finding {
+	findings := []Finding{}
+
+	for _, e := range endpoints {
+		name := fmt.Sprintf("%s endpoint %s in %s (%s)", strings.ToLower(e.Type), e.ID, e.VPC, e.Region)
+
+		// Without a policy the endpoint allows everything, as the default
+		// policy does.
+		grants := []policy.AnyBucketGrant{{Actions: []string{"*"}, Anyone: true}}
+		if e.Policy != nil {
+			grants = e.Policy.AnyBucketGrants()
+		}
+		if len(grants) == 0 {
+			continue
+		}
+
+		finding := Finding{
+			Account:   as.Profile,
+			AccountID: as.ID,
+			Check:     "vpc-endpoint-policy",
+			Subject:   e.ID,
+			Severity:  SeverityLow,
+			Message:   name + " lets requests through to any bucket, including other accounts'",
+		}
+		for _, g := range grants {
+			if g.Anyone {
+				finding.Severity = SeverityMedium
+				finding.Message = name + " lets anyone through to any bucket, including other accounts'"
+			}
+			finding.Details = append(finding.Details, g.String())
+		}
+		finding.Details = append(finding.Details, "limit the policy to known buckets with an aws:ResourceOrgID or aws:ResourceAccount condition")
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// watchMain re-checks a few buckets on an interval and prints findings as
+// they appear and are resolved, so an owner fixing a policy can see the
+// effect straight away.
+func watchMain(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	profiles := fs.String("profiles", "deployTools", "comma-separated AWS profiles to look for the buckets in")
+	configFile := fs.String("config", "", "JSON config file")
+	var buckets stringList
+	fs.Var(&buckets, "bucket", "bucket to watch; may be repeated")
+	interval := fs.Duration("interval", time.Minute, "how often to re-check the buckets")
+	onlyChecks := fs.String("checks", "", "comma-separated checks to run; all if empty")
+	skipChecks := fs.String("skip-checks", "", "comma-separated checks not to run")
+	accountNamesFile := fs.String("account-names", "", "JSON file mapping account IDs to friendly names")
+	ownersFile := fs.String("owners", "", "JSON file mapping Stack and App tags, and bucket names, to owning teams")
+	noWriteFlag := fs.Bool("no-write", false, "make no changes in any account, skipping the anonymous read probe (also set by S3_AUDIT_NO_WRITE)")
+	actionLog := fs.String("action-log", "", "append every change made in an account to this file as JSON lines with request IDs")
+	endpointURL := fs.String("endpoint-url", "", "send AWS requests to this URL instead")
+	s3Compatible := fs.Bool("s3-compatible", false, "the S3 endpoint is an S3-compatible store such as MinIO or Ceph: only call S3 and skip checks of AWS-only features")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: s3-audit watch -bucket bucket [-bucket bucket ...] [-interval 60s] [-profiles profiles]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if len(buckets) == 0 || *interval <= 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	conf, err := loadConfigFile(*configFile)
+	check(err, "unable to load config")
+	check(setEndpoints(*endpointURL, conf.Endpoints), "invalid endpoints")
+	conf.S3Compatible = conf.S3Compatible || *s3Compatible
+	check(checkS3Compatible(conf), "invalid endpoints")
+	check(setWriteGuard(*noWriteFlag, *actionLog), "invalid -action-log")
+
+	names, err := loadAccountNames(*accountNamesFile, nil)
+	check(err, "unable to load account names")
+	teams, err := loadOwners(*ownersFile)
+	check(err, "unable to load owners")
+
+	s := &scanner{accountNames: names, owners: teams, conf: conf, clients: newAWSClients, buckets: buckets, s3Compatible: conf.S3Compatible}
+	s.selection, err = newCheckSelection(*onlyChecks, *skipChecks, nil)
+	check(err, "invalid checks")
+	s.selection.s3Compatible = conf.S3Compatible
+	s.checks, err = s.builtinChecks()
+	check(err, "invalid config")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	targets, err := s.findTargets(ctx, strings.Split(*profiles, ","))
+	check(err, "unable to find buckets")
+	for _, name := range buckets {
+		if !s.found[name] {
+			check(fmt.Errorf("bucket %s not found in %s", name, *profiles), "unable to watch")
+		}
+	}
+
+	var previous []Finding
+	for {
+		current, errs := s.recheck(ctx, targets)
+		if ctx.Err() != nil {
+			return
+		}
+		printChanges(os.Stdout, time.Now(), previous, current)
+		if len(errs) > 0 {
+			printErrors(os.Stdout, errs)
+		}
+		previous = current
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// watchTarget is a watched bucket and the account it is in.
+type watchTarget struct {
+	as     *accountScan
+	bucket s3types.Bucket
+}
+
+// findTargets looks for the watched buckets in each account.
+func (s *scanner) findTargets(ctx context.Context, profiles []string) ([]watchTarget, error) {
+	targets := []watchTarget{}
+	for _, profile := range profiles {
+		as, _, err := s.newAccountScan(ctx, profile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan %s: %w", profile, err)
+		}
+
+		buckets, err := listBuckets(ctx, as.client)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list buckets in %s: %w", profile, err)
+		}
+
+		for _, bucket := range s.targetBuckets(buckets) {
+			targets = append(targets, watchTarget{as: as, bucket: bucket})
+		}
+	}
+
+	return targets, nil
+}
+
+// recheck scans the targets again. The caches an account scan keeps are
+// reset, as the point is to see changes.
+func (s *scanner) recheck(ctx context.Context, targets []watchTarget) ([]Finding, []scanError) {
+	findings := []Finding{}
+	errs := []scanError{}
+	for _, t := range targets {
+		as := t.as
+		as.errors = nil
+		as.kmsKeys = newKMSKeys(as.config)
+		as.notificationTargets = newNotificationTargets(as.config)
+
+		if !as.s3Compatible {
+			var err error
+			if as.publicByAccessAnalyzer, err = getAccessAnalyzerPublicBuckets(ctx, s.clients(as.config).accessAnalyzer); err != nil {
+				as.fail("", err)
+			}
+		}
+
+		findings = append(findings, s.scanBucket(ctx, as, t.bucket)...)
+		errs = append(errs, as.errors...)
+	}
+
+	return findings, errs
+}
+
+// printChanges prints the findings new since the previous check with a +,
+// and those resolved with a -. Nothing is printed if nothing changed. On the
+// first check previous is nil, and the current findings are printed.
+func printChanges(w io.Writer, at time.Time, previous []Finding, current []Finding) {
+	added, resolved := compareFindings(previous, current)
+	if previous != nil && len(added) == 0 && len(resolved) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%s: %d findings\n", formatTime(at), len(current))
+	for _, f := range added {
+		fmt.Fprintf(w, "+ %-8s\t%-60s\t%-14s\t(%s)\n", f.Severity, f.Bucket, f.Check, f.Message)
+	}
+	for _, f := range resolved {
+		fmt.Fprintf(w, "- %-8s\t%-60s\t%-14s\t(%s)\n", f.Severity, f.Bucket, f.Check, f.Message)
+	}
+}